@@ -34,6 +34,10 @@ func main() {
 		profiling.DumpProfile()
 		ovn_monitor.CmdMain()
 	case CmdSpeaker:
+		if len(os.Args) > 1 && os.Args[1] == "export" {
+			speaker.CmdExport(os.Args[2:])
+			return
+		}
 		profiling.DumpProfile()
 		speaker.CmdMain()
 	case CmdWebhook:
@@ -0,0 +1,25 @@
+package speaker
+
+import (
+	"os"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+
+	"github.com/kubeovn/kube-ovn/pkg/speaker"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// CmdExport prints the routes the speaker would announce as a static configuration of another BGP daemon
+func CmdExport(args []string) {
+	defer klog.Flush()
+
+	config, format, err := speaker.ParseExportFlags(args)
+	if err != nil {
+		util.LogFatalAndExit(err, "failed to parse export flags")
+	}
+
+	if err = speaker.ExportRoutes(config, format, os.Stdout, signals.SetupSignalHandler().Done()); err != nil {
+		util.LogFatalAndExit(err, "failed to export routes")
+	}
+}
@@ -38,9 +38,16 @@ func CmdMain() {
 
 	ctrl.SetLogger(klog.NewKlogr())
 	ctx := signals.SetupSignalHandler()
+	controller := speaker.NewController(config)
 	go func() {
 		if config.EnableMetrics {
 			metrics.InitKlogMetrics()
+			speaker.InitMetrics()
+			// The status is served without authentication along with the metrics, see StatusHandler
+			metrics.RegisterHandler(speaker.StatusPath, controller.StatusHandler())
+			if config.SoftReconfigurationInbound {
+				metrics.RegisterHandler(speaker.ReceivedRoutesPath, controller.ReceivedRoutesHandler())
+			}
 			if err = metrics.Run(ctx, nil, util.JoinHostPort("0.0.0.0", config.PprofPort), false, false, "", "", nil); err != nil {
 				util.LogFatalAndExit(err, "failed to run metrics server")
 			}
@@ -48,5 +55,5 @@ func CmdMain() {
 		<-ctx.Done()
 	}()
 
-	speaker.NewController(config).Run(ctx.Done())
+	controller.Run(ctx.Done())
 }
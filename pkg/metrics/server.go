@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/pprof"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -67,6 +68,19 @@ func CipherSuitesFromNames(suites []string) ([]uint16, error) {
 	return cipherSuites, nil
 }
 
+var (
+	handlersMutex sync.Mutex
+	handlers      = make(map[string]http.Handler)
+)
+
+// RegisterHandler registers an additional handler served by the metrics server on a path, with the same
+// authentication and authorization as the metrics. It must be called before the metrics server is started.
+func RegisterHandler(path string, handler http.Handler) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	handlers[path] = handler
+}
+
 // Run creates a listener on addr and starts serving metrics.
 // The listener is created synchronously before this function blocks on
 // serving, so callers can rely on the bind completing before Run returns
@@ -138,6 +152,20 @@ func ServeWithListener(ctx context.Context, config *rest.Config, listener net.Li
 		}
 	}
 
+	handlersMutex.Lock()
+	for path, h := range handlers {
+		if authFilter != nil {
+			log := klog.NewKlogr().WithValues("path", path)
+			var err error
+			if h, err = authFilter(log, h); err != nil {
+				handlersMutex.Unlock()
+				return fmt.Errorf("failed to apply auth filter to handler %s: %w", path, err)
+			}
+		}
+		mux.Handle(path, h)
+	}
+	handlersMutex.Unlock()
+
 	if secureServing {
 		minVersion, err := TLSVersionFromString(tlsMinVersion)
 		if err != nil {
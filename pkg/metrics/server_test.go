@@ -1,9 +1,15 @@
 package metrics
 
 import (
+	"context"
 	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
 	"slices"
 	"testing"
+
+	"k8s.io/client-go/rest"
 )
 
 func TestTLSVersionFromString(t *testing.T) {
@@ -112,3 +118,36 @@ func TestCipherSuitesFromNames(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterHandler(t *testing.T) {
+	RegisterHandler("/test", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("registered"))
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ServeWithListener(ctx, &rest.Config{}, listener, false, false, "", "", nil) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("ServeWithListener() error = %v", err)
+		}
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/test")
+	if err != nil {
+		t.Fatalf("failed to get registered handler: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "registered" {
+		t.Errorf("GET /test = %d %q, want %d %q", resp.StatusCode, body, http.StatusOK, "registered")
+	}
+}
@@ -0,0 +1,69 @@
+package speaker
+
+import (
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// getGatewayPodSelector returns the selector of the pods of the NAT gateway of the speaker
+func getGatewayPodSelector() labels.Selector {
+	return labels.Set{"app": util.GenNatGwName(getGatewayName()), util.VpcNatGatewayLabel: "true"}.AsSelector()
+}
+
+// isSingleAnnouncerCandidate returns whether a NAT gateway pod competes to announce the routes: it must be running
+// on a node and not be deleted
+func isSingleAnnouncerCandidate(pod *corev1.Pod) bool {
+	return pod.Spec.NodeName != "" && pod.DeletionTimestamp == nil && pod.Status.Phase == corev1.PodRunning
+}
+
+// electSingleAnnouncer returns the node announcing the routes among the nodes of the NAT gateway pods, the node
+// with the lowest name among the candidates, or an empty string if there is no candidate
+func electSingleAnnouncer(pods []*corev1.Pod) string {
+	var nodes []string
+	for _, pod := range pods {
+		if isSingleAnnouncerCandidate(pod) {
+			nodes = append(nodes, pod.Spec.NodeName)
+		}
+	}
+	if len(nodes) == 0 {
+		return ""
+	}
+	return slices.Min(nodes)
+}
+
+// singleAnnouncerReason returns why the node of the speaker does not announce the routes with
+// --single-announcer-by-name, or an empty string if it does
+func (c *Controller) singleAnnouncerReason() string {
+	if !c.config.SingleAnnouncerByName {
+		return ""
+	}
+
+	pods, err := c.podsLister.List(getGatewayPodSelector())
+	if err != nil {
+		klog.Errorf("failed to list the pods of vpc nat gateway %s: %v", getGatewayName(), err)
+		return fmt.Sprintf("failed to list the pods of vpc nat gateway %s", getGatewayName())
+	}
+	switch announcer := electSingleAnnouncer(pods); announcer {
+	case c.config.NodeName:
+		return ""
+	case "":
+		return fmt.Sprintf("no running pod of vpc nat gateway %s", getGatewayName())
+	default:
+		return fmt.Sprintf("node %s announces the routes of vpc nat gateway %s", announcer, getGatewayName())
+	}
+}
+
+// isSingleAnnouncerUpdate returns whether the update of a pod may change the node announcing the routes with
+// --single-announcer-by-name
+func (c *Controller) isSingleAnnouncerUpdate(oldPod, newPod *corev1.Pod) bool {
+	if !c.config.SingleAnnouncerByName || !getGatewayPodSelector().Matches(labels.Set(newPod.Labels)) {
+		return false
+	}
+	return isSingleAnnouncerCandidate(oldPod) != isSingleAnnouncerCandidate(newPod) || oldPod.Spec.NodeName != newPod.Spec.NodeName
+}
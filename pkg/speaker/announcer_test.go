@@ -0,0 +1,91 @@
+package speaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func newTestGatewayPod(name, gateway, node string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "kube-system",
+			Labels:    map[string]string{"app": util.GenNatGwName(gateway), util.VpcNatGatewayLabel: "true"},
+		},
+		Spec:   corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestElectSingleAnnouncer(t *testing.T) {
+	require.Empty(t, electSingleAnnouncer(nil))
+
+	node2 := newTestGatewayPod("vpc-nat-gw-gw1-0", "gw1", "node2", corev1.PodRunning)
+	node10 := newTestGatewayPod("vpc-nat-gw-gw1-1", "gw1", "node10", corev1.PodRunning)
+	node3 := newTestGatewayPod("vpc-nat-gw-gw1-2", "gw1", "node3", corev1.PodRunning)
+
+	// Node names are compared lexicographically
+	require.Equal(t, "node10", electSingleAnnouncer([]*corev1.Pod{node2, node10, node3}))
+
+	// Pods not running, not scheduled or being deleted do not compete
+	pending := node10.DeepCopy()
+	pending.Status.Phase = corev1.PodPending
+	require.Equal(t, "node2", electSingleAnnouncer([]*corev1.Pod{node2, pending, node3}))
+
+	unscheduled := node10.DeepCopy()
+	unscheduled.Spec.NodeName = ""
+	require.Equal(t, "node2", electSingleAnnouncer([]*corev1.Pod{node2, unscheduled, node3}))
+
+	deleted := node10.DeepCopy()
+	deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	require.Equal(t, "node2", electSingleAnnouncer([]*corev1.Pod{node2, deleted, node3}))
+	require.Empty(t, electSingleAnnouncer([]*corev1.Pod{deleted}))
+}
+
+func TestSingleAnnouncerReason(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := &Controller{
+		config:      &Configuration{NatGwMode: true, NodeName: "node2"},
+		podsLister:  listerv1.NewPodLister(podIndexer),
+		reconcileCh: make(chan struct{}, 1),
+	}
+
+	// Routes are announced from every node without --single-announcer-by-name
+	require.Empty(t, c.singleAnnouncerReason())
+
+	c.config.SingleAnnouncerByName = true
+	require.Equal(t, "no running pod of vpc nat gateway gw1", c.singleAnnouncerReason())
+
+	// The pods of other gateways do not compete
+	require.NoError(t, podIndexer.Add(newTestGatewayPod("vpc-nat-gw-gw2-0", "gw2", "node1", corev1.PodRunning)))
+	local := newTestGatewayPod("vpc-nat-gw-gw1-0", "gw1", "node2", corev1.PodRunning)
+	require.NoError(t, podIndexer.Add(local))
+	require.Empty(t, c.singleAnnouncerReason())
+
+	remote := newTestGatewayPod("vpc-nat-gw-gw1-1", "gw1", "node1", corev1.PodPending)
+	require.NoError(t, podIndexer.Add(remote))
+	require.Empty(t, c.singleAnnouncerReason())
+
+	// A pod on a node with a lower name taking over triggers a reconciliation
+	running := remote.DeepCopy()
+	running.Status.Phase = corev1.PodRunning
+	require.NoError(t, podIndexer.Update(running))
+	c.enqueueUpdatePod(remote, running)
+	require.Len(t, c.reconcileCh, 1)
+	require.Equal(t, "node node1 announces the routes of vpc nat gateway gw1", c.singleAnnouncerReason())
+	require.Equal(t, "node node1 announces the routes of vpc nat gateway gw1", c.announcementSuppressedReason(time.Now()))
+	<-c.reconcileCh
+
+	// Updates which do not change the candidates do not trigger a reconciliation
+	c.enqueueUpdatePod(running, running.DeepCopy())
+	require.Empty(t, c.reconcileCh)
+}
@@ -1,14 +1,20 @@
 package speaker
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"net"
+	"net/netip"
+	"slices"
+	"time"
 
 	"github.com/osrg/gobgp/v4/api"
 	"github.com/osrg/gobgp/v4/pkg/apiutil"
 	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	gobgp "github.com/osrg/gobgp/v4/pkg/server"
 	"github.com/vishvananda/netlink"
-	"golang.org/x/sys/unix"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/set"
 
@@ -16,134 +22,352 @@ import (
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
-// reconcileRoutes configures the BGP speaker to announce only the routes we are expected to announce
-// and to withdraw the ones that should not be announced anymore
-func (c *Controller) reconcileRoutes(expectedPrefixes prefixMap) error {
-	if c.config.ExtendedNexthop || len(c.config.NeighborAddresses) != 0 {
-		err := c.reconcileIPFamily(api.Family_AFI_IP, expectedPrefixes)
+// routeResults associates the routes a reconciliation announced or withdrew and the error their announcement
+// or withdrawal failed with, nil if it succeeded
+type routeResults map[string]error
+
+// failed returns the sorted routes whose announcement or withdrawal failed
+func (r routeResults) failed() []string {
+	var failed []string
+	for route, err := range r {
 		if err != nil {
-			return fmt.Errorf("failed to reconcile IPv4 routes: %w", err)
+			failed = append(failed, route)
 		}
 	}
+	slices.Sort(failed)
+	return failed
+}
 
-	if c.config.ExtendedNexthop || len(c.config.NeighborIPv6Addresses) != 0 {
-		err := c.reconcileIPFamily(api.Family_AFI_IP6, expectedPrefixes)
-		if err != nil {
-			return fmt.Errorf("failed to reconcile IPv6 routes: %w", err)
-		}
+// err returns the errors of the routes whose announcement or withdrawal failed, or nil if none failed
+func (r routeResults) err() error {
+	errs := make([]error, 0, len(r))
+	for _, route := range r.failed() {
+		errs = append(errs, r[route])
 	}
+	return errors.Join(errs...)
+}
 
-	return nil
+// RouteAnnouncer announces the paths of routes to the BGP neighbors and withdraws them
+type RouteAnnouncer interface {
+	AnnouncePaths(paths []*apiutil.Path) error
+	WithdrawPaths(paths []*apiutil.Path) error
+	// IsRouteAnnounced returns whether paths of a route are in the RIB, to verify announcements
+	IsRouteAnnounced(route string) (bool, error)
 }
 
-// reconcileIPFamily announces prefixes we are not currently announcing and withdraws prefixes we should
-// not be announcing for a given IP family (IPv4/IPv6)
-func (c *Controller) reconcileIPFamily(afi api.Family_Afi, expectedPrefixes prefixMap) error {
-	// Craft a BGP path listing request for this AFI
-	listPathRequest := apiutil.ListPathRequest{
-		TableType: api.TableType_TABLE_TYPE_GLOBAL,
-		Family:    apiutil.ToFamily(&api.Family{Afi: afi, Safi: api.Family_SAFI_UNICAST}),
-	}
-
-	// Anonymous function that stores the prefixes we are announcing for this AFI
-	existingPrefixes := set.New[string]()
-	fn := func(prefix bgp.NLRI, paths []*apiutil.Path) {
-		for _, path := range paths {
-			nextHop := getNextHopFromPathAttributes(path.Attrs)
-			klog.V(5).Infof("announcing route with prefix %s and nexthop: %s", prefix, nextHop)
-
-			route, _ := netlink.RouteGet(nextHop)
-			if len(route) == 1 && route[0].Type == unix.RTN_LOCAL || nextHop.Equal(c.config.RouterID) {
-				existingPrefixes.Insert(prefix.String())
-				return
-			}
-		}
+// gobgpAnnouncer announces and withdraws paths with the embedded gobgp server
+type gobgpAnnouncer struct {
+	server *gobgp.BgpServer
+	// labeledUnicast is whether the routes are announced as labeled unicast
+	labeledUnicast bool
+}
+
+func (a gobgpAnnouncer) AnnouncePaths(paths []*apiutil.Path) error {
+	_, err := a.server.AddPath(apiutil.AddPathRequest{Paths: paths})
+	return err
+}
+
+func (a gobgpAnnouncer) WithdrawPaths(paths []*apiutil.Path) error {
+	return a.server.DeletePath(apiutil.DeletePathRequest{Paths: paths})
+}
+
+func (a gobgpAnnouncer) IsRouteAnnounced(route string) (bool, error) {
+	prefix, err := parsePrefix(route)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse route %s: %w", route, err)
+	}
+	family := bgp.RF_IPv4_UC
+	switch {
+	case prefix.Addr().Is6() && a.labeledUnicast:
+		family = bgp.RF_IPv6_MPLS
+	case prefix.Addr().Is6():
+		family = bgp.RF_IPv6_UC
+	case a.labeledUnicast:
+		family = bgp.RF_IPv4_MPLS
 	}
 
-	// Ask the BGP speaker what route we're announcing for the IP family selected
-	if err := c.config.BgpServer.ListPath(listPathRequest, fn); err != nil {
-		return fmt.Errorf("failed to list existing %s routes: %w", afi, err)
+	req := apiutil.ListPathRequest{TableType: api.TableType_TABLE_TYPE_GLOBAL, Family: family}
+	// The RIB cannot be filtered by prefix for labeled unicast, the labeled prefixes are matched by their prefix
+	if !a.labeledUnicast {
+		req.Prefixes = []*apiutil.LookupPrefix{{Prefix: prefix.String(), LookupOption: apiutil.LOOKUP_EXACT}}
+	}
+	found := false
+	if err = a.server.ListPath(req, func(nlri bgp.NLRI, paths []*apiutil.Path) {
+		found = found || (len(paths) != 0 && nlri.String() == prefix.String())
+	}); err != nil {
+		return false, fmt.Errorf("failed to look up route %s in the RIB: %w", route, err)
 	}
+	return found, nil
+}
 
-	klog.V(5).Infof("currently announcing %s routes: %v", afi, existingPrefixes.SortedList())
+// getAnnouncer returns the announcer of the routes, the BGP server by default
+func (c *Controller) getAnnouncer() RouteAnnouncer {
+	if c.announcer != nil {
+		return c.announcer
+	}
+	return gobgpAnnouncer{server: c.config.BgpServer, labeledUnicast: c.config.EnableBgpLU}
+}
+
+// reconcileRoutes configures the BGP speaker to announce only the routes we are expected to announce
+// and to withdraw the ones that should not be announced anymore. Routes are announced with the optional
+// attributes found in attrs, and announced again when those attributes change. The static prefixes are
+// always expected to be announced.
+// Only the routes whose announcement or withdrawal succeeded are recorded as such, the others are found
+// again by the next reconciliation and retried, as are the routes left pending by the announce queue.
+// The result of each route announced or withdrawn is returned.
+func (c *Controller) reconcileRoutes(expectedPrefixes prefixMap, attrs prefixAttributes) routeResults {
+	c.addStaticPrefixes(expectedPrefixes)
+
+	reason := c.announcementSuppressedReason(time.Now())
+	c.suppressedReason.Store(reason)
+	if reason != "" {
+		klog.V(3).Infof("announcements are suppressed (%s), withdrawing all routes", reason)
+		expectedPrefixes = make(prefixMap)
+	}
 
+	klog.V(5).Infof("currently announcing routes: %v", c.announced.List())
+
+	// Filter the routes advertised to each neighbor before announcing them, so that filtered routes are never advertised
+	if err := c.applyExportFilters(c.getExportFilters(expectedPrefixes, attrs)); err != nil {
+		klog.Error(err)
+	}
+
+	results := make(routeResults)
+	queue := newAnnounceQueue(c.config.AnnounceQueueSize)
+	if c.config.ExtendedNexthop || c.config.IPv4OverIPv6Nexthop || len(c.config.NeighborAddresses)+len(c.config.SecondaryNeighborAddresses) != 0 {
+		maps.Copy(results, c.reconcileIPFamily(api.Family_AFI_IP, expectedPrefixes, attrs, queue))
+	}
+
+	if c.config.ExtendedNexthop || len(c.config.NeighborIPv6Addresses)+len(c.config.SecondaryNeighborIPv6Addresses) != 0 {
+		maps.Copy(results, c.reconcileIPFamily(api.Family_AFI_IP6, expectedPrefixes, attrs, queue))
+	}
+	c.reportAnnounceQueue(queue)
+
+	if failed := results.failed(); len(failed) != 0 {
+		klog.Errorf("failed to announce or withdraw routes %v, they will be retried by the next reconciliation", failed)
+	}
+	return results
+}
+
+// getExportFilters returns the prefixes which must not be advertised to each neighbor
+func (c *Controller) getExportFilters(expectedPrefixes prefixMap, attrs prefixAttributes) neighborFilters {
+	filters := make(neighborFilters)
+	c.addInstanceFilters(expectedPrefixes, attrs, filters)
+	c.addNeighborFilters(expectedPrefixes, attrs, filters)
+	c.addNextHopFilters(expectedPrefixes, filters)
+	c.addPrefixLimitFilters(expectedPrefixes, filters)
+	return filters
+}
+
+// announcementSuppressedReason returns why routes must not be announced at the moment,
+// or an empty string if they can be announced
+func (c *Controller) announcementSuppressedReason(now time.Time) string {
+	if !c.isLeading() {
+		return fmt.Sprintf("not holding leader lease %s/%s", c.config.LeaderElectionNamespace, c.config.LeaderElectionLease)
+	}
+	if reason := c.initialAnnounceHoldReason(now); reason != "" {
+		return reason
+	}
+	if reason := c.singleAnnouncerReason(); reason != "" {
+		return reason
+	}
+	if c.isPodTerminating() {
+		return c.terminatingReason()
+	}
+	if !c.config.AnnounceSchedule.isActive(now) {
+		return "outside of the announcement schedule"
+	}
+	if c.config.AnnounceGateFile != "" {
+		open, err := isAnnounceGateOpen(c.config.AnnounceGateFile)
+		if err != nil {
+			klog.Error(err)
+		}
+		if !open {
+			return fmt.Sprintf("announce gate file %s is not %s", c.config.AnnounceGateFile, announceGateReady)
+		}
+	}
+	return ""
+}
+
+// reconcileIPFamily announces prefixes we are not currently announcing and withdraws prefixes we should
+// not be announcing for a given IP family (IPv4/IPv6), the announcements being bounded by the announce queue
+func (c *Controller) reconcileIPFamily(afi api.Family_Afi, expectedPrefixes prefixMap, attrs prefixAttributes, queue *announceQueue) routeResults {
 	// Announce routes we should be announcing and withdraw the ones that are no longer valid
-	c.announceAndWithdraw(expectedPrefixes[afi], existingPrefixes)
-	return nil
+	toAdd, toDel := c.announced.Diff(afi, expectedPrefixes[afi], attrs)
+	return c.announceAndWithdraw(toAdd, toDel, attrs, queue)
 }
 
-// announceAndWithdraw commands the BGP speaker to start announcing new routes and to withdraw others
-func (c *Controller) announceAndWithdraw(expected, existing set.Set[string]) {
-	// Announce routes that need to be added
-	toAdd := expected.Difference(existing)
+// announceAndWithdraw commands the BGP speaker to start announcing new routes and to withdraw others.
+// Routes are announced and withdrawn in batches, each batch being sent to the BGP server in a single request.
+// Only the routes admitted by the announce queue are announced, withdrawals are not bounded.
+func (c *Controller) announceAndWithdraw(toAdd, toDel set.Set[string], attrs prefixAttributes, queue *announceQueue) routeResults {
+	results := make(routeResults, toAdd.Len()+toDel.Len())
+
+	// Announce routes that need to be added, announcing a route again replaces its previous attributes
 	klog.V(5).Infof("new routes we will announce: %v", toAdd.SortedList())
-	for route := range toAdd {
-		if err := c.addRoute(route); err != nil {
+	for _, batch := range batchRoutes(toAdd, attrs) {
+		batch = queue.admit(batch)
+		if len(batch) == 0 {
+			continue
+		}
+		batchResults := c.addRoutes(batch, attrs)
+		if err := batchResults.err(); err != nil {
 			klog.Error(err)
+			queue.stall()
 		}
+		maps.Copy(results, batchResults)
 	}
 
-	// Withdraw routes that should be deleted
-	toDel := existing.Difference(expected)
+	// Withdraw routes that should be deleted, batched the same way they were announced and paced if configured
 	klog.V(5).Infof("announced routes we will withdraw: %v", toDel.SortedList())
-	for route := range toDel {
-		if err := c.delRoute(route); err != nil {
-			klog.Error(err)
+	for _, batch := range batchRoutes(toDel, c.announced.Attributes()) {
+		for _, chunk := range c.paceWithdrawals(batch) {
+			c.waitWithdrawals(chunk)
+			chunkResults := c.delRoutes(chunk)
+			if err := chunkResults.err(); err != nil {
+				klog.Error(err)
+			}
+			maps.Copy(results, chunkResults)
 		}
 	}
+	return results
 }
 
-// addRoute adds a new route to advertise from our BGP speaker
-func (c *Controller) addRoute(route string) error {
-	// Get paths used to announce all the next hops possible
-	paths, err := c.getPathRequest(route)
-	if err != nil {
-		return fmt.Errorf("failed to get NLRI and attributes: %w", err)
+// batchRoutes groups routes by the batch of their attributes. Batches and the routes they hold are sorted.
+func batchRoutes(routes set.Set[string], attrs prefixAttributes) [][]string {
+	batches := make(map[string][]string)
+	for _, route := range routes.SortedList() {
+		batch := attrs[route].batch
+		batches[batch] = append(batches[batch], route)
+	}
+
+	result := make([][]string, 0, len(batches))
+	for _, batch := range slices.Sorted(maps.Keys(batches)) {
+		result = append(result, batches[batch])
+	}
+	return result
+}
+
+// addRoutes adds new routes to advertise from our BGP speaker in a single request, and returns the result
+// of each route. Only the routes successfully announced, and found in the RIB when announcements are verified,
+// are recorded as announced.
+func (c *Controller) addRoutes(routes []string, attrs prefixAttributes) routeResults {
+	results := make(routeResults, len(routes))
+	routePaths := make(map[string][]*apiutil.Path, len(routes))
+	for _, route := range routes {
+		// Get paths used to announce all the next hops possible
+		paths, err := c.getPathRequest(route, attrs[route])
+		if err != nil {
+			results[route] = fmt.Errorf("failed to get NLRI and attributes of route %s: %w", route, err)
+			continue
+		}
+		c.checkPrefixOrigin(route)
+		routePaths[route] = slices.Concat(paths...)
 	}
 
 	// Announce every next hop we have
-	for _, p := range paths {
-		if _, err = c.config.BgpServer.AddPath(apiutil.AddPathRequest{
-			Paths: p,
-		}); err != nil {
-			return fmt.Errorf("failed to add paths %+v: %w", p, err)
+	sendRoutePaths(routePaths, results, func(routes []string, paths []*apiutil.Path) error {
+		if err := c.getAnnouncer().AnnouncePaths(paths); err != nil {
+			return fmt.Errorf("failed to add paths of routes %v: %w", routes, err)
 		}
+		return nil
+	})
+	if c.config.VerifyAnnouncements {
+		c.verifyAnnouncements(routePaths, results)
 	}
 
-	return nil
+	var announced []string
+	for _, route := range routes {
+		if results[route] == nil {
+			c.announced.Add(route, attrs[route])
+			announced = append(announced, route)
+		}
+	}
+	c.events.publish(time.Now(), routeEventAnnounce, announced)
+	return results
 }
 
-// delRoute removes a route we are currently advertising from our BGP speaker
-func (c *Controller) delRoute(route string) error {
-	// Get paths used to withdraw all the next hops possible
-	paths, err := c.getPathRequest(route)
-	if err != nil {
-		return fmt.Errorf("failed to get NLRI and attributes: %w", err)
+// delRoutes removes routes we are currently advertising from our BGP speaker in a single request, and returns
+// the result of each route. Only the routes successfully withdrawn are not recorded as announced anymore.
+func (c *Controller) delRoutes(routes []string) routeResults {
+	results := make(routeResults, len(routes))
+	routePaths := make(map[string][]*apiutil.Path, len(routes))
+	for _, route := range routes {
+		// Get paths used to withdraw all the next hops possible
+		paths, err := c.getPathRequest(route, routeAttributes{})
+		if err != nil {
+			results[route] = fmt.Errorf("failed to get NLRI and attributes of route %s: %w", route, err)
+			continue
+		}
+		routePaths[route] = slices.Concat(paths...)
 	}
 
 	// Withdraw every next hop we have
-	for _, p := range paths {
-		if err = c.config.BgpServer.DeletePath(apiutil.DeletePathRequest{
-			Paths: p,
-		}); err != nil {
-			return fmt.Errorf("failed to delete paths %+v: %w", p, err)
+	sendRoutePaths(routePaths, results, func(routes []string, paths []*apiutil.Path) error {
+		if err := c.getAnnouncer().WithdrawPaths(paths); err != nil {
+			return fmt.Errorf("failed to delete paths of routes %v: %w", routes, err)
+		}
+		return nil
+	})
+
+	var withdrawn []string
+	for _, route := range routes {
+		if results[route] == nil {
+			c.announced.Remove(route)
+			withdrawn = append(withdrawn, route)
 		}
 	}
+	c.events.publish(time.Now(), routeEventWithdraw, withdrawn)
+	return results
+}
 
-	return nil
+// sendRoutePaths sends the paths of routes to the BGP server in a single request, and stores the result of each
+// route in results. The BGP server may have accepted some of the paths of a failed request, so the paths of each
+// route are then sent again in a request of their own, for the routes it accepts to be known.
+func sendRoutePaths(routePaths map[string][]*apiutil.Path, results routeResults, send func(routes []string, paths []*apiutil.Path) error) {
+	routes := slices.Sorted(maps.Keys(routePaths))
+	var paths []*apiutil.Path
+	for _, route := range routes {
+		paths = append(paths, routePaths[route]...)
+	}
+	if len(paths) == 0 {
+		for _, route := range routes {
+			results[route] = nil
+		}
+		return
+	}
+
+	err := send(routes, paths)
+	if err == nil || len(routes) == 1 {
+		for _, route := range routes {
+			results[route] = err
+		}
+		return
+	}
+
+	klog.Warningf("%v, sending the paths of each route separately", err)
+	for _, route := range routes {
+		if len(routePaths[route]) == 0 {
+			results[route] = nil
+			continue
+		}
+		results[route] = send([]string{route}, routePaths[route])
+	}
 }
 
-// getPathRequest returns paths to be used in add/delete path requests for a given route
-func (c *Controller) getPathRequest(route string) ([][]*apiutil.Path, error) {
-	// Should this route be advertised to IPv4 or IPv6 peers
-	// If extended-nexthop is enabled, we advertise IPv4 NLRIs to IPv6 peers and IPv6 NLRIs to IPv4 peers
-	neighborAddresses := c.config.NeighborAddresses
-	if c.config.ExtendedNexthop {
-		neighborAddresses = append(neighborAddresses, c.config.NeighborIPv6Addresses...)
-	} else if util.CheckProtocol(route) == kubeovnv1.ProtocolIPv6 {
-		neighborAddresses = c.config.NeighborIPv6Addresses
+// checkPrefixOrigin logs and counts the announcement of a prefix the cluster AS is not allowed to originate,
+// which upstream routers validating the origin of routes with RPKI would consider invalid
+func (c *Controller) checkPrefixOrigin(route string) {
+	prefix, err := parsePrefix(route)
+	if err != nil || isPrefixOriginAllowed(prefix, c.config.OriginAllowedCIDRs) {
+		return
 	}
+	klog.Warningf("announcing prefix %s which is outside of the CIDRs AS %d is allowed to originate", route, c.config.ClusterAs)
+	metricUnallowedOriginAnnouncements.WithLabelValues(util.CheckProtocol(prefix.Addr().String())).Inc()
+}
 
+// getPathRequest returns paths to be used in add/delete path requests for a given route
+func (c *Controller) getPathRequest(route string, attrs routeAttributes) ([][]*apiutil.Path, error) {
 	// Get the route we're about to advertise and transform it to an NLRI
 	prefix, err := parsePrefix(route)
 	if err != nil {
@@ -151,62 +375,96 @@ func (c *Controller) getPathRequest(route string) ([][]*apiutil.Path, error) {
 	}
 
 	// Create paths to be used in add/delete path request
+	neighborAddresses := c.getRouteNeighbors(route)
 	paths := make([][]*apiutil.Path, 0, len(neighborAddresses))
-	family := &api.Family{Afi: prefixToAFI(prefix), Safi: api.Family_SAFI_UNICAST}
 	for _, addr := range neighborAddresses {
-		path := &api.Path{
-			Family: family,
-			Nlri: &api.NLRI{
-				Nlri: &api.NLRI_Prefix{
-					Prefix: &api.IPAddressPrefix{
-						Prefix:    prefix.Addr().String(),
-						PrefixLen: uint32(prefix.Bits()), // #nosec G115
-					},
+		path, err := c.getNeighborPath(prefix, addr, attrs)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, []*apiutil.Path{path})
+	}
+
+	return paths, nil
+}
+
+// getRouteNeighbors returns the neighbors a route is advertised to
+func (c *Controller) getRouteNeighbors(route string) []net.IP {
+	// Should this route be advertised to IPv4 or IPv6 peers
+	// If extended-nexthop is enabled, we advertise IPv4 NLRIs to IPv6 peers and IPv6 NLRIs to IPv4 peers.
+	// If ipv4-over-ipv6-nexthop is enabled, only IPv4 NLRIs are also advertised to IPv6 peers (RFC 8950).
+	// Neighbors of the secondary BGP instance are included, the export policy filters what they receive.
+	neighborAddresses := slices.Concat(c.config.NeighborAddresses, c.config.SecondaryNeighborAddresses)
+	ipv6NeighborAddresses := slices.Concat(c.config.NeighborIPv6Addresses, c.config.SecondaryNeighborIPv6Addresses)
+	isIPv6 := util.CheckProtocol(route) == kubeovnv1.ProtocolIPv6
+	switch {
+	case c.config.ExtendedNexthop, c.config.IPv4OverIPv6Nexthop && !isIPv6:
+		neighborAddresses = append(neighborAddresses, ipv6NeighborAddresses...)
+	case isIPv6:
+		neighborAddresses = ipv6NeighborAddresses
+	}
+	return neighborAddresses
+}
+
+// getNeighborPath returns the path announcing a prefix to a specific neighbor
+func (c *Controller) getNeighborPath(prefix netip.Prefix, neighborAddress net.IP, attrs routeAttributes) (*apiutil.Path, error) {
+	nlri, safi := c.getPrefixNLRI(prefix)
+	path := &api.Path{
+		Family: &api.Family{Afi: prefixToAFI(prefix), Safi: safi},
+		Nlri:   nlri,
+		Pattrs: []*api.Attribute{{
+			Attr: &api.Attribute_Origin{
+				Origin: &api.OriginAttribute{
+					Origin: uint32(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
 				},
 			},
-			Pattrs: []*api.Attribute{{
-				Attr: &api.Attribute_Origin{
-					Origin: &api.OriginAttribute{
-						Origin: 0,
-					},
+		}, {
+			Attr: &api.Attribute_NextHop{
+				NextHop: &api.NextHopAttribute{
+					NextHop: c.getNextHopAttribute(neighborAddress).String(),
 				},
-			}, {
-				Attr: &api.Attribute_NextHop{
-					NextHop: &api.NextHopAttribute{
-						NextHop: c.getNextHopAttribute(addr).String(),
-					},
-				},
-			}},
-		}
-
-		nativeNlri, err := apiutil.GetNativeNlri(path)
-		if err != nil {
-			return nil, fmt.Errorf("invalid nlri: %w", err)
-		}
-		nativeAttrs, err := apiutil.GetNativePathAttributes(path)
-		if err != nil {
-			return nil, fmt.Errorf("invalid path attributes: %w", err)
-		}
+			},
+		}},
+	}
+	attrs.rpkiCommunity = c.config.RPKICommunities.getCommunity(prefix, c.config.OriginAllowedCIDRs)
+	path.Pattrs = append(path.Pattrs, attrs.toAPIAttributes(c.config.ClusterAs)...)
 
-		paths = append(paths, []*apiutil.Path{{
-			Family: bgp.NewFamily(uint16(path.Family.Afi), uint8(path.Family.Safi)), // #nosec G115
-			Nlri:   nativeNlri,
-			Attrs:  nativeAttrs,
-		}})
+	nativeNlri, err := apiutil.GetNativeNlri(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nlri: %w", err)
+	}
+	nativeAttrs, err := apiutil.GetNativePathAttributes(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path attributes: %w", err)
 	}
 
-	return paths, nil
+	return &apiutil.Path{
+		Family: bgp.NewFamily(uint16(path.Family.Afi), uint8(path.Family.Safi)), // #nosec G115
+		Nlri:   nativeNlri,
+		Attrs:  nativeAttrs,
+	}, nil
 }
 
 // getNextHopAttribute returns the next hop we should advertise for a specific BGP neighbor.
 // When source address whitelisting is enabled, the startup-selected local address is reused.
 // Otherwise, keep the historical behavior and resolve the source address dynamically.
+// The address of the tunnel interface, if configured, takes precedence over both, and the next hop configured
+// for the address family of the neighbor over all of them.
 func (c *Controller) getNextHopAttribute(neighborAddress net.IP) net.IP {
+	if nextHop := c.config.getConfiguredNextHop(neighborAddress); nextHop != nil {
+		return nextHop
+	}
+	if nextHop := c.getTunnelNextHop(neighborAddress); nextHop != nil {
+		return nextHop
+	}
 	if localAddr := c.config.getNeighborLocalAddress(neighborAddress); localAddr != nil {
 		return localAddr
 	}
 
 	nextHop := c.config.RouterID // If no route is found, fallback to router ID
+	if neighborAddress.To4() == nil && c.config.RouterIDv6 != nil {
+		nextHop = c.config.RouterIDv6
+	}
 
 	// Retrieve the route we use to speak to this neighbor and consider the source as next hop.
 	routes, err := netlink.RouteGet(neighborAddress)
@@ -237,3 +495,65 @@ func getNextHopFromPathAttributes(attrs []bgp.PathAttributeInterface) net.IP {
 	}
 	return nil
 }
+
+// toAPIAttributes returns the optional BGP path attributes to add to a path announced from the given AS
+func (a routeAttributes) toAPIAttributes(asn uint32) []*api.Attribute {
+	var attrs []*api.Attribute
+	if a.hasMED {
+		attrs = append(attrs, &api.Attribute{
+			Attr: &api.Attribute_MultiExitDisc{
+				MultiExitDisc: &api.MultiExitDiscAttribute{Med: a.med},
+			},
+		})
+	}
+	if a.hasLocalPref {
+		attrs = append(attrs, &api.Attribute{
+			Attr: &api.Attribute_LocalPref{
+				LocalPref: &api.LocalPrefAttribute{LocalPref: a.localPref},
+			},
+		})
+	}
+	var communities []uint32
+	if a.draining && a.drainingCommunity != 0 {
+		communities = append(communities, a.drainingCommunity)
+	}
+	if a.rpkiCommunity != 0 {
+		communities = append(communities, a.rpkiCommunity)
+	}
+	if a.gracefulShutdownCommunity != 0 {
+		communities = append(communities, a.gracefulShutdownCommunity)
+	}
+	if a.degradedCommunity != 0 {
+		communities = append(communities, a.degradedCommunity)
+	}
+	if len(communities) != 0 {
+		attrs = append(attrs, &api.Attribute{
+			Attr: &api.Attribute_Communities{
+				Communities: &api.CommunitiesAttribute{Communities: communities},
+			},
+		})
+	}
+	if a.linkBandwidth != 0 {
+		attrs = append(attrs, &api.Attribute{
+			Attr: &api.Attribute_ExtendedCommunities{
+				ExtendedCommunities: &api.ExtendedCommunitiesAttribute{
+					Communities: []*api.ExtendedCommunity{linkBandwidthCommunity(asn, a.linkBandwidth)},
+				},
+			},
+		})
+	}
+	return attrs
+}
+
+// linkBandwidthCommunity returns the link bandwidth extended community advertising a bandwidth in bytes per second.
+// The community only has room for a 2-octet AS number, AS_TRANS is used for 4-octet AS numbers.
+func linkBandwidthCommunity(asn uint32, bandwidth float32) *api.ExtendedCommunity {
+	if asn > math.MaxUint16 {
+		asn = bgp.AS_TRANS
+	}
+	return &api.ExtendedCommunity{
+		Extcom: &api.ExtendedCommunity_LinkBandwidth{
+			LinkBandwidth: &api.LinkBandwidthExtended{Asn: asn, Bandwidth: bandwidth},
+		},
+	}
+}
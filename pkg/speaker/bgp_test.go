@@ -0,0 +1,564 @@
+package speaker
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	gobgp "github.com/osrg/gobgp/v4/pkg/server"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// newTestBgpServer starts a BGP server which does not listen for BGP connections
+func newTestBgpServer(t *testing.T) *gobgp.BgpServer {
+	t.Helper()
+	s := gobgp.NewBgpServer()
+	go s.Serve()
+	require.NoError(t, s.StartBgp(context.Background(), &api.StartBgpRequest{
+		Global: &api.Global{Asn: 65000, RouterId: "10.32.32.2", ListenPort: -1},
+	}))
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// listTestBgpServerPrefixes returns the prefixes of an address family found in the global RIB of a BGP server
+func listTestBgpServerPrefixes(t *testing.T, s *gobgp.BgpServer, family bgp.Family) []string {
+	t.Helper()
+	var prefixes []string
+	require.NoError(t, s.ListPath(apiutil.ListPathRequest{
+		TableType: api.TableType_TABLE_TYPE_GLOBAL,
+		Family:    family,
+		SortType:  api.ListPathRequest_SORT_TYPE_PREFIX,
+	}, func(prefix bgp.NLRI, _ []*apiutil.Path) {
+		prefixes = append(prefixes, prefix.String())
+	}))
+	return prefixes
+}
+
+func TestGetPathRequestIPv4OverIPv6Nexthop(t *testing.T) {
+	ipv4Neighbor, ipv6Neighbor := net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")
+	ipv4Local, ipv6Local := net.ParseIP("10.32.32.2"), net.ParseIP("fd00::2")
+
+	tests := []struct {
+		name                string
+		ipv4OverIPv6Nexthop bool
+		nextHopIPv6         net.IP
+		route               string
+		expectedFamily      bgp.Family
+		expectedNextHops    []net.IP
+	}{
+		{
+			name:             "ipv4 prefix is only announced to ipv4 neighbors by default",
+			route:            "192.168.1.1",
+			expectedFamily:   bgp.RF_IPv4_UC,
+			expectedNextHops: []net.IP{ipv4Local},
+		},
+		{
+			name:                "ipv4 prefix is announced to ipv6 neighbors with an ipv6 next hop",
+			ipv4OverIPv6Nexthop: true,
+			route:               "192.168.1.1",
+			expectedFamily:      bgp.RF_IPv4_UC,
+			expectedNextHops:    []net.IP{ipv4Local, ipv6Local},
+		},
+		{
+			name:                "ipv4 prefix is announced to ipv6 neighbors with the configured ipv6 next hop",
+			ipv4OverIPv6Nexthop: true,
+			nextHopIPv6:         net.ParseIP("fd00::100"),
+			route:               "192.168.1.1",
+			expectedFamily:      bgp.RF_IPv4_UC,
+			expectedNextHops:    []net.IP{ipv4Local, net.ParseIP("fd00::100")},
+		},
+		{
+			name:                "ipv6 prefix is not announced to ipv4 neighbors",
+			ipv4OverIPv6Nexthop: true,
+			route:               "2001:db8::1",
+			expectedFamily:      bgp.RF_IPv6_UC,
+			expectedNextHops:    []net.IP{ipv6Local},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{config: &Configuration{
+				NeighborAddresses:     []net.IP{ipv4Neighbor},
+				NeighborIPv6Addresses: []net.IP{ipv6Neighbor},
+				NeighborLocalAddresses: map[string]net.IP{
+					ipv4Neighbor.String(): ipv4Local,
+					ipv6Neighbor.String(): ipv6Local,
+				},
+				IPv4OverIPv6Nexthop: tt.ipv4OverIPv6Nexthop,
+				NextHopIPv6:         tt.nextHopIPv6,
+			}}
+
+			paths, err := c.getPathRequest(tt.route, routeAttributes{})
+			require.NoError(t, err)
+			require.Len(t, paths, len(tt.expectedNextHops))
+			for i, p := range paths {
+				require.Len(t, p, 1)
+				require.Equal(t, tt.expectedFamily, p[0].Family)
+				require.True(t, tt.expectedNextHops[i].Equal(getNextHopFromPathAttributes(p[0].Attrs)))
+			}
+		})
+	}
+}
+
+func TestAddPeerAfiSafi(t *testing.T) {
+	peer := &api.Peer{}
+	addPeerAfiSafi(peer, api.Family_AFI_IP6)
+	addPeerAfiSafi(peer, api.Family_AFI_IP)
+	addPeerAfiSafi(peer, api.Family_AFI_IP6)
+
+	require.Len(t, peer.AfiSafis, 2)
+	require.Equal(t, api.Family_AFI_IP6, peer.AfiSafis[0].Config.Family.Afi)
+	require.Equal(t, api.Family_AFI_IP, peer.AfiSafis[1].Config.Family.Afi)
+	for _, afiSafi := range peer.AfiSafis {
+		require.Equal(t, api.Family_SAFI_UNICAST, afiSafi.Config.Family.Safi)
+		require.True(t, afiSafi.Config.Enabled)
+	}
+}
+
+func TestSetPeerAfiSafis(t *testing.T) {
+	type family struct {
+		afi             api.Family_Afi
+		safi            api.Family_Safi
+		gracefulRestart bool
+	}
+	tests := []struct {
+		name     string
+		config   Configuration
+		ipFamily api.Family_Afi
+		expected []family
+	}{
+		{name: "ipv6 neighbor", ipFamily: api.Family_AFI_IP6},
+		{
+			name:     "ipv4 over ipv6 session",
+			config:   Configuration{IPv4OverIPv6Nexthop: true},
+			ipFamily: api.Family_AFI_IP6,
+			expected: []family{{afi: api.Family_AFI_IP6, safi: api.Family_SAFI_UNICAST}, {afi: api.Family_AFI_IP, safi: api.Family_SAFI_UNICAST}},
+		},
+		{
+			name:     "ipv4 over ipv6 session does not change ipv4 sessions",
+			config:   Configuration{IPv4OverIPv6Nexthop: true},
+			ipFamily: api.Family_AFI_IP,
+		},
+		{
+			name:     "ipv4 over ipv6 session with graceful restart",
+			config:   Configuration{IPv4OverIPv6Nexthop: true, GracefulRestart: true},
+			ipFamily: api.Family_AFI_IP6,
+			expected: []family{
+				{afi: api.Family_AFI_IP6, safi: api.Family_SAFI_UNICAST, gracefulRestart: true},
+				{afi: api.Family_AFI_IP, safi: api.Family_SAFI_UNICAST, gracefulRestart: true},
+			},
+		},
+		{
+			name:     "ipv4 labeled unicast over ipv6 session",
+			config:   Configuration{IPv4OverIPv6Nexthop: true, EnableBgpLU: true},
+			ipFamily: api.Family_AFI_IP6,
+			expected: []family{
+				{afi: api.Family_AFI_IP6, safi: api.Family_SAFI_UNICAST},
+				{afi: api.Family_AFI_IP, safi: api.Family_SAFI_UNICAST},
+				{afi: api.Family_AFI_IP6, safi: api.Family_SAFI_MPLS_LABEL},
+				{afi: api.Family_AFI_IP, safi: api.Family_SAFI_MPLS_LABEL},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := &api.Peer{}
+			tt.config.setPeerAfiSafis(peer, tt.ipFamily)
+			var families []family
+			for _, afiSafi := range peer.AfiSafis {
+				require.True(t, afiSafi.Config.Enabled)
+				families = append(families, family{
+					afi:             afiSafi.Config.Family.Afi,
+					safi:            afiSafi.Config.Family.Safi,
+					gracefulRestart: afiSafi.MpGracefulRestart.GetConfig().GetEnabled(),
+				})
+			}
+			require.Equal(t, tt.expected, families)
+		})
+	}
+}
+
+func TestGetPathRequestAttributes(t *testing.T) {
+	c := &Controller{config: &Configuration{
+		NeighborAddresses:      []net.IP{net.ParseIP("10.32.32.1")},
+		NeighborLocalAddresses: map[string]net.IP{"10.32.32.1": net.ParseIP("10.32.32.2")},
+	}}
+
+	getMED := func(attrs []bgp.PathAttributeInterface) *uint32 {
+		for _, attr := range attrs {
+			if a, ok := attr.(*bgp.PathAttributeMultiExitDisc); ok {
+				return &a.Value
+			}
+		}
+		return nil
+	}
+
+	paths, err := c.getPathRequest("192.168.1.1", routeAttributes{})
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	require.Nil(t, getMED(paths[0][0].Attrs))
+
+	for _, med := range []uint32{0, 100} {
+		paths, err = c.getPathRequest("192.168.1.1", routeAttributes{hasMED: true, med: med})
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		require.NotNil(t, getMED(paths[0][0].Attrs))
+		require.Equal(t, med, *getMED(paths[0][0].Attrs))
+	}
+
+	getLocalPref := func(attrs []bgp.PathAttributeInterface) *uint32 {
+		for _, attr := range attrs {
+			if a, ok := attr.(*bgp.PathAttributeLocalPref); ok {
+				return &a.Value
+			}
+		}
+		return nil
+	}
+	require.Nil(t, getLocalPref(paths[0][0].Attrs))
+	paths, err = c.getPathRequest("192.168.1.1", routeAttributes{hasLocalPref: true, localPref: 50})
+	require.NoError(t, err)
+	require.NotNil(t, getLocalPref(paths[0][0].Attrs))
+	require.Equal(t, uint32(50), *getLocalPref(paths[0][0].Attrs))
+}
+
+func TestLinkBandwidthCommunity(t *testing.T) {
+	tests := []struct {
+		name        string
+		asn         uint32
+		bandwidth   float32
+		expectedAS  uint16
+		expectedHex string
+	}{
+		{name: "2-octet AS", asn: 65000, bandwidth: 125000000, expectedAS: 65000, expectedHex: "4004fde84cee6b28"},
+		{name: "4-octet AS uses AS_TRANS", asn: 4200000000, bandwidth: 125000000, expectedAS: bgp.AS_TRANS, expectedHex: "40045ba04cee6b28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{config: &Configuration{
+				ClusterAs:              tt.asn,
+				NeighborAddresses:      []net.IP{net.ParseIP("10.32.32.1")},
+				NeighborLocalAddresses: map[string]net.IP{"10.32.32.1": net.ParseIP("10.32.32.2")},
+			}}
+
+			paths, err := c.getPathRequest("192.168.1.1", routeAttributes{linkBandwidth: tt.bandwidth})
+			require.NoError(t, err)
+			require.Len(t, paths, 1)
+
+			var community *bgp.LinkBandwidthExtended
+			for _, attr := range paths[0][0].Attrs {
+				if a, ok := attr.(*bgp.PathAttributeExtendedCommunities); ok {
+					require.Len(t, a.Value, 1)
+					community, ok = a.Value[0].(*bgp.LinkBandwidthExtended)
+					require.True(t, ok)
+				}
+			}
+			require.NotNil(t, community)
+			require.Equal(t, tt.expectedAS, community.AS)
+			require.Equal(t, tt.bandwidth, community.Bandwidth)
+
+			buf, err := community.Serialize()
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedHex, hex.EncodeToString(buf))
+		})
+	}
+
+	// no community is advertised without bandwidth
+	c := &Controller{config: &Configuration{
+		NeighborAddresses:      []net.IP{net.ParseIP("10.32.32.1")},
+		NeighborLocalAddresses: map[string]net.IP{"10.32.32.1": net.ParseIP("10.32.32.2")},
+	}}
+	paths, err := c.getPathRequest("192.168.1.1", routeAttributes{})
+	require.NoError(t, err)
+	for _, attr := range paths[0][0].Attrs {
+		require.NotEqual(t, bgp.BGP_ATTR_TYPE_EXTENDED_COMMUNITIES, attr.GetType())
+	}
+}
+
+func TestIsPrefixOriginAllowed(t *testing.T) {
+	allowed := ipNetsToPrefixes([]net.IPNet{
+		{IP: net.ParseIP("203.0.113.0"), Mask: net.CIDRMask(24, 32)},
+		{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)},
+	})
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24"), netip.MustParsePrefix("2001:db8::/32")}, allowed)
+
+	tests := []struct {
+		name    string
+		prefix  string
+		allowed []netip.Prefix
+		expect  bool
+	}{
+		{name: "every prefix is allowed without allowed CIDRs", prefix: "192.168.1.1/32", expect: true},
+		{name: "ipv4 host prefix inside an allowed CIDR", prefix: "203.0.113.10/32", allowed: allowed, expect: true},
+		{name: "ipv6 host prefix inside an allowed CIDR", prefix: "2001:db8::1/128", allowed: allowed, expect: true},
+		{name: "allowed CIDR itself", prefix: "203.0.113.0/24", allowed: allowed, expect: true},
+		{name: "ipv4 prefix outside of the allowed CIDRs", prefix: "192.168.1.1/32", allowed: allowed, expect: false},
+		{name: "ipv6 prefix outside of the allowed CIDRs", prefix: "2001:db9::1/128", allowed: allowed, expect: false},
+		{name: "prefix larger than an allowed CIDR", prefix: "203.0.112.0/23", allowed: allowed, expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expect, isPrefixOriginAllowed(netip.MustParsePrefix(tt.prefix), tt.allowed))
+		})
+	}
+}
+
+func TestCheckPrefixOrigin(t *testing.T) {
+	c := &Controller{config: &Configuration{
+		ClusterAs:          65000,
+		OriginAllowedCIDRs: []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")},
+	}}
+	metricUnallowedOriginAnnouncements.Reset()
+
+	c.checkPrefixOrigin("203.0.113.10")
+	require.Zero(t, testutil.ToFloat64(metricUnallowedOriginAnnouncements.WithLabelValues(kubeovnv1.ProtocolIPv4)))
+
+	c.checkPrefixOrigin("192.168.1.1")
+	c.checkPrefixOrigin("192.168.1.1/32")
+	require.Equal(t, float64(2), testutil.ToFloat64(metricUnallowedOriginAnnouncements.WithLabelValues(kubeovnv1.ProtocolIPv4)))
+
+	// The counters are by protocol and not by prefix to keep the cardinality of the metric bounded
+	c.checkPrefixOrigin("2001:db8::1")
+	require.Equal(t, float64(1), testutil.ToFloat64(metricUnallowedOriginAnnouncements.WithLabelValues(kubeovnv1.ProtocolIPv6)))
+}
+
+func TestGetPathRequestOrigin(t *testing.T) {
+	c := &Controller{config: &Configuration{
+		ClusterAs:              65000,
+		NeighborAddresses:      []net.IP{net.ParseIP("10.32.32.1")},
+		NeighborLocalAddresses: map[string]net.IP{"10.32.32.1": net.ParseIP("10.32.32.2")},
+	}}
+
+	paths, err := c.getPathRequest("192.168.1.1", routeAttributes{})
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	// Routes are originated by the speaker: the origin is IGP and the AS path is left to the BGP server,
+	// which prepends the cluster AS when announcing them to eBGP neighbors
+	var origin *bgp.PathAttributeOrigin
+	for _, attr := range paths[0][0].Attrs {
+		switch a := attr.(type) {
+		case *bgp.PathAttributeOrigin:
+			origin = a
+		case *bgp.PathAttributeAsPath:
+			require.Empty(t, a.Value)
+		}
+	}
+	require.NotNil(t, origin)
+	require.Equal(t, bgp.BGP_ORIGIN_ATTR_TYPE_IGP, origin.Value)
+}
+
+func TestBatchRoutes(t *testing.T) {
+	routes := set.New("192.168.1.3/32", "192.168.1.1/32", "192.168.1.2/32", "10.0.0.1/32", "10.0.0.2/32", "172.16.0.1/32")
+	attrs := prefixAttributes{
+		"192.168.1.1/32": {batch: "external-a"},
+		"192.168.1.2/32": {batch: "external-a", hasMED: true, med: 10},
+		"192.168.1.3/32": {batch: "external-a"},
+		"10.0.0.1/32":    {batch: "external-b"},
+		"10.0.0.2/32":    {batch: "external-b"},
+	}
+
+	require.Equal(t, [][]string{
+		{"172.16.0.1/32"},
+		{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32"},
+		{"10.0.0.1/32", "10.0.0.2/32"},
+	}, batchRoutes(routes, attrs))
+	require.Empty(t, batchRoutes(set.New[string](), attrs))
+}
+
+func TestAnnounceAndWithdrawBatches(t *testing.T) {
+	s := newTestBgpServer(t)
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:              s,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced: newAnnouncedStore(),
+	}
+
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	var eips []*kubeovnv1.IptablesEIP
+	for i := range 10 {
+		eip := newTestEIP(fmt.Sprintf("eip-%d", i), fmt.Sprintf("192.168.1.%d", i+1), "", true, bgpAnnotation)
+		eip.Spec.ExternalSubnet = "external"
+		eips = append(eips, eip)
+	}
+
+	// The EIPs of an external subnet are announced in a single batch
+	prefixes, attrs := getEIPExpectedPrefixes(eips, routeAttributes{})
+	toAdd, toDel := c.announced.Diff(api.Family_AFI_IP, prefixes[api.Family_AFI_IP], attrs)
+	batches := batchRoutes(toAdd, attrs)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], len(eips))
+
+	c.announceAndWithdraw(toAdd, toDel, attrs, newAnnounceQueue(0))
+	require.Len(t, c.announced.List(), len(eips))
+	require.Len(t, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC), len(eips))
+
+	// and withdrawn in a single batch
+	toAdd, toDel = c.announced.Diff(api.Family_AFI_IP, nil, nil)
+	require.Empty(t, toAdd)
+	batches = batchRoutes(toDel, c.announced.Attributes())
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], len(eips))
+
+	c.announceAndWithdraw(toAdd, toDel, nil, newAnnounceQueue(0))
+	require.Empty(t, c.announced.List())
+	require.Empty(t, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+}
+
+// fakeAnnouncer is a RouteAnnouncer keeping the announced prefixes in memory and recording its calls.
+// It fails to announce and withdraw the paths of the prefixes in failing, and accepts the paths of the prefixes
+// in rejected without announcing them, like a policy rejecting them would.
+type fakeAnnouncer struct {
+	announced set.Set[string]
+	failing   set.Set[string]
+	rejected  set.Set[string]
+	calls     []fakeAnnouncerCall
+}
+
+// fakeAnnouncerCall is a call to a fakeAnnouncer, with the sorted prefixes of its paths
+type fakeAnnouncerCall struct {
+	withdraw bool
+	prefixes []string
+}
+
+func newFakeAnnouncer() *fakeAnnouncer {
+	return &fakeAnnouncer{announced: set.New[string](), failing: set.New[string](), rejected: set.New[string]()}
+}
+
+func (a *fakeAnnouncer) record(withdraw bool, paths []*apiutil.Path) ([]string, error) {
+	prefixes := set.New[string]()
+	for _, p := range paths {
+		prefixes.Insert(p.Nlri.String())
+	}
+	a.calls = append(a.calls, fakeAnnouncerCall{withdraw: withdraw, prefixes: prefixes.SortedList()})
+	if failing := prefixes.Intersection(a.failing); failing.Len() != 0 {
+		return nil, fmt.Errorf("paths of %v rejected", failing.SortedList())
+	}
+	return prefixes.UnsortedList(), nil
+}
+
+func (a *fakeAnnouncer) AnnouncePaths(paths []*apiutil.Path) error {
+	prefixes, err := a.record(false, paths)
+	a.announced.Insert(set.New(prefixes...).Difference(a.rejected).UnsortedList()...)
+	return err
+}
+
+func (a *fakeAnnouncer) WithdrawPaths(paths []*apiutil.Path) error {
+	prefixes, err := a.record(true, paths)
+	a.announced.Delete(prefixes...)
+	return err
+}
+
+func (a *fakeAnnouncer) IsRouteAnnounced(route string) (bool, error) {
+	return a.announced.Has(route), nil
+}
+
+func (a *fakeAnnouncer) isRouteAnnounced(prefix string) bool {
+	return a.announced.Has(prefix)
+}
+
+func TestReconcileRoutesPartialFailure(t *testing.T) {
+	a := newFakeAnnouncer()
+	a.failing.Insert("192.168.1.2/32")
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced: newAnnouncedStore(),
+		announcer: a,
+	}
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32")}
+
+	// The routes announced despite the failure of the batch are recorded, the failed one is not
+	results := c.reconcileRoutes(expected, nil)
+	require.Len(t, results, 3)
+	require.Equal(t, []string{"192.168.1.2/32"}, results.failed())
+	require.Error(t, results.err())
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.3/32"}, c.announced.List())
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.3/32"}, a.announced.SortedList())
+	require.Equal(t, []fakeAnnouncerCall{
+		{prefixes: []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32"}},
+		{prefixes: []string{"192.168.1.1/32"}},
+		{prefixes: []string{"192.168.1.2/32"}},
+		{prefixes: []string{"192.168.1.3/32"}},
+	}, a.calls)
+
+	// Only the failed route is retried by the next reconciliation
+	a.failing.Clear()
+	a.calls = nil
+	results = c.reconcileRoutes(expected, nil)
+	require.Equal(t, routeResults{"192.168.1.2/32": nil}, results)
+	require.NoError(t, results.err())
+	require.Equal(t, []fakeAnnouncerCall{{prefixes: []string{"192.168.1.2/32"}}}, a.calls)
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32"}, c.announced.List())
+
+	// A route failing to be withdrawn is still recorded as announced
+	a.failing.Insert("192.168.1.3/32")
+	results = c.reconcileRoutes(make(prefixMap), nil)
+	require.Equal(t, []string{"192.168.1.3/32"}, results.failed())
+	require.Equal(t, []string{"192.168.1.3/32"}, c.announced.List())
+	require.True(t, a.isRouteAnnounced("192.168.1.3/32"))
+
+	a.failing.Clear()
+	require.Empty(t, c.reconcileRoutes(make(prefixMap), nil).failed())
+	require.Empty(t, c.announced.List())
+	require.Empty(t, a.announced)
+}
+
+func TestReconcileRoutesUnchangedAttributes(t *testing.T) {
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced: newAnnouncedStore(),
+		announcer: a,
+	}
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32", "192.168.1.2/32")}
+	attrs := prefixAttributes{
+		"192.168.1.1/32": {hasMED: true, med: 100, draining: true, drainingCommunity: 65000<<16 | 100},
+		"192.168.1.2/32": {hasLocalPref: true, localPref: 200, linkBandwidth: 125000},
+	}
+	require.Empty(t, c.reconcileRoutes(expected, attrs).failed())
+	require.Len(t, a.calls, 1)
+
+	// Routes announced with the same attributes are not advertised again
+	a.calls = nil
+	require.Empty(t, c.reconcileRoutes(expected, maps.Clone(attrs)))
+	require.Empty(t, a.calls)
+
+	// Only the route whose attributes changed is advertised again
+	changed := maps.Clone(attrs)
+	changed["192.168.1.1/32"] = routeAttributes{hasMED: true, med: 100}
+	require.Equal(t, routeResults{"192.168.1.1/32": nil}, c.reconcileRoutes(expected, changed))
+	require.Equal(t, []fakeAnnouncerCall{{prefixes: []string{"192.168.1.1/32"}}}, a.calls)
+	require.Equal(t, changed, c.announced.Attributes())
+
+	a.calls = nil
+	require.Empty(t, c.reconcileRoutes(expected, changed))
+	require.Empty(t, a.calls)
+}
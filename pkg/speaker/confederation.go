@@ -0,0 +1,57 @@
+package speaker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/osrg/gobgp/v4/api"
+)
+
+// parseConfederationMembers parses the AS numbers of the members of the confederation the cluster AS belongs to.
+// The members are the sub-ASes of the confederation, the confederation identifier being the AS it is seen as from
+// the outside.
+func parseConfederationMembers(identifier, clusterAs uint32, members []string) ([]uint32, error) {
+	if identifier == 0 {
+		if len(members) != 0 {
+			return nil, errors.New("--confederation-members requires --confederation-id")
+		}
+		return nil, nil
+	}
+	if identifier == clusterAs {
+		return nil, fmt.Errorf("the confederation identifier %d must not be the cluster AS, which is a member of the confederation", identifier)
+	}
+	if len(members) == 0 {
+		return nil, errors.New("--confederation-id requires --confederation-members")
+	}
+
+	result := make([]uint32, 0, len(members))
+	for _, member := range members {
+		asn, err := strconv.ParseUint(member, 10, 32)
+		if err != nil || asn == 0 {
+			return nil, fmt.Errorf("invalid member AS %q: must be an integer between 1 and 4294967295", member)
+		}
+		if uint32(asn) == identifier {
+			return nil, fmt.Errorf("the confederation identifier %d must not be a member AS", identifier)
+		}
+		if slices.Contains(result, uint32(asn)) {
+			return nil, fmt.Errorf("member AS %d is repeated", asn)
+		}
+		result = append(result, uint32(asn))
+	}
+	return result, nil
+}
+
+// getConfederation returns the confederation of the global configuration of the BGP server, nil if the cluster AS
+// does not belong to any
+func (config *Configuration) getConfederation() *api.Confederation {
+	if config.ConfederationID == 0 {
+		return nil
+	}
+	return &api.Confederation{
+		Enabled:      true,
+		Identifier:   config.ConfederationID,
+		MemberAsList: config.ConfederationMembers,
+	}
+}
@@ -0,0 +1,93 @@
+package speaker
+
+import (
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfederationMembers(t *testing.T) {
+	tests := []struct {
+		name        string
+		identifier  uint32
+		members     []string
+		expected    []uint32
+		errContains string
+	}{
+		{
+			name: "no confederation",
+		},
+		{
+			name:       "confederation",
+			identifier: 64500,
+			members:    []string{"65000", "65001", "4200000000"},
+			expected:   []uint32{65000, 65001, 4200000000},
+		},
+		{
+			name:        "members without identifier",
+			members:     []string{"65001"},
+			errContains: "requires --confederation-id",
+		},
+		{
+			name:        "identifier without members",
+			identifier:  64500,
+			errContains: "requires --confederation-members",
+		},
+		{
+			name:        "identifier is the cluster AS",
+			identifier:  65000,
+			members:     []string{"65001"},
+			errContains: "must not be the cluster AS",
+		},
+		{
+			name:        "identifier is a member",
+			identifier:  64500,
+			members:     []string{"65001", "64500"},
+			errContains: "must not be a member AS",
+		},
+		{
+			name:        "repeated member",
+			identifier:  64500,
+			members:     []string{"65001", "65001"},
+			errContains: "member AS 65001 is repeated",
+		},
+		{
+			name:        "zero member",
+			identifier:  64500,
+			members:     []string{"0"},
+			errContains: `invalid member AS "0"`,
+		},
+		{
+			name:        "member out of range",
+			identifier:  64500,
+			members:     []string{"4294967296"},
+			errContains: `invalid member AS "4294967296"`,
+		},
+		{
+			name:        "invalid member",
+			identifier:  64500,
+			members:     []string{"AS65001"},
+			errContains: `invalid member AS "AS65001"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := parseConfederationMembers(tt.identifier, 65000, tt.members)
+			if tt.errContains != "" {
+				require.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, members)
+		})
+	}
+}
+
+func TestGetConfederation(t *testing.T) {
+	config := &Configuration{ClusterAs: 65000}
+	require.Nil(t, config.getConfederation())
+
+	config.ConfederationID, config.ConfederationMembers = 64500, []uint32{65000, 65001}
+	require.Equal(t, &api.Confederation{Enabled: true, Identifier: 64500, MemberAsList: []uint32{65000, 65001}}, config.getConfederation())
+}
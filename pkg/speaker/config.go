@@ -6,9 +6,12 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"math"
 	"net"
+	"net/netip"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +25,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	clientset "github.com/kubeovn/kube-ovn/pkg/client/clientset/versioned"
@@ -44,6 +48,9 @@ type Configuration struct {
 	GrpcPort                    int32
 	ClusterAs                   uint32
 	RouterID                    net.IP
+	RouterIDv6                  net.IP
+	NextHopIPv4                 net.IP
+	NextHopIPv6                 net.IP
 	PodIPs                      map[string]net.IP
 	NodeIPs                     map[string]net.IP
 	NeighborAddresses           []net.IP
@@ -61,9 +68,74 @@ type Configuration struct {
 	GracefulRestartTime         time.Duration
 	PassiveMode                 bool
 	EbgpMultihopTTL             uint8
+	EnableGTSM                  bool
 	ExtendedNexthop             bool
+	IPv4OverIPv6Nexthop         bool
+	ReadvertiseOnEstablished    bool
 	NatGwMode                   bool
 	EnableMetrics               bool
+	AnnounceSchedule            announceSchedule
+	AnnounceGateFile            string
+	OriginAllowedCIDRs          []netip.Prefix
+	RPKICommunities             rpkiCommunities
+	StaticAnnounceCIDRs         []netip.Prefix
+	NodeAnnounceAddresses       []netip.Addr
+	SRv6Locator                 netip.Prefix
+	EnableBgpLU                 bool
+	NeighborMaxPrefixes         map[string]int
+	RoutesSnapshotFile          string
+	StateFile                   string
+	RouteEventsSocket           string
+	RoutesRefreshInterval       time.Duration
+	CacheSyncTimeout            time.Duration
+	InitialAnnounceDelay        time.Duration
+	WithdrawRate                float64
+	WithdrawBurst               int
+	AnnounceQueueSize           int
+	DrainingCommunity           uint32
+	DegradedCommunity           uint32
+	HealthProbeContainer        string
+	GracefulShutdownTime        time.Duration
+	GracefulShutdownCommunity   uint32
+	DefaultLocalPref            *uint32
+	ExternalSubnetFilter        []string
+	VpcAllowlist                []string
+	AnnounceOnCondition         string
+	SoftReconfigurationInbound  bool
+	DetectRouteLoops            bool
+	VerifyAnnouncements         bool
+	ValidateNextHopReachability bool
+	TunnelInterface             string
+	NodeWeightLabel             string
+	NodeWeightAttribute         string
+	ConfederationID             uint32
+	ConfederationMembers        []uint32
+	WithdrawOnTerminating       bool
+	SingleAnnouncerByName       bool
+	// PointToPointSubnets are the external subnets whose EIPs are announced with the /31 or /127 prefix of their
+	// point-to-point link rather than a host prefix
+	PointToPointSubnets set.Set[string]
+	// PodName and PodNamespace identify the pod of the speaker
+	PodName      string
+	PodNamespace string
+	// AutoNeighborAs is the range of the AS numbers accepted from the neighbors whose AS is not configured,
+	// the AS of every neighbor must be configured if nil
+	AutoNeighborAs *asRange
+	// SubnetNeighbors associates external subnets and the sorted comma separated list of the addresses of the only
+	// neighbors the routes of their EIPs are advertised to
+	SubnetNeighbors map[string]string
+
+	// With leader election, the routes are only announced while holding the lease LeaderElectionLease of
+	// LeaderElectionNamespace, LeaderElectionIdentity identifying the speaker
+	EnableLeaderElection    bool
+	LeaderElectionNamespace string
+	LeaderElectionLease     string
+	LeaderElectionIdentity  string
+
+	// Secondary neighbors only receive the routes of the EIPs selected for the secondary BGP instance
+	SecondaryNeighborAddresses     []net.IP
+	SecondaryNeighborIPv6Addresses []net.IP
+	SecondaryNeighborAs            uint32
 
 	NodeName       string
 	KubeConfigFile string
@@ -84,22 +156,80 @@ func ParseFlags() (*Configuration, error) {
 		argGrpcPort                    = pflag.Int32("grpc-port", DefaultBGPGrpcPort, "The port for grpc to listen, default:50051")
 		argClusterAs                   = pflag.Uint32("cluster-as", 0, "The AS number of the local BGP speaker (required)")
 		argRouterID                    = pflag.IP("router-id", nil, "The address for the speaker to use as router id, default the node ip")
+		argRouterIDv4                  = pflag.IP("router-id-v4", nil, "The IPv4 address for the speaker to use as router id, exclusive with --router-id")
+		argRouterIDv6                  = pflag.IP("router-id-v6", nil, "The IPv6 router id of the speaker, used as next hop of the IPv6 neighbors when no source address is found for them. BGP identifiers being IPv4 addresses, --router-id or --router-id-v4 is still the BGP identifier")
+		argNextHopIPv4                 = pflag.IP("nexthop-v4", nil, "The IPv4 next hop advertised to the IPv4 neighbors, instead of the one derived from the route to each neighbor")
+		argNextHopIPv6                 = pflag.IP("nexthop-v6", nil, "The IPv6 next hop advertised to the IPv6 neighbors, instead of the one derived from the route to each neighbor")
 		argNodeIPs                     = pflag.IPSlice("node-ips", nil, "The comma-separated list of node IP addresses to use instead of the pod IP address for the next hop router IP address.")
 		argNeighborAddress             = pflag.IPSlice("neighbor-address", nil, "Comma separated IPv4 router addresses the speaker connects to.")
 		argNeighborIPv6Address         = pflag.IPSlice("neighbor-ipv6-address", nil, "Comma separated IPv6 router addresses the speaker connects to.")
 		argAllowedSourceAddresses      = pflag.IPSlice("allowed-source-addresses", nil, "Comma separated IPv4 source addresses allowed for BGP peering and next-hop advertisement.")
 		argAllowedSourceIPv6Addresses  = pflag.IPSlice("allowed-source-ipv6-addresses", nil, "Comma separated IPv6 source addresses allowed for BGP peering and next-hop advertisement.")
 		argNeighborAs                  = pflag.Uint32("neighbor-as", 0, "The AS number of the BGP neighbor/peer (required)")
+		argSecondaryNeighborAddress    = pflag.IPSlice("secondary-neighbor-address", nil, "Comma separated IPv4 router addresses of the secondary BGP instance the speaker connects to, only EIPs selected with the bgp-instance annotation are announced to them")
+		argSecondaryNeighborIPv6       = pflag.IPSlice("secondary-neighbor-ipv6-address", nil, "Comma separated IPv6 router addresses of the secondary BGP instance the speaker connects to, only EIPs selected with the bgp-instance annotation are announced to them")
+		argSecondaryNeighborAs         = pflag.Uint32("secondary-neighbor-as", 0, "The AS number of the secondary BGP neighbors, default to the AS number of the BGP neighbors")
 		argAuthPassword                = pflag.String("auth-password", "", "bgp peer auth password")
 		argHoldTime                    = pflag.Duration("holdtime", DefaultBGPHoldtime, "ovn-speaker goes down abnormally, the local saving time of BGP route will be affected.Holdtime must be in the range 3s to 65536s. (default 90s)")
 		argPprofPort                   = pflag.Int32("pprof-port", DefaultPprofPort, "The port to get profiling data, default: 10667")
 		argNodeName                    = pflag.String("node-name", os.Getenv(util.EnvNodeName), "Name of the node on which the speaker is running on.")
 		argKubeConfigFile              = pflag.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information. If not set use the inCluster token.")
 		argPassiveMode                 = pflag.BoolP("passivemode", "", false, "Set BGP Speaker to passive model, do not actively initiate connections to peers")
-		argEbgpMultihopTTL             = pflag.Uint8("ebgp-multihop", DefaultEbgpMultiHop, "The TTL value of EBGP peer, default: 1")
+		argEbgpMultihop                = pflag.Uint8("ebgp-multihop", DefaultEbgpMultiHop, "The TTL value of EBGP peer, ignored if --ebgp-multihop-ttl is set, default: 1")
+		argEbgpMultihopTTL             = pflag.Uint8("ebgp-multihop-ttl", DefaultEbgpMultiHop, "The TTL of the packets sent to the EBGP peers, or the maximum number of hops to the peers with --enable-gtsm, between 1 and 255. Takes precedence over --ebgp-multihop, default: the value of --ebgp-multihop")
+		argEnableGTSM                  = pflag.Bool("enable-gtsm", false, "Enable the Generalized TTL Security Mechanism (RFC 5082) on the BGP sessions: packets are sent with a TTL of 255 and only the packets of peers at most --ebgp-multihop-ttl hops away are accepted. EBGP multihop is then not used")
 		argExtendedNexthop             = pflag.BoolP("extended-nexthop", "", false, "Announce IPv4/IPv6 prefixes to every neighbor, no matter their AFI")
+		argIPv4OverIPv6Nexthop         = pflag.BoolP("ipv4-over-ipv6-nexthop", "", false, "Announce IPv4 prefixes to IPv6 neighbors with an IPv6 next hop (RFC 8950), e.g. for BGP unnumbered fabrics")
+		argReadvertiseOnEstablished    = pflag.Bool("readvertise-on-session-established", false, "Advertise again all the routes announced to a neighbor when the BGP session with it is established again, for the backends which do not keep them across session flaps")
 		argNatGwMode                   = pflag.BoolP("nat-gw-mode", "", false, "Make the BGP speaker announce EIPs from inside a NAT gateway, Pod IP/Service/Subnet announcements will be disabled")
 		argEnableMetrics               = pflag.BoolP("enable-metrics", "", true, "Whether to support metrics query")
+		argAnnounceSchedule            = pflag.String("announce-schedule", "", "Comma separated daily windows during which routes are announced, e.g. \"Mon-Fri 08:00-20:00,Sat 10:00-14:00\". Routes are withdrawn outside of the windows. Empty means always")
+		argOriginAllowedCIDRs          = pflag.IPNetSlice("origin-allowed-cidrs", nil, "Comma separated CIDRs the cluster AS is allowed to originate, e.g. registered in RPKI ROAs. Announcing a prefix outside of them is logged and counted in metrics. Every prefix is allowed if empty")
+		argAnnounceNodeAddress         = pflag.Bool("announce-node-address", false, "Announce the addresses of the node as host routes for its reachability, independently of the pods, services, subnets or EIPs. The global addresses of the loopback interface are announced unless --node-announce-addresses is set")
+		argNodeAnnounceAddresses       = pflag.IPSlice("node-announce-addresses", nil, "Comma separated addresses of the node announced with --announce-node-address instead of the addresses of the loopback interface, e.g. a management address")
+		argRPKIValidCommunity          = pflag.String("rpki-valid-community", "", "Community in the \"ASN:value\" format the routes covered by --origin-allowed-cidrs are tagged with")
+		argRPKIUnknownCommunity        = pflag.String("rpki-unknown-community", "", "Community in the \"ASN:value\" format the routes not covered by --origin-allowed-cidrs are tagged with")
+		argSRv6Locator                 = pflag.String("announce-srv6-locator", "", "IPv6 SRv6 locator prefix of the node always announced along with the routes of the EIPs, e.g. \"fd00:0:1::/48\", so that the segments of the node are reachable. Requires IPv6 neighbors or --extended-nexthop. Not announced if empty")
+		argEnableBgpLU                 = pflag.Bool("enable-bgp-lu", false, "Announce the routes as labeled unicast (BGP-LU, RFC 8277) with the implicit null label, for MPLS-enabled upstream routers. The upstream routers pop the label and forward the traffic to the next hop as IP")
+		argStaticAnnounceCIDRs         = pflag.IPNetSlice("static-announce-cidrs", nil, "Comma separated CIDRs always announced in addition to the routes of the pods, services, subnets or EIPs, e.g. for a management VIP")
+		argNeighborMaxPrefixes         = pflag.StringToInt("neighbor-max-prefixes", nil, "Comma separated maximum numbers of prefixes advertised to neighbors, e.g. \"10.0.0.1=100,fd00::1=100\". Additional prefixes are not advertised to a neighbor once its limit is reached")
+		argAnnounceOnCondition         = pflag.String("announce-on-condition", "", "Type of the status condition of the EIPs which must also be True for them to be announced, e.g. when they are ready before the application behind them. EIPs are announced once ready if empty")
+		argExternalSubnetFilter        = pflag.StringSlice("external-subnet-filter", nil, "Comma separated names of the external subnets whose EIPs are announced, the EIPs on other external subnets are ignored. The EIPs of every external subnet are announced if empty")
+		argVpcAllowlist                = pflag.StringSlice("vpc-allowlist", nil, "Comma separated names of the VPCs whose EIPs are announced, the VPC of an EIP being the VPC of its NAT gateway. EIPs of other VPCs, or whose VPC cannot be resolved, are withdrawn. The EIPs of every VPC are announced if empty")
+		argSubnetNeighborMap           = pflag.StringArray("subnet-neighbor-map", nil, "Neighbors the routes of the EIPs of an external subnet are only advertised to, e.g. \"external1=10.0.0.1,10.0.0.2\", can be repeated for each external subnet. The bgp-neighbor annotation of an EIP takes precedence")
+		argAnnounceGateFile            = pflag.String("announce-gate-file", "", "Path of a file gating announcements, routes are only announced while it exists and contains \"ready\" and are withdrawn otherwise. Announcements are not gated if empty")
+		argRoutesSnapshotFile          = pflag.String("routes-snapshot-file", "", "Path of a file the snapshot of the desired and announced routes is also written to on SIGUSR1, the snapshot is only logged if empty")
+		argStateFile                   = pflag.String("state-file", "", "Path of a local file the announced routes and their attributes are saved to whenever they change, and restored from at startup so that they are announced again before the informer caches sync, e.g. after a crash. The routes not expected anymore are withdrawn once the caches synced. The announced routes are not saved if empty")
+		argRouteEventsSocket           = pflag.String("route-events-socket", "", "Path of a UNIX socket the announcements and withdrawals of routes are published to as newline-delimited JSON, e.g. for a sidecar mirroring them. The oldest events of a subscriber not reading them fast enough are dropped. Events are not published if empty")
+		argAutoNeighborAs              = pflag.Bool("auto-neighbor-as", false, "Accept the AS advertised by the BGP neighbors when --neighbor-as or --secondary-neighbor-as is not set, as long as it is in --auto-neighbor-as-range")
+		argAutoNeighborAsRange         = pflag.String("auto-neighbor-as-range", "", "Range of the AS numbers accepted from the BGP neighbors with --auto-neighbor-as, e.g. \"64512-65534\". Every AS is accepted if empty")
+		argRoutesRefreshInterval       = pflag.Duration("routes-refresh-interval", 0, "Interval at which all the announced routes are advertised again to the neighbors, so that they can expire the routes of a speaker which stopped refreshing them. Routes are not refreshed if zero")
+		argDefaultLocalPref            = pflag.String("default-local-pref", "", "LOCAL_PREF the routes of the EIPs are announced with unless overridden by their bgp-local-pref annotation, between 0 and 4294967295. LOCAL_PREF is only advertised to iBGP neighbors. Routes are announced without LOCAL_PREF if empty")
+		argDegradedCommunity           = pflag.String("degraded-community", "", "Community in the \"ASN:value\" format the routes of the EIPs are announced with while the NAT gateway pod is running but its --health-probe-container is not ready, so that upstream routers deprioritize them rather than losing them. Requires --nat-gw-mode and the POD_NAME and POD_NAMESPACE environment variables. Not advertised if empty")
+		argHealthProbeContainer        = pflag.String("health-probe-container", "vpc-nat-gw", "Container of the NAT gateway pod whose readiness probe checks the health of the gateway for --degraded-community")
+		argDrainingCommunity           = pflag.String("draining-community", "", "Community in the \"ASN:value\" format the routes of drained EIPs are announced with instead of being withdrawn, so that monitoring can tell a graceful drain from a crash. Drained EIPs are withdrawn if empty")
+		argGracefulShutdownTime        = pflag.Duration("graceful-shutdown-time", 0, "Time the routes are announced with --graceful-shutdown-community when the speaker is stopped before being withdrawn, so that the neighbors move the traffic to other paths first. Must be shorter than the termination grace period of the pod. Routes are not withdrawn on shutdown if zero")
+		argGracefulShutdownCommunity   = pflag.String("graceful-shutdown-community", defaultGracefulShutdownCommunity, "Community in the \"ASN:value\" format the routes are announced with during --graceful-shutdown-time, the GRACEFUL_SHUTDOWN community of RFC 8326 by default")
+		argSoftReconfigInbound         = pflag.Bool("soft-reconfiguration-inbound", false, "Serve the routes received from each neighbor, before import policies, as JSON on /debug/routes of the metrics server to troubleshoot them without resetting the sessions")
+		argDetectRouteLoops            = pflag.Bool("detect-route-loops", false, "Warn about the announced prefixes also received from a neighbor, which indicates a routing loop or a misconfiguration, on each reconciliation and count them in metrics by neighbor. Requires --soft-reconfiguration-inbound")
+		argVerifyAnnouncements         = pflag.Bool("verify-announcements", false, "Check that the announced routes are in the RIB of the BGP server within a second before recording them as announced, so that routes whose paths are rejected by a policy are announced again by the next reconciliation")
+		argValidateNextHop             = pflag.Bool("validate-nexthop-reachability", false, "Only advertise the routes to the neighbors directly connected to the node through the network of the next hop advertised to them, checked with a route lookup on each reconciliation. Some neighbors drop the routes whose next hop is not directly connected. An event is recorded when the next hop of a neighbor becomes unreachable")
+		argTunnelInterface             = pflag.String("tunnel-interface", "", "Name of the GRE or IPIP tunnel interface the neighbors are reached through, e.g. in overlay scenarios. Its address of the family of each neighbor is advertised as next hop to it instead of the source address of the route to the neighbor")
+		argNodeWeightLabel             = pflag.String("node-weight-label", "", "Label of the node of the speaker holding its anycast weight, e.g. \"bgp-weight\", advertised with --node-weight-attribute on the routes of the EIPs unless their GW sets the attribute. The weight is not advertised if empty")
+		argNodeWeightAttribute         = pflag.String("node-weight-attribute", nodeWeightMED, "Attribute the weight of the node is advertised with: \"med\", the weight being converted to a MED like the bgp-priority annotation of a GW, or \"link-bandwidth\", the weight being a bandwidth in Mbit/s")
+		argConfederationID             = pflag.Uint32("confederation-id", 0, "Identifier of the BGP confederation the cluster AS is a member of, the AS the confederation is seen as by the neighbors outside of it. The cluster AS does not belong to a confederation if zero")
+		argConfederationMembers        = pflag.StringSlice("confederation-members", nil, "Comma separated AS numbers of the member ASes of the confederation of --confederation-id, the neighbors of these ASes being confederation neighbors")
+		argWithdrawOnTerminating       = pflag.Bool("withdraw-on-terminating", false, "Withdraw the routes as soon as the pod of the speaker, e.g. a NAT gateway pod, is terminating, so that the traffic is drained before the pod stops. Requires the POD_NAME and POD_NAMESPACE environment variables")
+		argSingleAnnouncerByName       = pflag.Bool("single-announcer-by-name", false, "Only announce the routes of the EIPs from the node with the lowest name among the nodes running a pod of the NAT gateway, so that a single speaker announces them without leader election. Requires --nat-gw-mode and --node-name")
+		argPointToPointSubnets         = pflag.StringSlice("point-to-point-subnets", nil, "Comma separated external subnets whose EIPs egress through /31 or /127 point-to-point links, the routes of their EIPs being announced with the prefix of the link rather than a /32 or /128")
+		argWithdrawRate                = pflag.Float64("withdraw-rate", 0, "Maximum number of routes withdrawn per second once --withdraw-burst routes were withdrawn at once, e.g. on the deletion of a subnet, so that fragile upstream routers are not overwhelmed. Announcements are not paced. Withdrawals are not paced if zero")
+		argWithdrawBurst               = pflag.Int("withdraw-burst", 100, "Number of routes withdrawn at once before withdrawals are paced by --withdraw-rate")
+		argAnnounceQueueSize           = pflag.Int("announce-queue-size", 0, "Maximum number of routes announced by a reconciliation so that a BGP server backed up by a slow neighbor is not flooded, the other routes being announced by the next reconciliations. Once an announcement fails, the remaining routes wait for the next reconciliation. Announcements are not bounded if zero")
+		argEnableLeaderElection        = pflag.Bool("enable-leader-election", false, "Only announce the routes while holding a Lease, so that a single speaker among its replicas announces them at once. The routes are withdrawn when the Lease is lost")
+		argLeaderElectionLease         = pflag.String("leader-election-lease", "kube-ovn-speaker", "Name of the Lease held by the speaker announcing the routes with --enable-leader-election")
+		argLeaderElectionNamespace     = pflag.String("leader-election-namespace", os.Getenv(util.EnvPodNamespace), "Namespace of the Lease held by the speaker announcing the routes with --enable-leader-election, default to the namespace of the pod")
+		argCacheSyncTimeout            = pflag.Duration("cache-sync-timeout", 0, "Maximum time to wait for the informer caches to sync at startup before exiting with the list of the informers which did not sync. Wait indefinitely if zero")
+		argInitialAnnounceDelay        = pflag.Duration("initial-announce-delay", 0, "Maximum time to hold the announcements after startup until the BGP sessions with every neighbor are established, so that routes are not announced to sessions about to flap. Announcements are not held if zero")
 		argLogPerm                     = pflag.String("log-perm", "640", "The permission for the log file")
 	)
 	klogFlags := flag.NewFlagSet("klog", flag.ExitOnError)
@@ -120,12 +250,79 @@ func ParseFlags() (*Configuration, error) {
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	pflag.Parse()
 
-	ht := argHoldTime.Seconds()
-	if ht > 65536 || ht < 3 {
-		return nil, errors.New("the bgp holdtime must be in the range 3s to 65536s")
+	// --ebgp-multihop-ttl takes precedence over --ebgp-multihop, which is only used when it is not set
+	if !pflag.CommandLine.Changed("ebgp-multihop-ttl") {
+		*argEbgpMultihopTTL = *argEbgpMultihop
+	}
+	if err := validatePeerTTL(*argEbgpMultihopTTL); err != nil {
+		return nil, err
+	}
+
+	schedule, err := parseAnnounceSchedule(*argAnnounceSchedule)
+	if err != nil {
+		return nil, err
 	}
-	if *argEbgpMultihopTTL == 0 {
-		return nil, errors.New("the bgp MultihopTtl must be in the range 1 to 255")
+
+	routerID, routerIDv6, err := parseRouterIDs(*argRouterID, *argRouterIDv4, *argRouterIDv6)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaultLocalPref *uint32
+	if *argDefaultLocalPref != "" {
+		localPref, err := parseLocalPref(*argDefaultLocalPref)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default-local-pref: %w", err)
+		}
+		defaultLocalPref = &localPref
+	}
+
+	var drainingCommunity uint32
+	if *argDrainingCommunity != "" {
+		if drainingCommunity, err = parseCommunity(*argDrainingCommunity); err != nil {
+			return nil, fmt.Errorf("invalid draining-community: %w", err)
+		}
+	}
+
+	var degradedCommunity uint32
+	if *argDegradedCommunity != "" {
+		if degradedCommunity, err = parseCommunity(*argDegradedCommunity); err != nil {
+			return nil, fmt.Errorf("invalid degraded-community: %w", err)
+		}
+	}
+
+	gracefulShutdownCommunity, err := parseCommunity(*argGracefulShutdownCommunity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid graceful-shutdown-community: %w", err)
+	}
+
+	var rpki rpkiCommunities
+	if *argRPKIValidCommunity != "" {
+		if rpki.valid, err = parseCommunity(*argRPKIValidCommunity); err != nil {
+			return nil, fmt.Errorf("invalid rpki-valid-community: %w", err)
+		}
+	}
+	if *argRPKIUnknownCommunity != "" {
+		if rpki.unknown, err = parseCommunity(*argRPKIUnknownCommunity); err != nil {
+			return nil, fmt.Errorf("invalid rpki-unknown-community: %w", err)
+		}
+	}
+	if (rpki.valid != 0 || rpki.unknown != 0) && len(*argOriginAllowedCIDRs) == 0 {
+		return nil, errors.New("--rpki-valid-community and --rpki-unknown-community require --origin-allowed-cidrs")
+	}
+
+	confederationMembers, err := parseConfederationMembers(*argConfederationID, *argClusterAs, *argConfederationMembers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid confederation: %w", err)
+	}
+
+	var autoNeighborAs *asRange
+	if *argAutoNeighborAs {
+		r, err := parseASRange(*argAutoNeighborAsRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auto-neighbor-as-range: %w", err)
+		}
+		autoNeighborAs = &r
 	}
 
 	podIpsEnv := os.Getenv(util.EnvPodIPs)
@@ -148,7 +345,10 @@ func ParseFlags() (*Configuration, error) {
 		GrpcHost:                   *argGrpcHost,
 		GrpcPort:                   *argGrpcPort,
 		ClusterAs:                  *argClusterAs,
-		RouterID:                   *argRouterID,
+		RouterID:                   routerID,
+		RouterIDv6:                 routerIDv6,
+		NextHopIPv4:                *argNextHopIPv4,
+		NextHopIPv6:                *argNextHopIPv6,
 		NeighborAddresses:          *argNeighborAddress,
 		NeighborIPv6Addresses:      *argNeighborIPv6Address,
 		AllowedSourceAddresses:     *argAllowedSourceAddresses,
@@ -160,7 +360,7 @@ func ParseFlags() (*Configuration, error) {
 		PodIPs:                      make(map[string]net.IP, 2),
 		NeighborAs:                  *argNeighborAs,
 		AuthPassword:                *argAuthPassword,
-		HoldTime:                    ht,
+		HoldTime:                    argHoldTime.Seconds(),
 		PprofPort:                   *argPprofPort,
 		NodeName:                    strings.ToLower(*argNodeName),
 		KubeConfigFile:              *argKubeConfigFile,
@@ -169,10 +369,60 @@ func ParseFlags() (*Configuration, error) {
 		GracefulRestartTime:         *argDefaultGracefulTime,
 		PassiveMode:                 *argPassiveMode,
 		EbgpMultihopTTL:             *argEbgpMultihopTTL,
+		EnableGTSM:                  *argEnableGTSM,
 		ExtendedNexthop:             *argExtendedNexthop,
+		IPv4OverIPv6Nexthop:         *argIPv4OverIPv6Nexthop,
+		ReadvertiseOnEstablished:    *argReadvertiseOnEstablished,
 		NatGwMode:                   *argNatGwMode,
 		EnableMetrics:               *argEnableMetrics,
+		AnnounceSchedule:            schedule,
+		AnnounceGateFile:            *argAnnounceGateFile,
+		OriginAllowedCIDRs:          ipNetsToPrefixes(*argOriginAllowedCIDRs),
+		RPKICommunities:             rpki,
+		StaticAnnounceCIDRs:         ipNetsToPrefixes(*argStaticAnnounceCIDRs),
+		RoutesSnapshotFile:          *argRoutesSnapshotFile,
+		StateFile:                   *argStateFile,
+		RouteEventsSocket:           *argRouteEventsSocket,
+		RoutesRefreshInterval:       *argRoutesRefreshInterval,
+		CacheSyncTimeout:            *argCacheSyncTimeout,
+		InitialAnnounceDelay:        *argInitialAnnounceDelay,
+		WithdrawRate:                *argWithdrawRate,
+		WithdrawBurst:               *argWithdrawBurst,
+		AnnounceQueueSize:           *argAnnounceQueueSize,
+		DrainingCommunity:           drainingCommunity,
+		DegradedCommunity:           degradedCommunity,
+		HealthProbeContainer:        *argHealthProbeContainer,
+		GracefulShutdownTime:        *argGracefulShutdownTime,
+		GracefulShutdownCommunity:   gracefulShutdownCommunity,
+		DefaultLocalPref:            defaultLocalPref,
+		ExternalSubnetFilter:        *argExternalSubnetFilter,
+		VpcAllowlist:                *argVpcAllowlist,
+		AnnounceOnCondition:         *argAnnounceOnCondition,
+		SoftReconfigurationInbound:  *argSoftReconfigInbound,
+		DetectRouteLoops:            *argDetectRouteLoops,
+		VerifyAnnouncements:         *argVerifyAnnouncements,
+		ValidateNextHopReachability: *argValidateNextHop,
+		TunnelInterface:             *argTunnelInterface,
+		NodeWeightLabel:             *argNodeWeightLabel,
+		EnableBgpLU:                 *argEnableBgpLU,
+		NodeWeightAttribute:         *argNodeWeightAttribute,
+		ConfederationID:             *argConfederationID,
+		ConfederationMembers:        confederationMembers,
+		WithdrawOnTerminating:       *argWithdrawOnTerminating,
+		SingleAnnouncerByName:       *argSingleAnnouncerByName,
+		PointToPointSubnets:         set.New(*argPointToPointSubnets...),
+		PodName:                     os.Getenv(util.EnvPodName),
+		PodNamespace:                os.Getenv(util.EnvPodNamespace),
+		AutoNeighborAs:              autoNeighborAs,
 		LogPerm:                     *argLogPerm,
+
+		SecondaryNeighborAddresses:     *argSecondaryNeighborAddress,
+		SecondaryNeighborIPv6Addresses: *argSecondaryNeighborIPv6,
+		SecondaryNeighborAs:            *argSecondaryNeighborAs,
+
+		EnableLeaderElection:    *argEnableLeaderElection,
+		LeaderElectionNamespace: *argLeaderElectionNamespace,
+		LeaderElectionLease:     *argLeaderElectionLease,
 	}
 
 	if podIPv4 != "" {
@@ -193,25 +443,76 @@ func ParseFlags() (*Configuration, error) {
 	if err := config.validateRequiredFlags(); err != nil {
 		return nil, err
 	}
+	if err := config.validateTimers(); err != nil {
+		return nil, err
+	}
+	if err := config.validatePacing(); err != nil {
+		return nil, err
+	}
+	if err := config.validateNeighbors(); err != nil {
+		return nil, err
+	}
+	if err := config.normalizeNeighborAddresses(); err != nil {
+		return nil, err
+	}
+	if config.SecondaryNeighborAs == 0 {
+		config.SecondaryNeighborAs = config.NeighborAs
+	}
 
-	for _, addr := range config.NeighborAddresses {
-		if addr.To4() == nil {
-			return nil, fmt.Errorf("invalid neighbor-address format: %s", *argNeighborAddress)
-		}
+	config.warnNeighborFamilies()
+
+	if err := validateNodeWeightAttribute(config.NodeWeightAttribute); err != nil {
+		return nil, err
+	}
+	if err := config.validateReceivedRoutes(); err != nil {
+		return nil, err
 	}
-	for _, addr := range config.NeighborIPv6Addresses {
-		if addr.To4() != nil {
-			return nil, fmt.Errorf("invalid neighbor-ipv6-address format: %s is not an IPv6 address", addr)
+	if err := config.validateAnnounceConditions(); err != nil {
+		return nil, err
+	}
+
+	if config.TunnelInterface != "" {
+		link, err := netlink.LinkByName(config.TunnelInterface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tunnel interface %s: %w", config.TunnelInterface, err)
 		}
+		if err = validateTunnelInterface(link); err != nil {
+			return nil, err
+		}
+		config.checkTunnelRoutes(link)
+	}
+
+	if config.SRv6Locator, err = parseSRv6Locator(*argSRv6Locator); err != nil {
+		return nil, err
+	}
+	if err = config.validateNextHop(); err != nil {
+		return nil, err
+	}
+	if err = validateStaticAnnounceCIDRs(config.StaticAnnounceCIDRs); err != nil {
+		return nil, err
 	}
-	for _, addr := range config.AllowedSourceAddresses {
-		if addr.To4() == nil {
-			return nil, fmt.Errorf("invalid allowed-source-addresses format: %s is not an IPv4 address", addr)
+	if *argAnnounceNodeAddress {
+		if config.NodeAnnounceAddresses, err = getNodeAnnounceAddresses(*argNodeAnnounceAddresses); err != nil {
+			return nil, err
+		}
+		if len(config.NodeAnnounceAddresses) == 0 {
+			return nil, errors.New("--announce-node-address is set but the node has no address to announce")
 		}
+		klog.Infof("announcing node addresses %v", config.NodeAnnounceAddresses)
+	} else if len(*argNodeAnnounceAddresses) != 0 {
+		return nil, errors.New("--node-announce-addresses requires --announce-node-address")
+	}
+
+	if config.NeighborMaxPrefixes, err = parseNeighborMaxPrefixes(*argNeighborMaxPrefixes, config.allNeighborAddresses()); err != nil {
+		return nil, err
+	}
+	if config.SubnetNeighbors, err = parseSubnetNeighborMap(*argSubnetNeighborMap, config.allNeighborAddresses()); err != nil {
+		return nil, err
 	}
-	for _, addr := range config.AllowedSourceIPv6Addresses {
-		if addr.To4() != nil {
-			return nil, fmt.Errorf("invalid allowed-source-ipv6-addresses format: %s is not an IPv6 address", addr)
+
+	if config.EnableLeaderElection {
+		if config.LeaderElectionIdentity, err = getLeaderElectionIdentity(); err != nil {
+			return nil, err
 		}
 	}
 
@@ -248,12 +549,13 @@ func (config *Configuration) validateRequiredFlags() error {
 	if config.ClusterAs == 0 {
 		missingFlags = append(missingFlags, "--cluster-as must be specified")
 	}
-	if config.NeighborAs == 0 {
+	if config.NeighborAs == 0 && config.AutoNeighborAs == nil {
 		missingFlags = append(missingFlags, "--neighbor-as must be specified")
 	}
 	// NodeName is only used for the BGP "local" policy match in syncSubnetRoutes;
-	// NAT GW mode runs syncEIPRoutes exclusively and never reads NodeName, so skip
-	// the requirement there to stay compatible with GenNatGwBgpSpeakerContainer.
+	// NAT GW mode runs syncEIPRoutes exclusively and only reads NodeName for the
+	// node weight label, so skip the requirement there to stay compatible with
+	// GenNatGwBgpSpeakerContainer.
 	if !config.NatGwMode && config.NodeName == "" {
 		missingFlags = append(missingFlags, "--node-name must be specified (usually via NODE_NAME env from downward API)")
 	}
@@ -264,6 +566,289 @@ func (config *Configuration) validateRequiredFlags() error {
 	return nil
 }
 
+// validateTimers checks that the BGP hold time is in its valid range and that the other durations are not negative
+func (config *Configuration) validateTimers() error {
+	if config.HoldTime > 65536 || config.HoldTime < 3 {
+		return errors.New("the bgp holdtime must be in the range 3s to 65536s")
+	}
+	if config.RoutesRefreshInterval < 0 {
+		return errors.New("the routes refresh interval must not be negative")
+	}
+	if config.InitialAnnounceDelay < 0 {
+		return errors.New("the initial announce delay must not be negative")
+	}
+	if config.CacheSyncTimeout < 0 {
+		return errors.New("the cache sync timeout must not be negative")
+	}
+	if config.GracefulShutdownTime < 0 {
+		return errors.New("the graceful shutdown time must not be negative")
+	}
+	return nil
+}
+
+// validatePacing checks the options pacing the announcements and the withdrawals of routes
+func (config *Configuration) validatePacing() error {
+	if config.WithdrawRate < 0 {
+		return errors.New("the withdraw rate must not be negative")
+	}
+	if config.WithdrawBurst < 1 {
+		return errors.New("the withdraw burst must be at least 1")
+	}
+	if config.AnnounceQueueSize < 0 {
+		return errors.New("the announce queue size must not be negative")
+	}
+	return nil
+}
+
+// validateNeighbors checks that the addresses of the neighbors, and the source addresses allowed to reach them,
+// are of the address family of their flag
+func (config *Configuration) validateNeighbors() error {
+	for _, flag := range []struct {
+		name      string
+		addresses []net.IP
+		ipv6      bool
+	}{
+		{"neighbor-address", config.NeighborAddresses, false},
+		{"neighbor-ipv6-address", config.NeighborIPv6Addresses, true},
+		{"secondary-neighbor-address", config.SecondaryNeighborAddresses, false},
+		{"secondary-neighbor-ipv6-address", config.SecondaryNeighborIPv6Addresses, true},
+		{"allowed-source-addresses", config.AllowedSourceAddresses, false},
+		{"allowed-source-ipv6-addresses", config.AllowedSourceIPv6Addresses, true},
+	} {
+		for _, addr := range flag.addresses {
+			switch {
+			case flag.ipv6 && addr.To4() != nil:
+				return fmt.Errorf("invalid %s format: %s is not an IPv6 address", flag.name, addr)
+			case !flag.ipv6 && addr.To4() == nil:
+				return fmt.Errorf("invalid %s format: %s is not an IPv4 address", flag.name, addr)
+			}
+		}
+	}
+	return nil
+}
+
+// validateNextHop checks that the configured next hops are of their address family, and that the options
+// advertising IPv4 routes or the SRv6 locator over IPv6 sessions have IPv6 neighbors to advertise them to
+func (config *Configuration) validateNextHop() error {
+	if err := validateNextHops(config.NextHopIPv4, config.NextHopIPv6); err != nil {
+		return err
+	}
+	hasIPv6Neighbors := len(config.NeighborIPv6Addresses)+len(config.SecondaryNeighborIPv6Addresses) != 0
+	if config.IPv4OverIPv6Nexthop && !hasIPv6Neighbors {
+		return errors.New("--ipv4-over-ipv6-nexthop requires IPv6 neighbors")
+	}
+	if config.SRv6Locator.IsValid() && !config.ExtendedNexthop && !hasIPv6Neighbors {
+		return errors.New("--announce-srv6-locator requires IPv6 neighbors or --extended-nexthop")
+	}
+	return nil
+}
+
+// validateReceivedRoutes checks the options of the features relying on the routes received from the neighbors
+func (config *Configuration) validateReceivedRoutes() error {
+	if config.SoftReconfigurationInbound && !config.EnableMetrics {
+		return errors.New("--soft-reconfiguration-inbound requires --enable-metrics to serve the received routes")
+	}
+	if config.DetectRouteLoops && !config.SoftReconfigurationInbound {
+		return errors.New("--detect-route-loops requires --soft-reconfiguration-inbound")
+	}
+	return nil
+}
+
+// validateAnnounceConditions checks that the features withdrawing or altering the routes depending on the lease,
+// the pod or the node of the speaker know which ones they are
+func (config *Configuration) validateAnnounceConditions() error {
+	if config.EnableLeaderElection && config.LeaderElectionNamespace == "" {
+		return errors.New("--enable-leader-election requires --leader-election-namespace or the POD_NAMESPACE env")
+	}
+	if config.WithdrawOnTerminating && (config.PodName == "" || config.PodNamespace == "") {
+		return fmt.Errorf("--withdraw-on-terminating requires the %s and %s environment variables", util.EnvPodName, util.EnvPodNamespace)
+	}
+	if config.DegradedCommunity != 0 && (!config.NatGwMode || config.PodName == "" || config.PodNamespace == "") {
+		return fmt.Errorf("--degraded-community requires --nat-gw-mode and the %s and %s environment variables", util.EnvPodName, util.EnvPodNamespace)
+	}
+	if config.SingleAnnouncerByName && (!config.NatGwMode || config.NodeName == "") {
+		return errors.New("--single-announcer-by-name requires --nat-gw-mode and --node-name")
+	}
+	if config.NodeWeightLabel != "" && config.NodeName == "" {
+		return errors.New("--node-weight-label requires --node-name")
+	}
+	return nil
+}
+
+// normalizeNeighborAddresses rejects the addresses of the neighbors which cannot be BGP neighbors and removes
+// the addresses repeated in a list of neighbors. A neighbor cannot be both a neighbor and a secondary neighbor.
+func (config *Configuration) normalizeNeighborAddresses() error {
+	var err error
+	if config.NeighborAddresses, err = dedupNeighborAddresses("neighbor-address", config.NeighborAddresses); err != nil {
+		return err
+	}
+	if config.NeighborIPv6Addresses, err = dedupNeighborAddresses("neighbor-ipv6-address", config.NeighborIPv6Addresses); err != nil {
+		return err
+	}
+	if config.SecondaryNeighborAddresses, err = dedupNeighborAddresses("secondary-neighbor-address", config.SecondaryNeighborAddresses); err != nil {
+		return err
+	}
+	if config.SecondaryNeighborIPv6Addresses, err = dedupNeighborAddresses("secondary-neighbor-ipv6-address", config.SecondaryNeighborIPv6Addresses); err != nil {
+		return err
+	}
+
+	for _, addr := range slices.Concat(config.NeighborAddresses, config.NeighborIPv6Addresses) {
+		if config.isSecondaryNeighbor(addr) {
+			return fmt.Errorf("neighbor %s cannot be both a neighbor and a secondary neighbor", addr)
+		}
+	}
+	return nil
+}
+
+// dedupNeighborAddresses returns the addresses of the neighbors of a flag without the repeated ones, and fails
+// if one of them is not a unicast address
+func dedupNeighborAddresses(flag string, addresses []net.IP) ([]net.IP, error) {
+	if addresses == nil {
+		return nil, nil
+	}
+
+	result := make([]net.IP, 0, len(addresses))
+	for _, addr := range addresses {
+		if addr == nil || addr.IsUnspecified() || addr.IsMulticast() || addr.Equal(net.IPv4bcast) {
+			return nil, fmt.Errorf("invalid %s: %q is not a unicast address", flag, addr)
+		}
+		if slices.ContainsFunc(result, addr.Equal) {
+			klog.Warningf("neighbor %s is repeated in %s, ignoring the duplicate", addr, flag)
+			continue
+		}
+		result = append(result, addr)
+	}
+	return result, nil
+}
+
+// warnNeighborFamilies warns when neighbors of an address family are configured while the speaker has no local
+// address of this family to use as next hop, the routes advertised to them would then have an unusable next hop
+func (config *Configuration) warnNeighborFamilies() {
+	hasIPv4Neighbors := len(config.NeighborAddresses)+len(config.SecondaryNeighborAddresses) != 0
+	hasIPv6Neighbors := len(config.NeighborIPv6Addresses)+len(config.SecondaryNeighborIPv6Addresses) != 0
+	hasIPv4Address := config.PodIPs[kubeovnv1.ProtocolIPv4] != nil || config.NodeIPs[kubeovnv1.ProtocolIPv4] != nil ||
+		len(config.AllowedSourceAddresses) != 0
+	hasIPv6Address := config.PodIPs[kubeovnv1.ProtocolIPv6] != nil || config.NodeIPs[kubeovnv1.ProtocolIPv6] != nil ||
+		len(config.AllowedSourceIPv6Addresses) != 0 || config.RouterIDv6 != nil
+
+	if hasIPv4Neighbors && !hasIPv4Address {
+		klog.Warning("IPv4 neighbors are configured but the speaker has no IPv4 pod, node or allowed source address to use as next hop")
+	}
+	if hasIPv6Neighbors && !hasIPv6Address {
+		klog.Warning("IPv6 neighbors are configured but the speaker has no IPv6 pod, node or allowed source address, nor --router-id-v6, to use as next hop")
+	}
+}
+
+// parseNeighborMaxPrefixes validates the maximum numbers of prefixes advertised to neighbors and returns them
+// indexed by the normalized address of their neighbor
+func parseNeighborMaxPrefixes(maxPrefixes map[string]int, neighbors []net.IP) (map[string]int, error) {
+	result := make(map[string]int, len(maxPrefixes))
+	for neighbor, limit := range maxPrefixes {
+		addr := net.ParseIP(neighbor)
+		if addr == nil || !slices.ContainsFunc(neighbors, addr.Equal) {
+			return nil, fmt.Errorf("invalid neighbor-max-prefixes: %s is not a neighbor address", neighbor)
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("invalid neighbor-max-prefixes: the maximum number of prefixes of neighbor %s must not be negative", neighbor)
+		}
+		result[addr.String()] = limit
+	}
+	return result, nil
+}
+
+// parseSubnetNeighborMap validates the neighbors the routes of the EIPs of each external subnet are advertised to
+// and returns them as sorted comma separated lists of addresses
+func parseSubnetNeighborMap(entries []string, neighbors []net.IP) (map[string]string, error) {
+	result := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		subnet, addresses, found := strings.Cut(entry, "=")
+		if !found || subnet == "" || addresses == "" {
+			return nil, fmt.Errorf("invalid subnet-neighbor-map %q: must be in the subnet=neighbor[,neighbor...] format", entry)
+		}
+		if _, ok := result[subnet]; ok {
+			return nil, fmt.Errorf("invalid subnet-neighbor-map: the neighbors of subnet %s are set more than once", subnet)
+		}
+
+		var subnetNeighbors []string
+		for s := range strings.SplitSeq(addresses, ",") {
+			addr := net.ParseIP(strings.TrimSpace(s))
+			if addr == nil || !slices.ContainsFunc(neighbors, addr.Equal) {
+				return nil, fmt.Errorf("invalid subnet-neighbor-map: %s of subnet %s is not a neighbor address", s, subnet)
+			}
+			subnetNeighbors = append(subnetNeighbors, addr.String())
+		}
+		slices.Sort(subnetNeighbors)
+		result[subnet] = strings.Join(slices.Compact(subnetNeighbors), ",")
+	}
+	return result, nil
+}
+
+// parseRouterIDs returns the router ids of the speaker. The IPv4 router id, the BGP identifier of the speaker,
+// is set with either --router-id or --router-id-v4.
+func parseRouterIDs(routerID, routerIDv4, routerIDv6 net.IP) (net.IP, net.IP, error) {
+	if routerIDv4 != nil {
+		if routerID != nil {
+			return nil, nil, errors.New("--router-id and --router-id-v4 are mutually exclusive")
+		}
+		if routerIDv4.To4() == nil {
+			return nil, nil, fmt.Errorf("invalid router-id-v4: %s is not an IPv4 address", routerIDv4)
+		}
+		routerID = routerIDv4
+	}
+	if routerIDv6 != nil && routerIDv6.To4() != nil {
+		return nil, nil, fmt.Errorf("invalid router-id-v6: %s is not an IPv6 address", routerIDv6)
+	}
+	return routerID, routerIDv6, nil
+}
+
+// validateNextHops checks that the next hops of --nexthop-v4 and --nexthop-v6 are of their address family
+func validateNextHops(nextHopIPv4, nextHopIPv6 net.IP) error {
+	if nextHopIPv4 != nil && nextHopIPv4.To4() == nil {
+		return fmt.Errorf("invalid nexthop-v4: %s is not an IPv4 address", nextHopIPv4)
+	}
+	if nextHopIPv6 != nil && nextHopIPv6.To4() != nil {
+		return fmt.Errorf("invalid nexthop-v6: %s is not an IPv6 address", nextHopIPv6)
+	}
+	return nil
+}
+
+// getConfiguredNextHop returns the next hop configured for the address family of a neighbor, nil if none is
+func (config *Configuration) getConfiguredNextHop(neighborAddress net.IP) net.IP {
+	if neighborAddress.To4() != nil {
+		return config.NextHopIPv4
+	}
+	return config.NextHopIPv6
+}
+
+// parseCommunity parses a BGP community in the "ASN:value" format, both parts being 16-bit numbers
+func parseCommunity(s string) (uint32, error) {
+	asn, value, found := strings.Cut(s, ":")
+	if !found {
+		return 0, fmt.Errorf("community %q is not in the ASN:value format", s)
+	}
+	high, err := strconv.ParseUint(asn, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ASN of community %q: %w", s, err)
+	}
+	low, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value of community %q: %w", s, err)
+	}
+	if high == 0 && low == 0 {
+		return 0, fmt.Errorf("community %q is reserved", s)
+	}
+	return uint32(high<<16 | low), nil
+}
+
+// parseLocalPref parses a BGP LOCAL_PREF, a 32-bit number
+func parseLocalPref(s string) (uint32, error) {
+	localPref, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("local-pref %q is not a number between 0 and %d", s, uint32(math.MaxUint32))
+	}
+	return uint32(localPref), nil
+}
+
 func (config *Configuration) initKubeClient() error {
 	var cfg *rest.Config
 	var err error
@@ -334,8 +919,8 @@ func (config *Configuration) initBgpServer() error {
 	go s.Serve()
 
 	peersMap := map[api.Family_Afi][]net.IP{
-		api.Family_AFI_IP:  config.NeighborAddresses,
-		api.Family_AFI_IP6: config.NeighborIPv6Addresses,
+		api.Family_AFI_IP:  slices.Concat(config.NeighborAddresses, config.SecondaryNeighborAddresses),
+		api.Family_AFI_IP6: slices.Concat(config.NeighborIPv6Addresses, config.SecondaryNeighborIPv6Addresses),
 	}
 
 	if config.PassiveMode {
@@ -359,6 +944,7 @@ func (config *Configuration) initBgpServer() error {
 			RouterId:         config.RouterID.String(),
 			ListenPort:       listenPort,
 			UseMultiplePaths: true,
+			Confederation:    config.getConfederation(),
 		},
 	}); err != nil {
 		err = fmt.Errorf("failed to start bgp server: %w", err)
@@ -377,16 +963,11 @@ func (config *Configuration) initBgpServer() error {
 				Timers: &api.Timers{Config: &api.TimersConfig{HoldTime: uint64(config.HoldTime)}},
 				Conf: &api.PeerConf{
 					NeighborAddress: addr.String(),
-					PeerAsn:         config.NeighborAs,
+					PeerAsn:         config.getNeighborAs(addr),
 				},
 				Transport: transport,
 			}
-			if config.EbgpMultihopTTL != DefaultEbgpMultiHop {
-				peer.EbgpMultihop = &api.EbgpMultihop{
-					Enabled:     true,
-					MultihopTtl: uint32(config.EbgpMultihopTTL),
-				}
-			}
+			config.setPeerTTL(peer)
 			if config.AuthPassword != "" {
 				peer.Conf.AuthPassword = config.AuthPassword
 			}
@@ -402,33 +983,8 @@ func (config *Configuration) initBgpServer() error {
 					DeferralTime:    uint32(config.GracefulRestartDeferralTime.Seconds()),
 					LocalRestarting: true,
 				}
-				peer.AfiSafis = []*api.AfiSafi{
-					{
-						Config: &api.AfiSafiConfig{
-							Family:  &api.Family{Afi: ipFamily, Safi: api.Family_SAFI_UNICAST},
-							Enabled: true,
-						},
-						MpGracefulRestart: &api.MpGracefulRestart{
-							Config: &api.MpGracefulRestartConfig{
-								Enabled: true,
-							},
-						},
-					},
-				}
-			}
-
-			// If extended nexthop is enabled, advertise the IPv4 unicast AFI/SAFI even if
-			// we have no IPv4 neighbor
-			if config.ExtendedNexthop {
-				peer.AfiSafis = append(peer.AfiSafis, &api.AfiSafi{
-					Config: &api.AfiSafiConfig{
-						Family: &api.Family{
-							Afi:  api.Family_AFI_IP,
-							Safi: api.Family_SAFI_UNICAST,
-						},
-					},
-				})
 			}
+			config.setPeerAfiSafis(peer, ipFamily)
 
 			logBgpPeer(peer)
 			if err := addPeerWithRetry(s, peer); err != nil {
@@ -443,6 +999,103 @@ func (config *Configuration) initBgpServer() error {
 	return nil
 }
 
+// validatePeerTTL validates the TTL of the packets sent to EBGP peers, or the number of hops to the peers with GTSM
+func validatePeerTTL(ttl uint8) error {
+	if ttl == 0 {
+		return errors.New("the bgp MultihopTtl must be in the range 1 to 255")
+	}
+	return nil
+}
+
+// setPeerTTL configures the TTL handling of a peer. With GTSM, packets are sent with a TTL of 255 and only the
+// packets received with a TTL of at least 256 minus the number of hops to the peer are accepted. Otherwise EBGP
+// multihop is enabled when the TTL is not the default one.
+func (config *Configuration) setPeerTTL(peer *api.Peer) {
+	if config.EnableGTSM {
+		peer.TtlSecurity = &api.TtlSecurity{
+			Enabled: true,
+			TtlMin:  256 - uint32(config.EbgpMultihopTTL),
+		}
+		return
+	}
+	if config.EbgpMultihopTTL != DefaultEbgpMultiHop {
+		peer.EbgpMultihop = &api.EbgpMultihop{
+			Enabled:     true,
+			MultihopTtl: uint32(config.EbgpMultihopTTL),
+		}
+	}
+}
+
+// setPeerAfiSafis configures the address families of a peer whose address is of the given family, consistently with
+// the NLRIs advertised over its session
+func (config *Configuration) setPeerAfiSafis(peer *api.Peer, ipFamily api.Family_Afi) {
+	if config.GracefulRestart {
+		peer.AfiSafis = []*api.AfiSafi{
+			{
+				Config: &api.AfiSafiConfig{
+					Family:  &api.Family{Afi: ipFamily, Safi: api.Family_SAFI_UNICAST},
+					Enabled: true,
+				},
+				MpGracefulRestart: &api.MpGracefulRestart{
+					Config: &api.MpGracefulRestartConfig{
+						Enabled: true,
+					},
+				},
+			},
+		}
+	}
+
+	// If extended nexthop is enabled, advertise the IPv4 unicast AFI/SAFI even if
+	// we have no IPv4 neighbor
+	if config.ExtendedNexthop {
+		peer.AfiSafis = append(peer.AfiSafis, &api.AfiSafi{
+			Config: &api.AfiSafiConfig{
+				Family: &api.Family{
+					Afi:  api.Family_AFI_IP,
+					Safi: api.Family_SAFI_UNICAST,
+				},
+			},
+		})
+	} else if config.IPv4OverIPv6Nexthop && ipFamily == api.Family_AFI_IP6 {
+		// RFC 8950: IPv6 peers carry the IPv4 unicast AFI/SAFI in addition to their native
+		// one, GoBGP then negotiates the extended next hop capability on its own. With graceful
+		// restart, the IPv4 routes are retained across restarts like the IPv6 ones.
+		addPeerAfiSafi(peer, api.Family_AFI_IP6)
+		addPeerAfiSafi(peer, api.Family_AFI_IP)
+		if config.GracefulRestart {
+			peer.AfiSafis[len(peer.AfiSafis)-1].MpGracefulRestart = &api.MpGracefulRestart{
+				Config: &api.MpGracefulRestartConfig{
+					Enabled: true,
+				},
+			}
+		}
+	}
+
+	if config.EnableBgpLU {
+		addPeerLabeledUnicast(peer, ipFamily)
+	}
+}
+
+// addPeerAfiSafi enables the unicast SAFI of an address family on a peer if not already enabled
+func addPeerAfiSafi(peer *api.Peer, afi api.Family_Afi) {
+	addPeerFamily(peer, afi, api.Family_SAFI_UNICAST)
+}
+
+// addPeerFamily enables an AFI/SAFI on a peer if not already enabled
+func addPeerFamily(peer *api.Peer, afi api.Family_Afi, safi api.Family_Safi) {
+	for _, afiSafi := range peer.AfiSafis {
+		if family := afiSafi.GetConfig().GetFamily(); family.GetAfi() == afi && family.GetSafi() == safi {
+			return
+		}
+	}
+	peer.AfiSafis = append(peer.AfiSafis, &api.AfiSafi{
+		Config: &api.AfiSafiConfig{
+			Family:  &api.Family{Afi: afi, Safi: safi},
+			Enabled: true,
+		},
+	})
+}
+
 // addPeerWithRetry attempts to add a BGP peer with retry logic.
 // It retries up to addPeerMaxRetries times with addPeerRetryInterval between attempts.
 func addPeerWithRetry(s *gobgp.BgpServer, peer *api.Peer) error {
@@ -465,21 +1118,22 @@ func addPeerWithRetry(s *gobgp.BgpServer, peer *api.Peer) error {
 
 // logBgpPeer logs the BGP peer configuration for debugging purposes.
 func logBgpPeer(peer *api.Peer) {
-	klog.Infof("BGP Peer Configuration: NeighborAddress=%s, LocalAddress=%s, PeerAsn=%d, HoldTime=%d, PassiveMode=%v, EbgpMultihop=%v, GracefulRestart=%v, AfiSafis=%v",
+	klog.Infof("BGP Peer Configuration: NeighborAddress=%s, LocalAddress=%s, PeerAsn=%d, HoldTime=%d, PassiveMode=%v, EbgpMultihop=%v, TtlSecurity=%v, GracefulRestart=%v, AfiSafis=%v",
 		peer.Conf.NeighborAddress,
 		peer.Transport.LocalAddress,
 		peer.Conf.PeerAsn,
 		peer.Timers.Config.HoldTime,
 		peer.Transport.PassiveMode,
 		peer.EbgpMultihop,
+		peer.TtlSecurity,
 		peer.GracefulRestart,
 		peer.AfiSafis)
 }
 
 func (config *Configuration) initNeighborLocalAddresses() error {
-	config.NeighborLocalAddresses = make(map[string]net.IP, len(config.NeighborAddresses)+len(config.NeighborIPv6Addresses))
+	config.NeighborLocalAddresses = make(map[string]net.IP, len(config.allNeighborAddresses()))
 
-	for _, neighbor := range config.NeighborAddresses {
+	for _, neighbor := range slices.Concat(config.NeighborAddresses, config.SecondaryNeighborAddresses) {
 		if len(config.AllowedSourceAddresses) != 0 {
 			klog.Infof("Resolving BGP local address for neighbor %s with allowed IPv4 source addresses %v", neighbor, config.AllowedSourceAddresses)
 			localAddr, err := config.resolveWhitelistedNeighborLocalAddress(neighbor, config.AllowedSourceAddresses)
@@ -490,7 +1144,7 @@ func (config *Configuration) initNeighborLocalAddresses() error {
 		}
 	}
 
-	for _, neighbor := range config.NeighborIPv6Addresses {
+	for _, neighbor := range slices.Concat(config.NeighborIPv6Addresses, config.SecondaryNeighborIPv6Addresses) {
 		if len(config.AllowedSourceIPv6Addresses) != 0 {
 			klog.Infof("Resolving BGP local address for neighbor %s with allowed IPv6 source addresses %v", neighbor, config.AllowedSourceIPv6Addresses)
 			localAddr, err := config.resolveWhitelistedNeighborLocalAddress(neighbor, config.AllowedSourceIPv6Addresses)
@@ -504,6 +1158,25 @@ func (config *Configuration) initNeighborLocalAddresses() error {
 	return nil
 }
 
+// allNeighborAddresses returns the addresses of all the neighbors, whatever their address family and BGP instance
+func (config *Configuration) allNeighborAddresses() []net.IP {
+	return slices.Concat(config.NeighborAddresses, config.NeighborIPv6Addresses, config.SecondaryNeighborAddresses, config.SecondaryNeighborIPv6Addresses)
+}
+
+// isSecondaryNeighbor returns whether a neighbor belongs to the secondary BGP instance
+func (config *Configuration) isSecondaryNeighbor(neighborAddress net.IP) bool {
+	return slices.ContainsFunc(config.SecondaryNeighborAddresses, neighborAddress.Equal) ||
+		slices.ContainsFunc(config.SecondaryNeighborIPv6Addresses, neighborAddress.Equal)
+}
+
+// getNeighborAs returns the AS number of a neighbor
+func (config *Configuration) getNeighborAs(neighborAddress net.IP) uint32 {
+	if config.isSecondaryNeighbor(neighborAddress) {
+		return config.SecondaryNeighborAs
+	}
+	return config.NeighborAs
+}
+
 func (config *Configuration) getNeighborLocalAddress(neighborAddress net.IP) net.IP {
 	if localAddr := config.NeighborLocalAddresses[neighborAddress.String()]; localAddr != nil {
 		return localAddr
@@ -1,11 +1,16 @@
 package speaker
 
 import (
+	"context"
 	"net"
+	"net/netip"
 	"testing"
+	"time"
 
+	"github.com/osrg/gobgp/v4/api"
 	"github.com/stretchr/testify/require"
 	"github.com/vishvananda/netlink"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestValidateRequiredFlags(t *testing.T) {
@@ -96,6 +101,16 @@ func TestValidateRequiredFlags(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "neighbor AS discovered automatically",
+			config: &Configuration{
+				NeighborAddresses: []net.IP{net.ParseIP("192.168.1.1")},
+				ClusterAs:         65000,
+				AutoNeighborAs:    &asRange{first: 64512, last: 65534},
+				NodeName:          "node1",
+			},
+			expectError: false,
+		},
 		{
 			name:        "missing all required flags",
 			config:      &Configuration{},
@@ -119,6 +134,169 @@ func TestValidateRequiredFlags(t *testing.T) {
 	}
 }
 
+// requireValidationError checks that a validation succeeded if errContains is empty, or failed with an error
+// containing it otherwise
+func requireValidationError(t *testing.T, err error, errContains string) {
+	t.Helper()
+	if errContains == "" {
+		require.NoError(t, err)
+	} else {
+		require.ErrorContains(t, err, errContains)
+	}
+}
+
+func TestValidateTimers(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Configuration
+		errContains string
+	}{
+		{name: "valid", config: &Configuration{HoldTime: 90, RoutesRefreshInterval: time.Hour, GracefulShutdownTime: time.Minute}},
+		{name: "minimum holdtime", config: &Configuration{HoldTime: 3}},
+		{name: "maximum holdtime", config: &Configuration{HoldTime: 65536}},
+		{name: "holdtime too short", config: &Configuration{HoldTime: 2}, errContains: "holdtime must be in the range"},
+		{name: "holdtime too long", config: &Configuration{HoldTime: 65537}, errContains: "holdtime must be in the range"},
+		{name: "negative refresh interval", config: &Configuration{HoldTime: 90, RoutesRefreshInterval: -time.Second}, errContains: "routes refresh interval"},
+		{name: "negative initial announce delay", config: &Configuration{HoldTime: 90, InitialAnnounceDelay: -time.Second}, errContains: "initial announce delay"},
+		{name: "negative cache sync timeout", config: &Configuration{HoldTime: 90, CacheSyncTimeout: -time.Second}, errContains: "cache sync timeout"},
+		{name: "negative graceful shutdown time", config: &Configuration{HoldTime: 90, GracefulShutdownTime: -time.Second}, errContains: "graceful shutdown time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireValidationError(t, tt.config.validateTimers(), tt.errContains)
+		})
+	}
+}
+
+func TestValidatePacing(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Configuration
+		errContains string
+	}{
+		{name: "not paced", config: &Configuration{WithdrawBurst: 100}},
+		{name: "paced", config: &Configuration{WithdrawRate: 10, WithdrawBurst: 1, AnnounceQueueSize: 50}},
+		{name: "negative withdraw rate", config: &Configuration{WithdrawRate: -1, WithdrawBurst: 100}, errContains: "withdraw rate"},
+		{name: "zero withdraw burst", config: &Configuration{WithdrawBurst: 0}, errContains: "withdraw burst"},
+		{name: "negative announce queue size", config: &Configuration{WithdrawBurst: 100, AnnounceQueueSize: -1}, errContains: "announce queue size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireValidationError(t, tt.config.validatePacing(), tt.errContains)
+		})
+	}
+}
+
+func TestValidateNeighbors(t *testing.T) {
+	ipv4, ipv6 := []net.IP{net.ParseIP("10.32.32.1")}, []net.IP{net.ParseIP("fd00::1")}
+	tests := []struct {
+		name        string
+		config      *Configuration
+		errContains string
+	}{
+		{
+			name: "addresses of their family",
+			config: &Configuration{
+				NeighborAddresses: ipv4, NeighborIPv6Addresses: ipv6,
+				SecondaryNeighborAddresses: ipv4, SecondaryNeighborIPv6Addresses: ipv6,
+				AllowedSourceAddresses: ipv4, AllowedSourceIPv6Addresses: ipv6,
+			},
+		},
+		{name: "IPv6 neighbor-address", config: &Configuration{NeighborAddresses: ipv6}, errContains: "invalid neighbor-address format: fd00::1 is not an IPv4 address"},
+		{name: "IPv4 neighbor-ipv6-address", config: &Configuration{NeighborIPv6Addresses: ipv4}, errContains: "invalid neighbor-ipv6-address format"},
+		{name: "IPv6 secondary-neighbor-address", config: &Configuration{SecondaryNeighborAddresses: ipv6}, errContains: "invalid secondary-neighbor-address format"},
+		{name: "IPv4 secondary-neighbor-ipv6-address", config: &Configuration{SecondaryNeighborIPv6Addresses: ipv4}, errContains: "invalid secondary-neighbor-ipv6-address format"},
+		{name: "IPv6 allowed-source-addresses", config: &Configuration{AllowedSourceAddresses: ipv6}, errContains: "invalid allowed-source-addresses format"},
+		{name: "IPv4 allowed-source-ipv6-addresses", config: &Configuration{AllowedSourceIPv6Addresses: ipv4}, errContains: "invalid allowed-source-ipv6-addresses format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireValidationError(t, tt.config.validateNeighbors(), tt.errContains)
+		})
+	}
+}
+
+func TestValidateNextHop(t *testing.T) {
+	ipv4, ipv6 := []net.IP{net.ParseIP("10.32.32.1")}, []net.IP{net.ParseIP("fd00::1")}
+	locator := netip.MustParsePrefix("fd00:0:1::/48")
+	tests := []struct {
+		name        string
+		config      *Configuration
+		errContains string
+	}{
+		{name: "no next hop", config: &Configuration{NeighborAddresses: ipv4}},
+		{name: "next hops of their family", config: &Configuration{NextHopIPv4: net.ParseIP("172.16.0.1"), NextHopIPv6: net.ParseIP("fd01::1")}},
+		{name: "IPv6 nexthop-v4", config: &Configuration{NextHopIPv4: net.ParseIP("fd01::1")}, errContains: "invalid nexthop-v4"},
+		{name: "IPv4 over IPv6 sessions", config: &Configuration{IPv4OverIPv6Nexthop: true, NeighborIPv6Addresses: ipv6}},
+		{name: "IPv4 over secondary IPv6 sessions", config: &Configuration{IPv4OverIPv6Nexthop: true, SecondaryNeighborIPv6Addresses: ipv6}},
+		{name: "IPv4 over IPv6 without IPv6 neighbors", config: &Configuration{IPv4OverIPv6Nexthop: true, NeighborAddresses: ipv4}, errContains: "--ipv4-over-ipv6-nexthop requires IPv6 neighbors"},
+		{name: "SRv6 locator to IPv6 neighbors", config: &Configuration{SRv6Locator: locator, NeighborIPv6Addresses: ipv6}},
+		{name: "SRv6 locator with extended next hop", config: &Configuration{SRv6Locator: locator, ExtendedNexthop: true, NeighborAddresses: ipv4}},
+		{name: "SRv6 locator without IPv6 neighbors", config: &Configuration{SRv6Locator: locator, NeighborAddresses: ipv4}, errContains: "--announce-srv6-locator requires"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireValidationError(t, tt.config.validateNextHop(), tt.errContains)
+		})
+	}
+}
+
+func TestValidateReceivedRoutes(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Configuration
+		errContains string
+	}{
+		{name: "disabled", config: &Configuration{}},
+		{name: "route loops detected", config: &Configuration{EnableMetrics: true, SoftReconfigurationInbound: true, DetectRouteLoops: true}},
+		{name: "soft reconfiguration without metrics", config: &Configuration{SoftReconfigurationInbound: true}, errContains: "--soft-reconfiguration-inbound requires --enable-metrics"},
+		{name: "route loops without soft reconfiguration", config: &Configuration{EnableMetrics: true, DetectRouteLoops: true}, errContains: "--detect-route-loops requires --soft-reconfiguration-inbound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireValidationError(t, tt.config.validateReceivedRoutes(), tt.errContains)
+		})
+	}
+}
+
+func TestValidateAnnounceConditions(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Configuration
+		errContains string
+	}{
+		{name: "disabled", config: &Configuration{}},
+		{
+			name: "every condition",
+			config: &Configuration{
+				NatGwMode: true, NodeName: "node1", PodName: "vpc-nat-gw-gw1-0", PodNamespace: "kube-system",
+				EnableLeaderElection: true, LeaderElectionNamespace: "kube-system",
+				WithdrawOnTerminating: true, DegradedCommunity: 65000<<16 | 1, SingleAnnouncerByName: true, NodeWeightLabel: "bgp-weight",
+			},
+		},
+		{name: "leader election without namespace", config: &Configuration{EnableLeaderElection: true}, errContains: "--enable-leader-election requires"},
+		{name: "withdraw on terminating without pod", config: &Configuration{WithdrawOnTerminating: true, PodNamespace: "kube-system"}, errContains: "--withdraw-on-terminating requires"},
+		{
+			name:        "degraded community without nat-gw mode",
+			config:      &Configuration{DegradedCommunity: 1, PodName: "pod", PodNamespace: "kube-system"},
+			errContains: "--degraded-community requires",
+		},
+		{name: "single announcer without node name", config: &Configuration{SingleAnnouncerByName: true, NatGwMode: true}, errContains: "--single-announcer-by-name requires"},
+		{name: "node weight label without node name", config: &Configuration{NodeWeightLabel: "bgp-weight"}, errContains: "--node-weight-label requires"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireValidationError(t, tt.config.validateAnnounceConditions(), tt.errContains)
+		})
+	}
+}
+
 func TestValidateLocalAddressFamily(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -304,3 +482,201 @@ func TestInitNeighborLocalAddressesPureExtensionMode(t *testing.T) {
 	require.Nil(t, config.getNeighborLocalAddress(net.ParseIP("10.32.32.1")))
 	require.Nil(t, config.getNeighborLocalAddress(net.ParseIP("fd00::254")))
 }
+
+func TestParseNeighborMaxPrefixes(t *testing.T) {
+	neighbors := []net.IP{net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")}
+
+	maxPrefixes, err := parseNeighborMaxPrefixes(map[string]int{"10.32.32.1": 100, "fd00:0::1": 0}, neighbors)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"10.32.32.1": 100, "fd00::1": 0}, maxPrefixes)
+
+	_, err = parseNeighborMaxPrefixes(map[string]int{"10.32.32.2": 100}, neighbors)
+	require.Error(t, err)
+	_, err = parseNeighborMaxPrefixes(map[string]int{"invalid": 100}, neighbors)
+	require.Error(t, err)
+	_, err = parseNeighborMaxPrefixes(map[string]int{"10.32.32.1": -1}, neighbors)
+	require.Error(t, err)
+}
+
+func TestNormalizeNeighborAddresses(t *testing.T) {
+	config := &Configuration{
+		NeighborAddresses:          []net.IP{net.ParseIP("10.32.32.1"), net.ParseIP("10.32.32.2"), net.ParseIP("10.32.32.1")},
+		NeighborIPv6Addresses:      []net.IP{net.ParseIP("fd00::1"), net.ParseIP("fd00:0::1")},
+		SecondaryNeighborAddresses: []net.IP{net.ParseIP("10.32.33.1")},
+	}
+	require.NoError(t, config.normalizeNeighborAddresses())
+	require.Equal(t, []net.IP{net.ParseIP("10.32.32.1"), net.ParseIP("10.32.32.2")}, config.NeighborAddresses)
+	require.Equal(t, []net.IP{net.ParseIP("fd00::1")}, config.NeighborIPv6Addresses)
+	require.Equal(t, []net.IP{net.ParseIP("10.32.33.1")}, config.SecondaryNeighborAddresses)
+	require.Nil(t, config.SecondaryNeighborIPv6Addresses)
+
+	for _, addr := range []net.IP{nil, net.IPv4zero, net.IPv4bcast, net.ParseIP("224.0.0.5"), net.IPv6unspecified, net.ParseIP("ff02::5")} {
+		_, err := dedupNeighborAddresses("neighbor-address", []net.IP{net.ParseIP("10.32.32.1"), addr})
+		require.Error(t, err, addr)
+	}
+
+	config.SecondaryNeighborAddresses = append(config.SecondaryNeighborAddresses, net.ParseIP("10.32.32.2"))
+	require.ErrorContains(t, config.normalizeNeighborAddresses(), "both a neighbor and a secondary neighbor")
+}
+
+func TestParseSubnetNeighborMap(t *testing.T) {
+	neighbors := []net.IP{net.ParseIP("10.32.32.1"), net.ParseIP("10.32.32.5"), net.ParseIP("fd00::1")}
+
+	subnetNeighbors, err := parseSubnetNeighborMap([]string{"external1=10.32.32.5, 10.32.32.1", "external2=fd00:0::1"}, neighbors)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"external1": "10.32.32.1,10.32.32.5", "external2": "fd00::1"}, subnetNeighbors)
+
+	subnetNeighbors, err = parseSubnetNeighborMap(nil, neighbors)
+	require.NoError(t, err)
+	require.Empty(t, subnetNeighbors)
+
+	for _, entries := range [][]string{
+		{"external1=10.32.32.2"},
+		{"external1=upstream"},
+		{"external1"},
+		{"=10.32.32.1"},
+		{"external1="},
+		{"external1=10.32.32.1", "external1=10.32.32.5"},
+	} {
+		_, err = parseSubnetNeighborMap(entries, neighbors)
+		require.Error(t, err, entries)
+	}
+}
+
+func TestGetNeighborAs(t *testing.T) {
+	config := &Configuration{
+		NeighborAddresses:              []net.IP{net.ParseIP("10.32.32.1")},
+		NeighborIPv6Addresses:          []net.IP{net.ParseIP("fd00::1")},
+		SecondaryNeighborAddresses:     []net.IP{net.ParseIP("10.33.33.1")},
+		SecondaryNeighborIPv6Addresses: []net.IP{net.ParseIP("fd01::1")},
+		NeighborAs:                     65001,
+		SecondaryNeighborAs:            65002,
+	}
+
+	require.Len(t, config.allNeighborAddresses(), 4)
+	require.Equal(t, uint32(65001), config.getNeighborAs(net.ParseIP("10.32.32.1")))
+	require.Equal(t, uint32(65001), config.getNeighborAs(net.ParseIP("fd00::1")))
+	require.Equal(t, uint32(65002), config.getNeighborAs(net.ParseIP("10.33.33.1")))
+	require.Equal(t, uint32(65002), config.getNeighborAs(net.ParseIP("fd01::1")))
+	require.False(t, config.isSecondaryNeighbor(net.ParseIP("10.32.32.1")))
+	require.True(t, config.isSecondaryNeighbor(net.ParseIP("fd01::1")))
+}
+
+func TestParseLocalPref(t *testing.T) {
+	for input, expected := range map[string]uint32{"0": 0, "100": 100, "4294967295": 4294967295} {
+		localPref, err := parseLocalPref(input)
+		require.NoError(t, err)
+		require.Equal(t, expected, localPref)
+	}
+	for _, input := range []string{"", "-1", "4294967296", "high"} {
+		_, err := parseLocalPref(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestParseCommunity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint32
+		wantErr  bool
+	}{
+		{input: "65000:100", expected: 65000<<16 | 100},
+		{input: "0:1", expected: 1},
+		{input: "65535:65535", expected: 0xffffffff},
+		{input: "0:0", wantErr: true},
+		{input: "65536:100", wantErr: true},
+		{input: "65000:-1", wantErr: true},
+		{input: "65000", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			community, err := parseCommunity(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, community)
+		})
+	}
+}
+
+func TestParseRouterIDs(t *testing.T) {
+	ipv4, otherIPv4, ipv6 := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("fd00::1")
+	tests := []struct {
+		name               string
+		routerID           net.IP
+		routerIDv4         net.IP
+		routerIDv6         net.IP
+		expectedRouterID   net.IP
+		expectedRouterIDv6 net.IP
+		expectError        bool
+	}{
+		{name: "no router id"},
+		{name: "single router id", routerID: ipv4, expectedRouterID: ipv4},
+		{name: "ipv4 router id", routerIDv4: ipv4, expectedRouterID: ipv4},
+		{name: "ipv6 router id", routerIDv6: ipv6, expectedRouterIDv6: ipv6},
+		{name: "router id per family", routerIDv4: ipv4, routerIDv6: ipv6, expectedRouterID: ipv4, expectedRouterIDv6: ipv6},
+		{name: "single router id and ipv6 router id", routerID: ipv4, routerIDv6: ipv6, expectedRouterID: ipv4, expectedRouterIDv6: ipv6},
+		{name: "single and ipv4 router ids", routerID: otherIPv4, routerIDv4: ipv4, expectError: true},
+		{name: "ipv6 address as ipv4 router id", routerIDv4: ipv6, expectError: true},
+		{name: "ipv4 address as ipv6 router id", routerIDv6: ipv4, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routerID, routerIDv6, err := parseRouterIDs(tt.routerID, tt.routerIDv4, tt.routerIDv6)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedRouterID, routerID)
+			require.Equal(t, tt.expectedRouterIDv6, routerIDv6)
+		})
+	}
+}
+
+func TestValidatePeerTTL(t *testing.T) {
+	require.Error(t, validatePeerTTL(0))
+	require.NoError(t, validatePeerTTL(1))
+	require.NoError(t, validatePeerTTL(255))
+}
+
+func TestSetPeerTTL(t *testing.T) {
+	tests := []struct {
+		name                 string
+		ttl                  uint8
+		gtsm                 bool
+		expectedEbgpMultihop *api.EbgpMultihop
+		expectedTtlSecurity  *api.TtlSecurity
+	}{
+		{name: "default TTL", ttl: DefaultEbgpMultiHop},
+		{name: "EBGP multihop", ttl: 3, expectedEbgpMultihop: &api.EbgpMultihop{Enabled: true, MultihopTtl: 3}},
+		{name: "GTSM with directly connected peers", ttl: DefaultEbgpMultiHop, gtsm: true, expectedTtlSecurity: &api.TtlSecurity{Enabled: true, TtlMin: 255}},
+		{name: "GTSM with peers 3 hops away", ttl: 3, gtsm: true, expectedTtlSecurity: &api.TtlSecurity{Enabled: true, TtlMin: 253}},
+		{name: "GTSM with the maximum number of hops", ttl: 255, gtsm: true, expectedTtlSecurity: &api.TtlSecurity{Enabled: true, TtlMin: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{EbgpMultihopTTL: tt.ttl, EnableGTSM: tt.gtsm}
+			peer := &api.Peer{Conf: &api.PeerConf{NeighborAddress: "10.32.32.1", PeerAsn: 65001}}
+			config.setPeerTTL(peer)
+			require.True(t, proto.Equal(tt.expectedEbgpMultihop, peer.EbgpMultihop), "unexpected EBGP multihop %v", peer.EbgpMultihop)
+			require.True(t, proto.Equal(tt.expectedTtlSecurity, peer.TtlSecurity), "unexpected TTL security %v", peer.TtlSecurity)
+
+			// The BGP server accepts the peer, EBGP multihop and TTL security being exclusive
+			s := newTestBgpServer(t)
+			require.NoError(t, s.AddPeer(context.Background(), &api.AddPeerRequest{Peer: peer}))
+			var peers []*api.Peer
+			require.NoError(t, s.ListPeer(context.Background(), &api.ListPeerRequest{}, func(p *api.Peer) {
+				peers = append(peers, p)
+			}))
+			require.Len(t, peers, 1)
+			require.Equal(t, tt.gtsm, peers[0].GetTtlSecurity().GetEnabled())
+		})
+	}
+}
@@ -1,10 +1,17 @@
 package speaker
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
@@ -14,6 +21,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
 
 	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	kubeovninformer "github.com/kubeovn/kube-ovn/pkg/client/informers/externalversions"
@@ -41,8 +49,51 @@ type Controller struct {
 	natgatewayLister kubeovnlister.VpcNatGatewayLister
 	natgatewaySynced cache.InformerSynced
 
+	// nodesLister only lists the node of the speaker, it is nil unless the node weight label is configured
+	nodesLister listerv1.NodeLister
+	nodesSynced cache.InformerSynced
+
+	announced *announcedStore
+	sessions  *sessionTracker
+	// announcer announces and withdraws the routes, the BGP server of the configuration if nil
+	announcer RouteAnnouncer
+	// withdrawLimiter paces the withdrawals of routes, nil if they are not paced
+	withdrawLimiter *rate.Limiter
+	// stopCtx is canceled once the controller is stopped, nil until it runs
+	stopCtx context.Context
+	// events publishes the announcements and withdrawals of routes, nil if the route events socket is not configured
+	events *routeEventPublisher
+
+	// exportFilters are the filters applied by the export policy of the BGP server
+	exportFilters        neighborFilters
+	exportPolicyAssigned bool
+	// prefixesOverLimit is the number of prefixes not advertised to each neighbor because of its prefix limit
+	prefixesOverLimit map[string]int
+	// unreachableNextHops associates the neighbors whose next hop is unreachable and the reason why
+	unreachableNextHops map[string]string
+	// loopedPrefixes are the announced prefixes received back from each neighbor already reported
+	loopedPrefixes map[string][]string
+	// eipsWithoutAddress is the set of names of the ready EIPs without any address already reported
+	eipsWithoutAddress set.Set[string]
+	// eipsConflictingWithPeering is the set of names of the EIPs whose address is a BGP peering address already reported
+	eipsConflictingWithPeering set.Set[string]
+	// savedState are the announced routes and their attributes last saved to the state file
+	savedState prefixAttributes
+	// lastRoutesRefresh is when the announced routes were last advertised again to every neighbor
+	lastRoutesRefresh time.Time
+	// reconcileCh triggers a reconciliation without waiting for the next periodic one
+	reconcileCh chan struct{}
+	// leading is whether the speaker holds the Lease with leader election
+	leading atomic.Bool
+	// startTime is when the speaker started, initialAnnounceReleased whether the initial announce hold ended
+	startTime               time.Time
+	initialAnnounceReleased atomic.Bool
+	// suppressedReason is why the last reconciliation suppressed the announcements, empty if it did not
+	suppressedReason atomic.Value
+
 	informerFactory        kubeinformers.SharedInformerFactory
 	podInformerFactory     kubeinformers.SharedInformerFactory
+	nodeInformerFactory    kubeinformers.SharedInformerFactory
 	kubeovnInformerFactory kubeovninformer.SharedInformerFactory
 	recorder               record.EventRecorder
 }
@@ -54,7 +105,11 @@ func NewController(config *Configuration) *Controller {
 	eventBroadcaster.StartLogging(klog.Infof)
 	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: config.KubeClient.CoreV1().Events(corev1.NamespaceAll)})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
+	return newController(config, recorder)
+}
 
+// newController creates a controller recording its events with the given recorder
+func newController(config *Configuration, recorder record.EventRecorder) *Controller {
 	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(config.KubeClient, 0,
 		kubeinformers.WithTransform(util.TrimManagedFields),
 		kubeinformers.WithTweakListOptions(func(listOption *metav1.ListOptions) {
@@ -92,34 +147,206 @@ func NewController(config *Configuration) *Controller {
 		natgatewayLister: natgatewayInformer.Lister(),
 		natgatewaySynced: natgatewayInformer.Informer().HasSynced,
 
+		announced: newAnnouncedStore(),
+		sessions:  newSessionTracker(),
+
+		prefixesOverLimit:          make(map[string]int),
+		unreachableNextHops:        make(map[string]string),
+		eipsWithoutAddress:         set.New[string](),
+		eipsConflictingWithPeering: set.New[string](),
+		reconcileCh:                make(chan struct{}, 1),
+		startTime:                  time.Now(),
+		withdrawLimiter:            newWithdrawLimiter(config.WithdrawRate, config.WithdrawBurst),
+
 		informerFactory:        informerFactory,
 		podInformerFactory:     podInformerFactory,
 		kubeovnInformerFactory: kubeovnInformerFactory,
 		recorder:               recorder,
 	}
 
+	if _, err := podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: controller.enqueueUpdatePod,
+	}); err != nil {
+		util.LogFatalAndExit(err, "failed to add pod event handler")
+	}
+	if _, err := eipInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: controller.enqueueUpdateEIP,
+	}); err != nil {
+		util.LogFatalAndExit(err, "failed to add iptables eip event handler")
+	}
+	if _, err := natgatewayInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: controller.enqueueUpdateNatGateway,
+	}); err != nil {
+		util.LogFatalAndExit(err, "failed to add vpc nat gateway event handler")
+	}
+	if _, err := subnetInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: controller.enqueueDeleteSubnet,
+	}); err != nil {
+		util.LogFatalAndExit(err, "failed to add subnet event handler")
+	}
+
+	if config.NodeWeightLabel != "" {
+		controller.nodeInformerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(config.KubeClient, 0,
+			kubeinformers.WithTransform(util.TrimManagedFields),
+			kubeinformers.WithTweakListOptions(func(listOption *metav1.ListOptions) {
+				listOption.FieldSelector = "metadata.name=" + config.NodeName
+				listOption.AllowWatchBookmarks = true
+			}))
+		nodeInformer := controller.nodeInformerFactory.Core().V1().Nodes()
+		controller.nodesLister = nodeInformer.Lister()
+		controller.nodesSynced = nodeInformer.Informer().HasSynced
+		if _, err := nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: controller.enqueueUpdateNode,
+		}); err != nil {
+			util.LogFatalAndExit(err, "failed to add node event handler")
+		}
+	}
+
 	return controller
 }
 
 func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
+	c.stopCtx = wait.ContextForChannel(stopCh)
 	c.informerFactory.Start(stopCh)
 	c.podInformerFactory.Start(stopCh)
 	c.kubeovnInformerFactory.Start(stopCh)
+	if c.nodeInformerFactory != nil {
+		c.nodeInformerFactory.Start(stopCh)
+	}
+
+	if c.config.RouteEventsSocket != "" {
+		events, err := newRouteEventPublisher(c.config.RouteEventsSocket)
+		if err != nil {
+			util.LogFatalAndExit(err, "failed to publish route events")
+		}
+		c.events = events
+		go c.events.serve(stopCh)
+	}
+
+	// Announce the routes announced before a restart right away, they are reconciled once the caches synced
+	c.restoreAnnouncedState()
 
-	if !cache.WaitForCacheSync(stopCh, c.podsSynced, c.subnetSynced, c.servicesSynced, c.eipSynced) {
-		util.LogFatalAndExit(nil, "failed to wait for caches to sync")
+	if err := c.waitForCacheSync(stopCh); err != nil {
+		util.LogFatalAndExit(err, "failed to wait for caches to sync")
 		return
 	}
 
-	klog.Info("Started workers")
-	go wait.Until(c.Reconcile, 5*time.Second, stopCh)
+	if err := c.watchSessions(c.stopCtx); err != nil {
+		util.LogFatalAndExit(err, "failed to watch BGP sessions")
+	}
+
+	go c.handleSnapshotSignal(stopCh)
+
+	if c.config.EnableLeaderElection {
+		elector, err := c.newLeaderElector(leaderLeaseDuration, leaderRenewDeadline, leaderRetryPeriod)
+		if err != nil {
+			util.LogFatalAndExit(err, "failed to run leader election")
+		}
+		go c.runLeaderElection(elector, stopCh)
+	}
 
-	<-stopCh
+	if c.config.InitialAnnounceDelay != 0 {
+		// Announce the routes as soon as the initial announce delay elapsed
+		time.AfterFunc(time.Until(c.startTime.Add(c.config.InitialAnnounceDelay)), c.requestReconcile)
+	}
+
+	klog.Info("Started workers")
+	// Reconcile in the foreground: once stopCh is closed, runReconcileLoop returns only after the reconciliation
+	// in progress, if any, completed, so that its announcements and withdrawals are all recorded.
+	c.runReconcileLoop(stopCh)
 	klog.Info("Shutting down workers")
+	c.gracefulShutdown()
 }
 
-func (c *Controller) Reconcile() {
+// runReconcileLoop reconciles the routes every 5 seconds, or as soon as a reconciliation is requested,
+// until stopCh is closed
+func (c *Controller) runReconcileLoop(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	origin := reconcileOriginStartup
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+
+		c.Reconcile(origin)
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			origin = reconcileOriginPeriodic
+		case <-c.reconcileCh:
+			origin = reconcileOriginEvent
+		}
+	}
+}
+
+// requestReconcile makes the routes be reconciled without waiting for the next periodic reconciliation
+func (c *Controller) requestReconcile() {
+	select {
+	case c.reconcileCh <- struct{}{}:
+	default: // a reconciliation is already requested
+	}
+}
+
+// waitForCacheSync waits for the caches of the informers to sync, at most for the cache sync timeout if any.
+// The error returned when the caches did not sync lists the informers which did not sync.
+func (c *Controller) waitForCacheSync(stopCh <-chan struct{}) error {
+	type namedInformer struct {
+		name   string
+		synced cache.InformerSynced
+	}
+	informers := []namedInformer{
+		{"pods", c.podsSynced},
+		{"subnets", c.subnetSynced},
+		{"services", c.servicesSynced},
+		{"iptables-eips", c.eipSynced},
+		{"vpc-nat-gateways", c.natgatewaySynced},
+	}
+	if c.nodesSynced != nil {
+		informers = append(informers, namedInformer{"nodes", c.nodesSynced})
+	}
+
+	ctx := wait.ContextForChannel(stopCh)
+	if c.config.CacheSyncTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.CacheSyncTimeout)
+		defer cancel()
+	}
+
+	synced := make([]cache.InformerSynced, 0, len(informers))
+	for _, informer := range informers {
+		synced = append(synced, informer.synced)
+	}
+	if cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil
+	}
+
+	var notSynced []string
+	for _, informer := range informers {
+		if !informer.synced() {
+			notSynced = append(notSynced, informer.name)
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("informers %s did not sync within %s", strings.Join(notSynced, ", "), c.config.CacheSyncTimeout)
+	}
+	return fmt.Errorf("stopped before informers %s synced", strings.Join(notSynced, ", "))
+}
+
+// Reconcile reconciles the routes, counting the routes announced by the reconciliation with the origin of
+// the reconciliation: startup, event or reconcile for the periodic reconciliations
+func (c *Controller) Reconcile(origin string) {
+	announced := set.New(c.announced.List()...)
+	defer func() {
+		countAnnouncedRoutes(origin, announced, c.announced.List())
+		c.saveAnnouncedState()
+	}()
+
 	if c.config.NatGwMode {
 		err := c.syncEIPRoutes()
 		if err != nil {
@@ -128,4 +355,43 @@ func (c *Controller) Reconcile() {
 	} else {
 		c.syncSubnetRoutes()
 	}
+
+	c.readvertiseEstablishedSessions()
+	c.refreshRoutesIfDue(time.Now())
+	c.detectRouteLoops()
+}
+
+// getDesiredRoutes returns the prefixes we should be announcing, and their attributes
+func (c *Controller) getDesiredRoutes() (prefixMap, prefixAttributes, error) {
+	var expectedPrefixes prefixMap
+	var attrs prefixAttributes
+	var err error
+	if c.config.NatGwMode {
+		expectedPrefixes, attrs, err = c.getEIPDesiredRoutes()
+	} else {
+		expectedPrefixes, err = c.getSubnetDesiredRoutes()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.addStaticPrefixes(expectedPrefixes)
+	return expectedPrefixes, attrs, nil
+}
+
+// recordEvent records an event on the object the speaker announces routes for,
+// its VPC NAT gateway in NAT gateway mode and its node otherwise
+func (c *Controller) recordEvent(eventType, reason, messageFmt string, args ...any) {
+	if c.config.NatGwMode {
+		gw, err := c.natgatewayLister.Get(getGatewayName())
+		if err != nil {
+			klog.Errorf("failed to get vpc nat gateway to record event %s: %v", reason, err)
+			return
+		}
+		c.recorder.Eventf(gw, eventType, reason, messageFmt, args...)
+		return
+	}
+
+	node := &corev1.ObjectReference{Kind: "Node", Name: c.config.NodeName, UID: types.UID(c.config.NodeName)}
+	c.recorder.Eventf(node, eventType, reason, messageFmt, args...)
 }
@@ -0,0 +1,136 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	kubeovnfake "github.com/kubeovn/kube-ovn/pkg/client/clientset/versioned/fake"
+	kubeovnlister "github.com/kubeovn/kube-ovn/pkg/client/listers/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestRunDrainsOnShutdown(t *testing.T) {
+	subnet := &kubeovnv1.Subnet{
+		ObjectMeta: metav1.ObjectMeta{Name: "subnet", Annotations: map[string]string{util.BgpAnnotation: "true"}},
+		Spec:       kubeovnv1.SubnetSpec{CIDRBlock: "10.16.0.0/16"},
+	}
+	subnet.Status.SetCondition(kubeovnv1.ConditionType(kubeovnv1.Ready), "Init", "")
+
+	neighbor := net.ParseIP("10.32.32.1")
+	c := NewController(&Configuration{
+		BgpServer:              newTestBgpServer(t),
+		NeighborAddresses:      []net.IP{neighbor},
+		NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		KubeClient:             fake.NewClientset(),
+		KubeOvnClient:          kubeovnfake.NewClientset(subnet),
+	})
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.Run(stopCh)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return c.announced.Has("10.16.0.0/16") }, 10*time.Second, 10*time.Millisecond)
+	close(stopCh)
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("controller did not stop")
+	}
+	require.Equal(t, []string{"10.16.0.0/16"}, c.announced.List())
+}
+
+func TestWaitForCacheSync(t *testing.T) {
+	synced := func() bool { return true }
+	neverSynced := func() bool { return false }
+	c := &Controller{
+		config:           &Configuration{CacheSyncTimeout: 200 * time.Millisecond},
+		podsSynced:       synced,
+		subnetSynced:     neverSynced,
+		servicesSynced:   synced,
+		eipSynced:        neverSynced,
+		natgatewaySynced: synced,
+	}
+
+	// The informers which never sync are reported once the timeout expired
+	start := time.Now()
+	err := c.waitForCacheSync(make(chan struct{}))
+	require.EqualError(t, err, "informers subnets, iptables-eips did not sync within 200ms")
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// or once stopped
+	c.config.CacheSyncTimeout = 0
+	stopCh := make(chan struct{})
+	close(stopCh)
+	require.EqualError(t, c.waitForCacheSync(stopCh), "stopped before informers subnets, iptables-eips synced")
+
+	c.subnetSynced, c.eipSynced = synced, synced
+	require.NoError(t, c.waitForCacheSync(make(chan struct{})))
+}
+
+func TestEIPMovedToAnotherGateway(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	eip := &kubeovnv1.IptablesEIP{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "eip",
+			Labels:      map[string]string{util.VpcNatGatewayNameLabel: "gw1"},
+			Annotations: map[string]string{util.BgpAnnotation: "true"},
+		},
+		Spec:   kubeovnv1.IptablesEIPSpec{V4ip: "192.168.1.1", NatGwDp: "gw1"},
+		Status: kubeovnv1.IptablesEIPStatus{Ready: true},
+	}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, eipIndexer.Add(eip))
+
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:              newTestBgpServer(t),
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			NatGwMode:              true,
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		announced:          newAnnouncedStore(),
+		eipsWithoutAddress: set.New[string](),
+		reconcileCh:        make(chan struct{}, 1),
+	}
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.runReconcileLoop(stopCh)
+		close(done)
+	}()
+	defer func() {
+		close(stopCh)
+		<-done
+	}()
+	require.Eventually(t, func() bool { return c.announced.Has("192.168.1.1/32") }, 2*time.Second, 10*time.Millisecond)
+
+	// Updating the EIP without moving it does not trigger a reconciliation
+	updated := eip.DeepCopy()
+	updated.Annotations[util.BgpDrainAnnotation] = "true"
+	c.enqueueUpdateEIP(eip, updated)
+	require.Empty(t, c.reconcileCh)
+
+	// Moving the EIP to another gateway withdraws it before the next periodic reconciliation, even if its label lags behind
+	moved := eip.DeepCopy()
+	moved.Spec.NatGwDp = "gw2"
+	require.NoError(t, eipIndexer.Update(moved))
+	c.enqueueUpdateEIP(eip, moved)
+	require.Eventually(t, func() bool { return !c.announced.Has("192.168.1.1/32") }, 2*time.Second, 10*time.Millisecond)
+}
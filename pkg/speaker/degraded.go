@@ -0,0 +1,51 @@
+package speaker
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+// getDegradedCommunity returns the community the routes of the EIPs are announced with while the NAT gateway is
+// degraded, or zero when it is healthy or no degraded community is configured. The gateway is degraded when its
+// pod is running but the health probe container, whose readiness probe checks the health of the gateway, is not
+// ready, so that upstream routers deprioritize its routes rather than losing them.
+func (c *Controller) getDegradedCommunity() uint32 {
+	if c.config.DegradedCommunity == 0 {
+		return 0
+	}
+
+	pod, err := c.podsLister.Pods(c.config.PodNamespace).Get(c.config.PodName)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to get pod %s/%s: %v", c.config.PodNamespace, c.config.PodName, err)
+		}
+		return 0
+	}
+	if !isGatewayDegraded(pod, c.config.HealthProbeContainer) {
+		return 0
+	}
+	return c.config.DegradedCommunity
+}
+
+// isGatewayDegraded returns whether a NAT gateway pod is running while its health probe container is not ready.
+// A container without status yet is not considered as failing its probe.
+func isGatewayDegraded(pod *corev1.Pod, container string) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	i := slices.IndexFunc(pod.Status.ContainerStatuses, func(status corev1.ContainerStatus) bool {
+		return status.Name == container
+	})
+	return i != -1 && !pod.Status.ContainerStatuses[i].Ready
+}
+
+// isDegradedUpdate returns whether the update of the pod of the speaker changes whether its NAT gateway is degraded
+func (c *Controller) isDegradedUpdate(oldPod, newPod *corev1.Pod) bool {
+	if c.config.DegradedCommunity == 0 || newPod.Name != c.config.PodName || newPod.Namespace != c.config.PodNamespace {
+		return false
+	}
+	return isGatewayDegraded(oldPod, c.config.HealthProbeContainer) != isGatewayDegraded(newPod, c.config.HealthProbeContainer)
+}
@@ -0,0 +1,108 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	kubeovnlister "github.com/kubeovn/kube-ovn/pkg/client/listers/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestIsGatewayDegraded(t *testing.T) {
+	pod := &corev1.Pod{Status: corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "vpc-nat-gw", Ready: true},
+			{Name: "speaker", Ready: true},
+		},
+	}}
+	require.False(t, isGatewayDegraded(pod, "vpc-nat-gw"))
+
+	unhealthy := pod.DeepCopy()
+	unhealthy.Status.ContainerStatuses[0].Ready = false
+	require.True(t, isGatewayDegraded(unhealthy, "vpc-nat-gw"))
+	require.False(t, isGatewayDegraded(unhealthy, "speaker"))
+	require.False(t, isGatewayDegraded(unhealthy, "unknown"))
+
+	// A pod which is not running is not degraded
+	unhealthy.Status.Phase = corev1.PodPending
+	require.False(t, isGatewayDegraded(unhealthy, "vpc-nat-gw"))
+}
+
+func TestDegradedCommunity(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	const community = 65000<<16 | 666
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc-nat-gw-gw1-0", Namespace: "kube-system"},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "vpc-nat-gw", Ready: true}},
+		},
+	}
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	require.NoError(t, podIndexer.Add(pod))
+	eip := newTestEIP("eip1", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"})
+
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:              true,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			DegradedCommunity:      community,
+			HealthProbeContainer:   "vpc-nat-gw",
+			PodName:                pod.Name,
+			PodNamespace:           pod.Namespace,
+		},
+		podsLister:         listerv1.NewPodLister(podIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		eipsWithoutAddress: set.New[string](),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+		reconcileCh:        make(chan struct{}, 1),
+	}
+	reconcile := func() routeAttributes {
+		expected, attrs := c.getGatewayEIPsExpectedRoutes([]*kubeovnv1.IptablesEIP{eip})
+		require.Empty(t, c.reconcileRoutes(expected, attrs).failed())
+		return c.announced.Attributes()["192.168.1.1/32"]
+	}
+
+	// The routes of a healthy gateway are announced without the degraded community
+	require.Zero(t, reconcile().degradedCommunity)
+
+	// They are announced again with the community once the health probe fails, the pod running
+	unhealthy := pod.DeepCopy()
+	unhealthy.Status.ContainerStatuses[0].Ready = false
+	require.NoError(t, podIndexer.Update(unhealthy))
+	c.enqueueUpdatePod(pod, unhealthy)
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+	attrs := reconcile()
+	require.Equal(t, uint32(community), attrs.degradedCommunity)
+	require.Contains(t, attrs.toAPIAttributes(65000), &api.Attribute{
+		Attr: &api.Attribute_Communities{Communities: &api.CommunitiesAttribute{Communities: []uint32{community}}},
+	})
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// and without the community once healthy again
+	require.NoError(t, podIndexer.Update(pod))
+	c.enqueueUpdatePod(unhealthy, pod)
+	require.Len(t, c.reconcileCh, 1)
+	require.Zero(t, reconcile().degradedCommunity)
+
+	// The community is never advertised when not configured
+	c.config.DegradedCommunity = 0
+	require.NoError(t, podIndexer.Update(unhealthy))
+	require.Zero(t, reconcile().degradedCommunity)
+}
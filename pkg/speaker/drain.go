@@ -0,0 +1,30 @@
+package speaker
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+
+	v1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// isEIPDrained returns whether an EIP is drained at the given time: either drained with the drain annotation, or
+// scheduled for maintenance with the drain-until annotation set to an RFC 3339 timestamp still in the future.
+// EIPs drained until a timestamp are announced again by the first reconciliation once it has passed.
+func isEIPDrained(eip *v1.IptablesEIP, now time.Time) bool {
+	if eip.Annotations[util.BgpDrainAnnotation] == "true" {
+		return true
+	}
+
+	value := eip.Annotations[util.BgpDrainUntilAnnotation]
+	if value == "" {
+		return false
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		klog.Errorf("invalid annotation %s=%s on EIP %s, not draining it: %v", util.BgpDrainUntilAnnotation, value, eip.Name, err)
+		return false
+	}
+	return now.Before(until)
+}
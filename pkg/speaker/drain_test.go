@@ -0,0 +1,61 @@
+package speaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestIsEIPDrained(t *testing.T) {
+	deadline := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		now         time.Time
+		expected    bool
+	}{
+		{name: "not drained", now: deadline},
+		{name: "drained", annotations: map[string]string{util.BgpDrainAnnotation: "true"}, now: deadline, expected: true},
+		{name: "before deadline", annotations: map[string]string{util.BgpDrainUntilAnnotation: "2024-06-01T12:00:00Z"}, now: deadline.Add(-time.Second), expected: true},
+		{name: "at deadline", annotations: map[string]string{util.BgpDrainUntilAnnotation: "2024-06-01T12:00:00Z"}, now: deadline},
+		{name: "after deadline", annotations: map[string]string{util.BgpDrainUntilAnnotation: "2024-06-01T12:00:00Z"}, now: deadline.Add(time.Hour)},
+		{name: "deadline with offset", annotations: map[string]string{util.BgpDrainUntilAnnotation: "2024-06-01T14:00:00+02:00"}, now: deadline.Add(-time.Second), expected: true},
+		{name: "drained after deadline", annotations: map[string]string{util.BgpDrainAnnotation: "true", util.BgpDrainUntilAnnotation: "2024-06-01T12:00:00Z"}, now: deadline.Add(time.Hour), expected: true},
+		{name: "invalid deadline", annotations: map[string]string{util.BgpDrainUntilAnnotation: "tomorrow"}, now: deadline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eip := newTestEIP("eip", "192.168.1.1", "", true, tt.annotations)
+			require.Equal(t, tt.expected, isEIPDrained(eip, tt.now))
+		})
+	}
+}
+
+func TestGetEIPExpectedPrefixesDrainUntil(t *testing.T) {
+	eip := newTestEIP("eip", "192.168.1.1", "", true, map[string]string{
+		util.BgpAnnotation:           "true",
+		util.BgpDrainUntilAnnotation: time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	other := newTestEIP("eip-other", "192.168.1.2", "", true, map[string]string{util.BgpAnnotation: "true"})
+
+	// EIPs drained until a future timestamp are withdrawn, or announced with the draining community
+	prefixes, _ := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip, other}, routeAttributes{})
+	require.Equal(t, []string{"192.168.1.2/32"}, expectedPrefixList(prefixes))
+
+	community := uint32(65000<<16 | 666)
+	prefixes, attrs := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip, other}, routeAttributes{drainingCommunity: community})
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, expectedPrefixList(prefixes))
+	require.True(t, attrs["192.168.1.1/32"].draining)
+	require.False(t, attrs["192.168.1.2/32"].draining)
+
+	// and announced again once the timestamp has passed
+	eip.Annotations[util.BgpDrainUntilAnnotation] = time.Now().Add(-time.Second).Format(time.RFC3339)
+	prefixes, attrs = getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip, other}, routeAttributes{drainingCommunity: community})
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, expectedPrefixList(prefixes))
+	require.False(t, attrs["192.168.1.1/32"].draining)
+}
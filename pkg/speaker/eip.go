@@ -3,21 +3,66 @@ package speaker
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
 
 	v1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
 	"github.com/kubeovn/kube-ovn/pkg/util"
 )
 
-// syncEIPRoutes retrieves all the EIPs attached to our GWs and starts announcing their route
+// maxGatewayPriority is the highest priority a GW can be configured with using the BGP priority annotation
+const maxGatewayPriority = math.MaxUint16
+
+// syncEIPRoutes retrieves all the EIPs attached to our GWs and starts announcing their route. A summary of the
+// reconciliation is logged and counted by result.
 func (c *Controller) syncEIPRoutes() error {
+	start := time.Now()
+	eips, err := c.listGatewayEIPs()
+	if err != nil {
+		return err
+	}
+	metricLocalEIPs.Set(float64(len(eips)))
+
+	c.reportEIPs(eips)
+	expectedPrefixes, attrs := c.getGatewayEIPsExpectedRoutes(eips)
+	skipped := countSkippedEIPs(eips, expectedPrefixes)
+	results := c.reconcileRoutes(expectedPrefixes, attrs)
+	summary := newReconcileSummary(len(eips), results, c.announced, time.Since(start))
+	summary.skipped = skipped
+	summary.count()
+	summary.log()
+	return nil
+}
+
+// getEIPDesiredRoutes returns the prefixes we should be announcing for the EIPs attached to our GW, and their attributes
+func (c *Controller) getEIPDesiredRoutes() (prefixMap, prefixAttributes, error) {
+	eips, err := c.listGatewayEIPs()
+	if err != nil {
+		return nil, nil, err
+	}
+	expectedPrefixes, attrs := c.getGatewayEIPsExpectedRoutes(eips)
+	return expectedPrefixes, attrs, nil
+}
+
+// listGatewayEIPs returns the EIPs attached to our GW which are considered for announcement
+func (c *Controller) listGatewayEIPs() ([]*v1.IptablesEIP, error) {
 	// Retrieve the name of our gateway
 	gatewayName := getGatewayName()
 	if gatewayName == "" {
-		return errors.New("failed to retrieve the name of the gateway, might not be running in a gateway pod")
+		return nil, errors.New("failed to retrieve the name of the gateway, might not be running in a gateway pod")
 	}
 
 	// Create label requirements to only get EIPs attached to our NAT GW
@@ -25,7 +70,7 @@ func (c *Controller) syncEIPRoutes() error {
 	if err != nil {
 		err = fmt.Errorf("failed to create label selector requirement: %w", err)
 		klog.Error(err)
-		return err
+		return nil, err
 	}
 
 	// Filter all EIPs attached to our NAT GW
@@ -33,34 +78,488 @@ func (c *Controller) syncEIPRoutes() error {
 	if err != nil {
 		err = fmt.Errorf("failed to list EIPs attached to our GW: %w", err)
 		klog.Error(err)
-		return err
+		return nil, err
 	}
 
-	if err = c.announceEIPs(eips); err != nil {
-		err = fmt.Errorf("failed to announce EIPs: %w", err)
-		klog.Error(err)
-		return err
+	// The label of an EIP may lag behind its gateway, EIPs moved to another gateway are withdrawn right away
+	eips = slices.DeleteFunc(eips, func(eip *v1.IptablesEIP) bool {
+		return !isGatewayEIP(eip, gatewayName)
+	})
+
+	// EIPs on external subnets not selected by the filter are not announced, nor are the EIPs of VPCs
+	// outside of the allowlist and the EIPs whose announce condition is not true yet
+	eips = slices.DeleteFunc(eips, func(eip *v1.IptablesEIP) bool {
+		return !c.isExternalSubnetSelected(eip) || !c.isVpcAllowed(eip) || !c.isAnnounceConditionTrue(eip)
+	})
+	return eips, nil
+}
+
+// getGatewayEIPsExpectedRoutes returns the prefixes we should be announcing for the EIPs attached to our GW,
+// and their attributes. It has no side effect, the EIPs which cannot be announced are reported by reportEIPs.
+func (c *Controller) getGatewayEIPsExpectedRoutes(eips []*v1.IptablesEIP) (prefixMap, prefixAttributes) {
+	eips = c.removePeeringEIPs(c.getServedEIPs(eips))
+	gwAttrs := c.getGatewayRouteAttributes(getGatewayName())
+	c.applyNodeWeight(&gwAttrs)
+	gwAttrs.drainingCommunity = c.config.DrainingCommunity
+	gwAttrs.degradedCommunity = c.getDegradedCommunity()
+	if c.config.DefaultLocalPref != nil {
+		gwAttrs.hasLocalPref, gwAttrs.localPref = true, *c.config.DefaultLocalPref
 	}
-	return nil
+	expectedPrefixes, attrs := getEIPExpectedPrefixes(eips, gwAttrs)
+	c.addSubnetNeighbors(attrs)
+	c.addPointToPointPrefixes(expectedPrefixes, attrs)
+	return expectedPrefixes, attrs
 }
 
-// announceEIPs announce all the prefixes related to EIPs attached to a GW
-func (c *Controller) announceEIPs(eips []*v1.IptablesEIP) error {
+// getEIPExpectedPrefixes returns the prefixes we should be announcing for EIPs attached to a GW, and their attributes
+func getEIPExpectedPrefixes(eips []*v1.IptablesEIP, gwAttrs routeAttributes) (prefixMap, prefixAttributes) {
 	expectedPrefixes := make(prefixMap)
+	attrs := make(prefixAttributes)
 	for _, eip := range eips {
 		// Only announce EIPs marked as "ready" and with the BGP annotation set to true
 		if eip.Annotations[util.BgpAnnotation] != "true" || !eip.Status.Ready {
 			continue
 		}
 
-		if eip.Spec.V4ip != "" { // If we have an IPv4, add it to prefixes we should be announcing
-			addExpectedPrefix(eip.Spec.V4ip, expectedPrefixes)
+		// Drained EIPs are withdrawn while left otherwise untouched, they are announced again once undrained.
+		// With a draining community, they are announced with the community instead, until fully withdrawn.
+		draining := isEIPDrained(eip, time.Now())
+		if draining && gwAttrs.drainingCommunity == 0 {
+			klog.V(3).Infof("EIP %s is drained, not announcing it", eip.Name)
+			continue
+		}
+
+		neighbors, err := getEIPNeighbors(eip)
+		if err != nil {
+			klog.Errorf("invalid annotation %s on EIP %s, not announcing it: %v", util.BgpNeighborAnnotation, eip.Name, err)
+			continue
+		}
+		eipAttrs := gwAttrs
+		eipAttrs.neighbors = neighbors
+		eipAttrs.draining = draining
+		if localPref := eip.Annotations[util.BgpLocalPrefAnnotation]; localPref != "" {
+			// The LOCAL_PREF of an EIP overrides the default one
+			if value, err := parseLocalPref(localPref); err != nil {
+				klog.Errorf("invalid annotation %s=%s on EIP %s: %v", util.BgpLocalPrefAnnotation, localPref, eip.Name, err)
+			} else {
+				eipAttrs.hasLocalPref, eipAttrs.localPref = true, value
+			}
+		}
+
+		// EIPs without any address yet are announced once it is populated
+		if v4ip := getEIPv4Address(eip); v4ip != "" { // If we have an IPv4, add it to prefixes we should be announcing
+			addEIPExpectedPrefix(eip, v4ip, v1.ProtocolIPv4, getEIPFamilyAttributes(eip, v1.ProtocolIPv4, eipAttrs), expectedPrefixes, attrs)
 		}
 
 		if eip.Spec.V6ip != "" { // If we have an IPv6, add it to prefixes we should be announcing
-			addExpectedPrefix(eip.Spec.V6ip, expectedPrefixes)
+			addEIPExpectedPrefix(eip, eip.Spec.V6ip, v1.ProtocolIPv6, getEIPFamilyAttributes(eip, v1.ProtocolIPv6, eipAttrs), expectedPrefixes, attrs)
+		}
+	}
+
+	return expectedPrefixes, attrs
+}
+
+// isExternalSubnetSelected returns whether an EIP is on one of the external subnets of the external subnet filter,
+// every EIP being selected if the filter is empty
+func (c *Controller) isExternalSubnetSelected(eip *v1.IptablesEIP) bool {
+	return len(c.config.ExternalSubnetFilter) == 0 ||
+		slices.Contains(c.config.ExternalSubnetFilter, util.GetExternalNetwork(eip.Spec.ExternalSubnet))
+}
+
+// externalSubnetExists returns whether the external subnet of an EIP exists, it is assumed to exist when
+// it cannot be retrieved
+func (c *Controller) externalSubnetExists(eip *v1.IptablesEIP) bool {
+	subnet := util.GetExternalNetwork(eip.Spec.ExternalSubnet)
+	if _, err := c.subnetsLister.Get(subnet); err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.V(3).Infof("external subnet %s of EIP %s does not exist, not announcing it", subnet, eip.Name)
+			return false
 		}
+		klog.Errorf("failed to get external subnet %s of EIP %s: %v", subnet, eip.Name, err)
 	}
+	return true
+}
 
-	return c.reconcileRoutes(expectedPrefixes)
+// getServedEIPs returns the EIPs which can be served by our GW
+func (c *Controller) getServedEIPs(eips []*v1.IptablesEIP) []*v1.IptablesEIP {
+	// EIPs whose external subnet was deleted cannot be served, they are withdrawn until they are deleted too
+	eips = slices.DeleteFunc(slices.Clone(eips), func(eip *v1.IptablesEIP) bool {
+		return !c.externalSubnetExists(eip)
+	})
+	// EIPs whose GW is being deleted are withdrawn right away rather than once the GW pod is gone
+	return slices.DeleteFunc(eips, func(eip *v1.IptablesEIP) bool {
+		return c.isEIPGatewayDeleting(eip)
+	})
+}
+
+// reportEIPs reports the EIPs attached to our GW which cannot be announced. Only the reconciliation reports them,
+// not the snapshots of the routes which are taken concurrently.
+func (c *Controller) reportEIPs(eips []*v1.IptablesEIP) {
+	eips = c.getServedEIPs(eips)
+	c.checkEIPAddresses(eips)
+	c.checkPeeringEIPs(eips)
+}
+
+// isEIPGatewayDeleting returns whether the vpc nat gateway of an EIP is being deleted, it is assumed not to be when
+// it cannot be resolved
+func (c *Controller) isEIPGatewayDeleting(eip *v1.IptablesEIP) bool {
+	gatewayName := eip.Labels[util.VpcNatGatewayNameLabel]
+	gw, err := c.natgatewayLister.Get(gatewayName)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to get vpc nat gateway %s of EIP %s: %v", gatewayName, eip.Name, err)
+		}
+		return false
+	}
+	if gw.DeletionTimestamp != nil {
+		klog.V(3).Infof("vpc nat gateway %s of EIP %s is being deleted, not announcing it", gatewayName, eip.Name)
+		return true
+	}
+	return false
+}
+
+// getExternalSubnetEIPs returns the EIPs attached to our GW on an external subnet
+func (c *Controller) getExternalSubnetEIPs(subnet string) ([]*v1.IptablesEIP, error) {
+	eips, err := c.listGatewayEIPs()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(eips, func(eip *v1.IptablesEIP) bool {
+		return util.GetExternalNetwork(eip.Spec.ExternalSubnet) != subnet
+	}), nil
+}
+
+// isVpcAllowed returns whether the VPC of an EIP is in the VPC allowlist, every EIP being allowed if the allowlist
+// is empty. EIPs whose VPC cannot be resolved are not allowed.
+func (c *Controller) isVpcAllowed(eip *v1.IptablesEIP) bool {
+	if len(c.config.VpcAllowlist) == 0 {
+		return true
+	}
+	vpc, err := c.getEIPVpc(eip)
+	if err != nil {
+		klog.Errorf("failed to resolve the VPC of EIP %s, not announcing it: %v", eip.Name, err)
+		return false
+	}
+	return slices.Contains(c.config.VpcAllowlist, vpc)
+}
+
+// getEIPVpc returns the VPC of an EIP, which is the VPC of the NAT GW it is attached to
+func (c *Controller) getEIPVpc(eip *v1.IptablesEIP) (string, error) {
+	gatewayName := eip.Spec.NatGwDp
+	if gatewayName == "" {
+		gatewayName = eip.Labels[util.VpcNatGatewayNameLabel]
+	}
+	if gatewayName == "" {
+		return "", errors.New("EIP is not attached to any vpc nat gateway")
+	}
+	gw, err := c.natgatewayLister.Get(gatewayName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get vpc nat gateway %s: %w", gatewayName, err)
+	}
+	if gw.Spec.Vpc == "" {
+		return "", fmt.Errorf("vpc nat gateway %s has no VPC", gatewayName)
+	}
+	return gw.Spec.Vpc, nil
+}
+
+// isAnnounceConditionTrue returns whether the announce condition of an EIP is true for its current generation,
+// on top of being ready. It is always true if no announce condition is configured.
+func (c *Controller) isAnnounceConditionTrue(eip *v1.IptablesEIP) bool {
+	if c.config.AnnounceOnCondition == "" {
+		return true
+	}
+	conditions := v1.Conditions(eip.Status.Conditions)
+	return conditions.IsConditionTrue(v1.ConditionType(c.config.AnnounceOnCondition), eip.Generation)
+}
+
+// getEIPv4Address returns the IPv4 address of an EIP, the address found in its status once the controller
+// synced it and the address of its spec until then
+func getEIPv4Address(eip *v1.IptablesEIP) string {
+	if eip.Status.IP != "" {
+		return eip.Status.IP
+	}
+	return eip.Spec.V4ip
+}
+
+// isGatewayEIP returns whether an EIP is attached to a gateway, as selected by listGatewayEIPs: the EIP is labeled
+// with the gateway, and its gateway is the same one unless not set yet, since its label may lag behind its gateway
+func isGatewayEIP(eip *v1.IptablesEIP, gatewayName string) bool {
+	if eip.Labels[util.VpcNatGatewayNameLabel] != gatewayName {
+		return false
+	}
+	return eip.Spec.NatGwDp == "" || eip.Spec.NatGwDp == gatewayName
+}
+
+// enqueueUpdateEIP requests a reconciliation when an EIP is moved from or to our GW, so that the GW which
+// no longer hosts the EIP withdraws its routes without waiting for the next periodic reconciliation.
+// A full reconciliation is also requested when an EIP of our GW, as selected by listGatewayEIPs, gets its IPv4
+// address populated, becomes ready or not ready anymore, has its announce condition change, or moves to another
+// external subnet, which may no longer be selected or exist.
+func (c *Controller) enqueueUpdateEIP(oldObj, newObj any) {
+	oldEIP, newEIP := oldObj.(*v1.IptablesEIP), newObj.(*v1.IptablesEIP)
+	if !c.config.NatGwMode {
+		return
+	}
+	gatewayEIP := isGatewayEIP(newEIP, getGatewayName())
+	if oldEIP.Spec.ExternalSubnet != newEIP.Spec.ExternalSubnet && gatewayEIP {
+		klog.Infof("EIP %s moved from external subnet %s to %s, reconciling its routes", newEIP.Name, oldEIP.Spec.ExternalSubnet, newEIP.Spec.ExternalSubnet)
+		c.requestReconcile()
+		return
+	}
+	if !c.isExternalSubnetSelected(newEIP) {
+		return
+	}
+	if oldEIP.Status.Ready != newEIP.Status.Ready && gatewayEIP && newEIP.Annotations[util.BgpAnnotation] == "true" {
+		// A full reconciliation is requested, which announces or withdraws the route of this EIP
+		klog.Infof("EIP %s became ready=%t, reconciling its routes", newEIP.Name, newEIP.Status.Ready)
+		c.requestReconcile()
+		return
+	}
+	if getEIPv4Address(oldEIP) == "" && getEIPv4Address(newEIP) != "" && gatewayEIP {
+		klog.Infof("IPv4 address of EIP %s populated, reconciling its routes", newEIP.Name)
+		c.requestReconcile()
+		return
+	}
+	if c.isAnnounceConditionTrue(oldEIP) != c.isAnnounceConditionTrue(newEIP) && gatewayEIP {
+		klog.Infof("condition %s of EIP %s changed, reconciling its routes", c.config.AnnounceOnCondition, newEIP.Name)
+		c.requestReconcile()
+		return
+	}
+	if oldEIP.Spec.NatGwDp == newEIP.Spec.NatGwDp {
+		return
+	}
+	if gatewayName := getGatewayName(); oldEIP.Spec.NatGwDp != gatewayName && newEIP.Spec.NatGwDp != gatewayName {
+		return
+	}
+
+	klog.Infof("EIP %s moved from gateway %s to %s, reconciling its routes", newEIP.Name, oldEIP.Spec.NatGwDp, newEIP.Spec.NatGwDp)
+	c.requestReconcile()
+}
+
+// enqueueUpdateNatGateway reconciles the routes of the EIPs attached to our GW when it starts being deleted,
+// so that they are withdrawn right away
+func (c *Controller) enqueueUpdateNatGateway(oldObj, newObj any) {
+	oldGw, newGw := oldObj.(*v1.VpcNatGateway), newObj.(*v1.VpcNatGateway)
+	if !c.config.NatGwMode || newGw.Name != getGatewayName() {
+		return
+	}
+	if oldGw.DeletionTimestamp == nil && newGw.DeletionTimestamp != nil {
+		klog.Infof("vpc nat gateway %s is being deleted, withdrawing the routes of its EIPs", newGw.Name)
+		c.requestReconcile()
+	}
+}
+
+// enqueueDeleteSubnet reconciles the routes of the EIPs attached to our GW when their external subnet is deleted,
+// so that they are withdrawn right away
+func (c *Controller) enqueueDeleteSubnet(obj any) {
+	var subnet *v1.Subnet
+	switch t := obj.(type) {
+	case *v1.Subnet:
+		subnet = t
+	case cache.DeletedFinalStateUnknown:
+		s, ok := t.Obj.(*v1.Subnet)
+		if !ok {
+			klog.Warningf("unexpected object type: %T", t.Obj)
+			return
+		}
+		subnet = s
+	default:
+		klog.Warningf("unexpected type: %T", obj)
+		return
+	}
+	if !c.config.NatGwMode {
+		return
+	}
+
+	eips, err := c.getExternalSubnetEIPs(subnet.Name)
+	if err != nil {
+		klog.Errorf("failed to get the EIPs of deleted external subnet %s: %v", subnet.Name, err)
+		return
+	}
+	if len(eips) == 0 {
+		return
+	}
+	names := make([]string, 0, len(eips))
+	for _, eip := range eips {
+		names = append(names, eip.Name)
+	}
+	klog.Infof("external subnet %s deleted, withdrawing the routes of EIPs %v", subnet.Name, names)
+	c.requestReconcile()
+}
+
+// checkEIPAddresses reports the EIPs which should be announced but have neither an IPv4 nor an IPv6 address,
+// each of them being reported once until it gets an address. It is only called by the reconciliation, which owns
+// the state of the reported EIPs.
+func (c *Controller) checkEIPAddresses(eips []*v1.IptablesEIP) {
+	withoutAddress := set.New[string]()
+	for _, eip := range eips {
+		if eip.Annotations[util.BgpAnnotation] != "true" || !eip.Status.Ready || getEIPv4Address(eip) != "" || eip.Spec.V6ip != "" {
+			continue
+		}
+
+		withoutAddress.Insert(eip.Name)
+		if c.eipsWithoutAddress.Has(eip.Name) {
+			continue
+		}
+		klog.Warningf("EIP %s is ready but has no address, it cannot be announced", eip.Name)
+		c.recorder.Event(eip, corev1.EventTypeWarning, "EIPNoAddress", "EIP is ready but has no address, it cannot be announced")
+		metricEIPNoAddress.Inc()
+	}
+	c.eipsWithoutAddress = withoutAddress
+}
+
+// getPeeringAddresses returns the addresses of the BGP neighbors and the local addresses the speaker peers with
+// them from
+func (c *Controller) getPeeringAddresses() []net.IP {
+	var peeringAddresses []net.IP
+	for _, neighbor := range c.config.allNeighborAddresses() {
+		peeringAddresses = append(peeringAddresses, neighbor, c.getNextHopAttribute(neighbor))
+	}
+	return peeringAddresses
+}
+
+// getEIPPeeringAddress returns the address of an EIP which is one of the peering addresses, nil if none is
+func getEIPPeeringAddress(eip *v1.IptablesEIP, peeringAddresses []net.IP) net.IP {
+	for _, address := range []string{getEIPv4Address(eip), eip.Spec.V6ip} {
+		if ip := net.ParseIP(address); ip != nil && slices.ContainsFunc(peeringAddresses, ip.Equal) {
+			return ip
+		}
+	}
+	return nil
+}
+
+// removePeeringEIPs removes the EIPs whose address is the address of a BGP neighbor or the local address the
+// speaker peers with it from: announcing them could disrupt the BGP sessions. They are reported by checkPeeringEIPs.
+func (c *Controller) removePeeringEIPs(eips []*v1.IptablesEIP) []*v1.IptablesEIP {
+	peeringAddresses := c.getPeeringAddresses()
+	return slices.DeleteFunc(eips, func(eip *v1.IptablesEIP) bool {
+		return getEIPPeeringAddress(eip, peeringAddresses) != nil
+	})
+}
+
+// checkPeeringEIPs reports the EIPs whose address is a BGP peering address, a warning event being recorded once for
+// each of them until it does not conflict anymore. It is only called by the reconciliation, which owns the state
+// of the reported EIPs.
+func (c *Controller) checkPeeringEIPs(eips []*v1.IptablesEIP) {
+	peeringAddresses := c.getPeeringAddresses()
+	conflicting := set.New[string]()
+	for _, eip := range eips {
+		ip := getEIPPeeringAddress(eip, peeringAddresses)
+		if ip == nil {
+			continue
+		}
+		conflicting.Insert(eip.Name)
+		if !c.eipsConflictingWithPeering.Has(eip.Name) {
+			klog.Warningf("address %s of EIP %s is a BGP peering address, the EIP is not announced to protect the BGP sessions", ip, eip.Name)
+			c.recorder.Eventf(eip, corev1.EventTypeWarning, "EIPIsPeeringAddress", "Address %s is a BGP peering address, the EIP is not announced to protect the BGP sessions", ip)
+		}
+	}
+	c.eipsConflictingWithPeering = conflicting
+}
+
+// addEIPExpectedPrefix adds the prefix of an EIP address to the prefixes we should be announcing, along with its attributes
+func addEIPExpectedPrefix(eip *v1.IptablesEIP, ip, protocol string, gwAttrs routeAttributes, expectedPrefixes prefixMap, attrs prefixAttributes) {
+	prefix, err := parseEIPDestination(ip, protocol)
+	if err != nil {
+		klog.Errorf("failed to parse address of EIP %s: %v", eip.Name, err)
+		return
+	}
+	if p := addExpectedPrefix(prefix.String(), expectedPrefixes); p != "" {
+		// Routes of the EIPs of an external subnet are announced together
+		eipAttrs := gwAttrs
+		eipAttrs.batch = eip.Spec.ExternalSubnet
+		eipAttrs.instance = getEIPInstance(eip)
+		attrs[p] = eipAttrs
+	}
+}
+
+// getEIPFamilyAttributes returns the attributes of the route of the address of an EIP of the given protocol. The MED
+// of the family set by the bgp-med-v4 or bgp-med-v6 annotation of the EIP overrides the MED of its GW, so that the
+// IPv4 and IPv6 routes of a dual-stack EIP can be preferred differently.
+func getEIPFamilyAttributes(eip *v1.IptablesEIP, protocol string, eipAttrs routeAttributes) routeAttributes {
+	annotation := util.BgpMEDv4Annotation
+	if protocol == v1.ProtocolIPv6 {
+		annotation = util.BgpMEDv6Annotation
+	}
+	value := eip.Annotations[annotation]
+	if value == "" {
+		return eipAttrs
+	}
+	med, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		klog.Errorf("invalid annotation %s=%s on EIP %s: MED must be a number between 0 and %d", annotation, value, eip.Name, uint32(math.MaxUint32))
+		return eipAttrs
+	}
+	eipAttrs.hasMED, eipAttrs.med = true, uint32(med)
+	return eipAttrs
+}
+
+// parseEIPDestination returns the host prefix (/32 or /128) announced for an EIP address of the given protocol.
+// Addresses with a prefix length are rejected, announcing the whole network of an EIP would attract the traffic
+// of addresses which are not ours.
+func parseEIPDestination(ip, protocol string) (netip.Prefix, error) {
+	if strings.Contains(ip, "/") {
+		return netip.Prefix{}, fmt.Errorf("EIP address %q must not have a prefix length", ip)
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid EIP address %q: %w", ip, err)
+	}
+	if protocol == v1.ProtocolIPv6 && addr.Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("EIP address %q is an IPv4-mapped IPv6 address, it must be the IPv4 address of the EIP", ip)
+	}
+	if addr = addr.Unmap(); (protocol == v1.ProtocolIPv6) != addr.Is6() {
+		return netip.Prefix{}, fmt.Errorf("EIP address %q is not an %s address", ip, protocol)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// getGatewayRouteAttributes returns the attributes of the routes announced for the EIPs of a GW
+func (c *Controller) getGatewayRouteAttributes(gatewayName string) routeAttributes {
+	gw, err := c.natgatewayLister.Get(gatewayName)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to get vpc nat gateway %s: %v", gatewayName, err)
+		}
+		return routeAttributes{}
+	}
+
+	var attrs routeAttributes
+	if priority := gw.Annotations[util.BgpPriorityAnnotation]; priority != "" {
+		med, err := priorityToMED(priority)
+		if err != nil {
+			klog.Errorf("invalid annotation %s=%s on vpc nat gateway %s: %v", util.BgpPriorityAnnotation, priority, gatewayName, err)
+		} else {
+			attrs.hasMED, attrs.med = true, med
+		}
+	}
+	if bandwidth := gw.Annotations[util.BgpLinkBandwidthAnnotation]; bandwidth != "" {
+		linkBandwidth, err := parseLinkBandwidth(bandwidth)
+		if err != nil {
+			klog.Errorf("invalid annotation %s=%s on vpc nat gateway %s: %v", util.BgpLinkBandwidthAnnotation, bandwidth, gatewayName, err)
+		} else {
+			attrs.linkBandwidth = linkBandwidth
+		}
+	}
+	return attrs
+}
+
+// parseLinkBandwidth converts a GW bandwidth in Mbit/s to the bandwidth advertised in the
+// link bandwidth extended community, which is expressed in bytes per second
+func parseLinkBandwidth(bandwidth string) (float32, error) {
+	mbps, err := strconv.ParseUint(bandwidth, 10, 32)
+	if err != nil || mbps == 0 {
+		return 0, fmt.Errorf("bandwidth must be a positive integer in Mbit/s")
+	}
+	return float32(mbps) * 1000 * 1000 / 8, nil
+}
+
+// priorityToMED converts a GW priority to the MED of its routes: the higher the priority,
+// the lower the MED and the more preferred the GW is by upstream routers
+func priorityToMED(priority string) (uint32, error) {
+	p, err := strconv.ParseUint(priority, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("priority must be an integer between 0 and %d", maxGatewayPriority)
+	}
+	return uint32(maxGatewayPriority - p), nil
 }
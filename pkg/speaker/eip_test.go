@@ -0,0 +1,1006 @@
+package speaker
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	kubeovnlister "github.com/kubeovn/kube-ovn/pkg/client/listers/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestPriorityToMED(t *testing.T) {
+	tests := []struct {
+		name        string
+		priority    string
+		expectedMED uint32
+		expectError bool
+	}{
+		{name: "lowest priority has the highest MED", priority: "0", expectedMED: maxGatewayPriority},
+		{name: "highest priority has the lowest MED", priority: "65535", expectedMED: 0},
+		{name: "higher priority has a lower MED", priority: "100", expectedMED: maxGatewayPriority - 100},
+		{name: "negative priority", priority: "-1", expectError: true},
+		{name: "priority out of range", priority: "65536", expectError: true},
+		{name: "non numeric priority", priority: "high", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			med, err := priorityToMED(tt.priority)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedMED, med)
+		})
+	}
+}
+
+func TestGetGatewayRouteAttributes(t *testing.T) {
+	newController := func(gws ...*kubeovnv1.VpcNatGateway) *Controller {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		for _, gw := range gws {
+			require.NoError(t, indexer.Add(gw))
+		}
+		return &Controller{natgatewayLister: kubeovnlister.NewVpcNatGatewayLister(indexer)}
+	}
+	newGateway := func(name string, annotations map[string]string) *kubeovnv1.VpcNatGateway {
+		return &kubeovnv1.VpcNatGateway{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+	}
+
+	c := newController(
+		newGateway("gw-priority", map[string]string{util.BgpPriorityAnnotation: "10"}),
+		newGateway("gw-invalid", map[string]string{util.BgpPriorityAnnotation: "invalid"}),
+		newGateway("gw-default", nil),
+		newGateway("gw-bandwidth", map[string]string{util.BgpPriorityAnnotation: "10", util.BgpLinkBandwidthAnnotation: "1000"}),
+		newGateway("gw-invalid-bandwidth", map[string]string{util.BgpLinkBandwidthAnnotation: "10G"}),
+	)
+
+	require.Equal(t, routeAttributes{hasMED: true, med: maxGatewayPriority - 10}, c.getGatewayRouteAttributes("gw-priority"))
+	require.Equal(t, routeAttributes{}, c.getGatewayRouteAttributes("gw-invalid"))
+	require.Equal(t, routeAttributes{}, c.getGatewayRouteAttributes("gw-default"))
+	require.Equal(t, routeAttributes{}, c.getGatewayRouteAttributes("gw-missing"))
+	require.Equal(t, routeAttributes{hasMED: true, med: maxGatewayPriority - 10, linkBandwidth: 125000000}, c.getGatewayRouteAttributes("gw-bandwidth"))
+	require.Equal(t, routeAttributes{}, c.getGatewayRouteAttributes("gw-invalid-bandwidth"))
+}
+
+func TestParseLinkBandwidth(t *testing.T) {
+	tests := []struct {
+		name        string
+		bandwidth   string
+		expected    float32
+		expectError bool
+	}{
+		{name: "1 Mbit/s", bandwidth: "1", expected: 125000},
+		{name: "10 Gbit/s", bandwidth: "10000", expected: 1250000000},
+		{name: "zero bandwidth", bandwidth: "0", expectError: true},
+		{name: "negative bandwidth", bandwidth: "-1", expectError: true},
+		{name: "bandwidth with unit", bandwidth: "10G", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bandwidth, err := parseLinkBandwidth(tt.bandwidth)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, bandwidth)
+		})
+	}
+}
+
+func TestGatewayBandwidthChange(t *testing.T) {
+	eips := []*kubeovnv1.IptablesEIP{newTestEIP("eip", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"})}
+	store := newAnnouncedStore()
+
+	prefixes, attrs := getEIPExpectedPrefixes(eips, routeAttributes{linkBandwidth: 125000})
+	store.Add("192.168.1.1/32", attrs["192.168.1.1/32"])
+	toAdd, toDel := store.Diff(api.Family_AFI_IP, prefixes[api.Family_AFI_IP], attrs)
+	require.Empty(t, toAdd)
+	require.Empty(t, toDel)
+
+	// A bandwidth change announces the EIP again with the new community
+	prefixes, attrs = getEIPExpectedPrefixes(eips, routeAttributes{linkBandwidth: 1250000})
+	toAdd, toDel = store.Diff(api.Family_AFI_IP, prefixes[api.Family_AFI_IP], attrs)
+	require.Equal(t, []string{"192.168.1.1/32"}, toAdd.SortedList())
+	require.Empty(t, toDel)
+}
+
+func newTestEIP(name, v4ip, v6ip string, ready bool, annotations map[string]string) *kubeovnv1.IptablesEIP {
+	return &kubeovnv1.IptablesEIP{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Spec:       kubeovnv1.IptablesEIPSpec{V4ip: v4ip, V6ip: v6ip},
+		Status:     kubeovnv1.IptablesEIPStatus{Ready: ready},
+	}
+}
+
+// newTestSubnetLister returns a lister of the subnets of the given names, the default external subnet by default
+func newTestSubnetLister(t *testing.T, names ...string) kubeovnlister.SubnetLister {
+	t.Helper()
+	if len(names) == 0 {
+		names = []string{util.GetExternalNetwork("")}
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range names {
+		require.NoError(t, indexer.Add(&kubeovnv1.Subnet{ObjectMeta: metav1.ObjectMeta{Name: name}}))
+	}
+	return kubeovnlister.NewSubnetLister(indexer)
+}
+
+// expectedPrefixList returns all the prefixes of a prefix map, whatever their address family
+func expectedPrefixList(prefixes prefixMap) []string {
+	var list []string
+	for _, s := range prefixes {
+		list = append(list, s.UnsortedList()...)
+	}
+	return list
+}
+
+func TestGetEIPExpectedPrefixes(t *testing.T) {
+	bgp := map[string]string{util.BgpAnnotation: "true"}
+	gwAttrs := routeAttributes{hasMED: true, med: 10}
+
+	eips := []*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-v4", "192.168.1.1", "", true, bgp),
+		newTestEIP("eip-dual", "192.168.1.2", "2001:db8::2", true, bgp),
+		newTestEIP("eip-not-ready", "192.168.1.3", "", false, bgp),
+		newTestEIP("eip-no-bgp", "192.168.1.4", "", true, nil),
+	}
+	prefixes, attrs := getEIPExpectedPrefixes(eips, gwAttrs)
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "192.168.1.2/32", "2001:db8::2/128"}, expectedPrefixList(prefixes))
+	for _, prefix := range expectedPrefixList(prefixes) {
+		require.Equal(t, gwAttrs, attrs[prefix])
+	}
+}
+
+func TestGetEIPExpectedPrefixesDrain(t *testing.T) {
+	eip := newTestEIP("eip", "192.168.1.1", "2001:db8::1", true, map[string]string{util.BgpAnnotation: "true"})
+
+	prefixes, _ := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip}, routeAttributes{})
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "2001:db8::1/128"}, expectedPrefixList(prefixes))
+
+	// Draining the EIP withdraws its routes
+	eip.Annotations[util.BgpDrainAnnotation] = "true"
+	prefixes, _ = getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip}, routeAttributes{})
+	require.Empty(t, expectedPrefixList(prefixes))
+
+	// Any other value does not drain the EIP
+	eip.Annotations[util.BgpDrainAnnotation] = "false"
+	prefixes, _ = getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip}, routeAttributes{})
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "2001:db8::1/128"}, expectedPrefixList(prefixes))
+
+	// Removing the annotation announces the EIP again
+	eip.Annotations[util.BgpDrainAnnotation] = "true"
+	prefixes, _ = getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip}, routeAttributes{})
+	require.Empty(t, expectedPrefixList(prefixes))
+	delete(eip.Annotations, util.BgpDrainAnnotation)
+	prefixes, _ = getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip}, routeAttributes{})
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "2001:db8::1/128"}, expectedPrefixList(prefixes))
+}
+
+func TestParseEIPDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		ip          string
+		protocol    string
+		expected    string
+		expectError bool
+	}{
+		{name: "ipv4 address", ip: "192.168.1.1", protocol: kubeovnv1.ProtocolIPv4, expected: "192.168.1.1/32"},
+		{name: "ipv6 address", ip: "2001:db8::1", protocol: kubeovnv1.ProtocolIPv6, expected: "2001:db8::1/128"},
+		{name: "ipv4-mapped ipv6 address", ip: "::ffff:192.168.1.1", protocol: kubeovnv1.ProtocolIPv4, expected: "192.168.1.1/32"},
+		{name: "ipv4 address with a prefix length", ip: "192.168.1.1/24", protocol: kubeovnv1.ProtocolIPv4, expectError: true},
+		{name: "ipv6 address with a prefix length", ip: "2001:db8::1/64", protocol: kubeovnv1.ProtocolIPv6, expectError: true},
+		{name: "ipv4 host prefix", ip: "192.168.1.1/32", protocol: kubeovnv1.ProtocolIPv4, expectError: true},
+		{name: "ipv6 address as ipv4", ip: "2001:db8::1", protocol: kubeovnv1.ProtocolIPv4, expectError: true},
+		{name: "ipv4 address as ipv6", ip: "192.168.1.1", protocol: kubeovnv1.ProtocolIPv6, expectError: true},
+		{name: "invalid address", ip: "192.168.1", protocol: kubeovnv1.ProtocolIPv4, expectError: true},
+		{name: "ipv4-mapped ipv6 address as ipv6", ip: "::ffff:192.168.1.1", protocol: kubeovnv1.ProtocolIPv6, expectError: true},
+		{name: "ipv4-compatible ipv6 address as ipv6", ip: "::192.168.1.1", protocol: kubeovnv1.ProtocolIPv6, expected: "::c0a8:101/128"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, err := parseEIPDestination(tt.ip, tt.protocol)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, prefix.String())
+		})
+	}
+}
+
+func TestGetEIPExpectedPrefixesInvalidAddress(t *testing.T) {
+	eips := []*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-v4-cidr", "192.168.1.1/24", "2001:db8::1", true, map[string]string{util.BgpAnnotation: "true"}),
+		newTestEIP("eip-v6-cidr", "192.168.1.2", "2001:db8::2/64", true, map[string]string{util.BgpAnnotation: "true"}),
+	}
+
+	// Only the valid addresses of the EIPs are announced
+	prefixes, _ := getEIPExpectedPrefixes(eips, routeAttributes{})
+	require.ElementsMatch(t, []string{"2001:db8::1/128", "192.168.1.2/32"}, expectedPrefixList(prefixes))
+}
+
+func TestCheckEIPAddresses(t *testing.T) {
+	bgp := map[string]string{util.BgpAnnotation: "true"}
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{recorder: recorder, eipsWithoutAddress: set.New[string]()}
+	before := testutil.ToFloat64(metricEIPNoAddress)
+
+	noAddress := newTestEIP("eip-no-address", "", "", true, bgp)
+	eips := []*kubeovnv1.IptablesEIP{
+		noAddress,
+		newTestEIP("eip-v4", "192.168.1.1", "", true, bgp),
+		newTestEIP("eip-not-ready", "", "", false, bgp),
+		newTestEIP("eip-no-bgp", "", "", true, nil),
+	}
+	expected, _ := getEIPExpectedPrefixes(eips, routeAttributes{})
+	require.ElementsMatch(t, []string{"192.168.1.1/32"}, expectedPrefixList(expected))
+
+	// The EIP without address is only reported once
+	c.checkEIPAddresses(eips)
+	c.checkEIPAddresses(eips)
+	require.Equal(t, set.New("eip-no-address"), c.eipsWithoutAddress)
+	require.Equal(t, before+1, testutil.ToFloat64(metricEIPNoAddress))
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "EIPNoAddress")
+
+	// It is reported again if it loses its address after getting one
+	noAddress.Spec.V4ip = "192.168.1.2"
+	c.checkEIPAddresses(eips)
+	require.Empty(t, c.eipsWithoutAddress)
+	noAddress.Spec.V4ip = ""
+	c.checkEIPAddresses(eips)
+	require.Equal(t, before+2, testutil.ToFloat64(metricEIPNoAddress))
+	require.Len(t, recorder.Events, 1)
+}
+
+func TestRemovePeeringEIPs(t *testing.T) {
+	bgp := map[string]string{util.BgpAnnotation: "true"}
+	recorder := record.NewFakeRecorder(10)
+	neighbor, neighborV6 := net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:     []net.IP{neighbor},
+			NeighborIPv6Addresses: []net.IP{neighborV6},
+			NeighborLocalAddresses: map[string]net.IP{
+				neighbor.String():   net.ParseIP("10.32.32.2"),
+				neighborV6.String(): net.ParseIP("fd00::2"),
+			},
+		},
+		recorder:                   recorder,
+		eipsConflictingWithPeering: set.New[string](),
+	}
+
+	eip := newTestEIP("eip", "192.168.1.1", "fd00:1::1", true, bgp)
+	neighborEIP := newTestEIP("eip-neighbor", "10.32.32.1", "", true, bgp)
+	localEIP := newTestEIP("eip-local", "192.168.1.2", "fd00::2", true, bgp)
+	eips := []*kubeovnv1.IptablesEIP{eip, neighborEIP, localEIP}
+
+	// The EIPs conflicting with the peering addresses are removed without being reported
+	require.Equal(t, []*kubeovnv1.IptablesEIP{eip}, c.removePeeringEIPs(slices.Clone(eips)))
+	require.Empty(t, c.eipsConflictingWithPeering)
+	require.Empty(t, recorder.Events)
+
+	// and are only reported once
+	c.checkPeeringEIPs(eips)
+	c.checkPeeringEIPs(eips)
+	require.Equal(t, set.New("eip-neighbor", "eip-local"), c.eipsConflictingWithPeering)
+	require.Len(t, recorder.Events, 2)
+	require.Contains(t, <-recorder.Events, "EIPIsPeeringAddress Address 10.32.32.1")
+	require.Contains(t, <-recorder.Events, "EIPIsPeeringAddress Address fd00::2")
+
+	// An EIP is reported again if it conflicts again after being fixed
+	neighborEIP.Spec.V4ip = "192.168.1.3"
+	require.Len(t, c.removePeeringEIPs(slices.Clone(eips)), 2)
+	c.checkPeeringEIPs(eips)
+	neighborEIP.Spec.V4ip = "10.32.32.1"
+	require.Len(t, c.removePeeringEIPs(slices.Clone(eips)), 1)
+	c.checkPeeringEIPs(eips)
+	require.Len(t, recorder.Events, 1)
+}
+
+func TestReportEIPsOnlyOnReconciliation(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	bgp := map[string]string{util.BgpAnnotation: "true"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, eip := range []*kubeovnv1.IptablesEIP{
+		newTestEIP("eip", "192.168.1.1", "", true, bgp),
+		newTestEIP("eip-no-address", "", "", true, bgp),
+		newTestEIP("eip-neighbor", "10.32.32.1", "", true, bgp),
+	} {
+		eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+		require.NoError(t, eipIndexer.Add(eip))
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:              true,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		eipLister:                  kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:              newTestSubnetLister(t),
+		natgatewayLister:           kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		recorder:                   recorder,
+		eipsWithoutAddress:         set.New[string](),
+		eipsConflictingWithPeering: set.New[string](),
+		announced:                  newAnnouncedStore(),
+		announcer:                  newFakeAnnouncer(),
+	}
+
+	// Computing the desired routes, e.g. for a snapshot, reports nothing
+	expected, _, err := c.getEIPDesiredRoutes()
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.168.1.1/32"}, expectedPrefixList(expected))
+	require.Empty(t, recorder.Events)
+	require.Empty(t, c.eipsWithoutAddress)
+	require.Empty(t, c.eipsConflictingWithPeering)
+
+	// The reconciliation does
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, set.New("eip-no-address"), c.eipsWithoutAddress)
+	require.Equal(t, set.New("eip-neighbor"), c.eipsConflictingWithPeering)
+	require.Len(t, recorder.Events, 2)
+}
+
+func TestDrainingCommunity(t *testing.T) {
+	s := newTestBgpServer(t)
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:              s,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced: newAnnouncedStore(),
+	}
+	community := uint32(65000<<16 | 666)
+
+	// getCommunities returns the communities of the routes announced by the BGP server
+	getCommunities := func() map[string][]uint32 {
+		communities := make(map[string][]uint32)
+		require.NoError(t, s.ListPath(apiutil.ListPathRequest{
+			TableType: api.TableType_TABLE_TYPE_GLOBAL,
+			Family:    bgp.RF_IPv4_UC,
+		}, func(prefix bgp.NLRI, paths []*apiutil.Path) {
+			communities[prefix.String()] = nil
+			for _, attr := range paths[0].Attrs {
+				if a, ok := attr.(*bgp.PathAttributeCommunities); ok {
+					communities[prefix.String()] = a.Value
+				}
+			}
+		}))
+		return communities
+	}
+	reconcile := func(eips []*kubeovnv1.IptablesEIP) {
+		prefixes, attrs := getEIPExpectedPrefixes(eips, routeAttributes{drainingCommunity: community})
+		c.reconcileIPFamily(api.Family_AFI_IP, prefixes, attrs, newAnnounceQueue(0))
+	}
+
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	eip := newTestEIP("eip", "192.168.1.1", "", true, bgpAnnotation)
+	other := newTestEIP("eip-other", "192.168.1.2", "", true, bgpAnnotation)
+
+	// Routes are announced without community normally
+	reconcile([]*kubeovnv1.IptablesEIP{eip, other})
+	require.Equal(t, map[string][]uint32{"192.168.1.1/32": nil, "192.168.1.2/32": nil}, getCommunities())
+
+	// with the draining community once drained
+	eip.Annotations = map[string]string{util.BgpAnnotation: "true", util.BgpDrainAnnotation: "true"}
+	reconcile([]*kubeovnv1.IptablesEIP{eip, other})
+	require.Equal(t, map[string][]uint32{"192.168.1.1/32": {community}, "192.168.1.2/32": nil}, getCommunities())
+
+	// and the community leaves with the route once fully withdrawn
+	reconcile([]*kubeovnv1.IptablesEIP{other})
+	require.Equal(t, map[string][]uint32{"192.168.1.2/32": nil}, getCommunities())
+
+	// Drained EIPs are withdrawn without draining community
+	prefixes, _ := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip, other}, routeAttributes{})
+	require.Equal(t, []string{"192.168.1.2/32"}, expectedPrefixList(prefixes))
+}
+
+func TestGetEIPExpectedPrefixesMismatchedFamilies(t *testing.T) {
+	bgp := map[string]string{util.BgpAnnotation: "true"}
+	eips := []*kubeovnv1.IptablesEIP{
+		// IPv4-mapped addresses stored in the IPv4 field are announced as IPv4
+		newTestEIP("eip-v4-mapped", "::ffff:192.168.1.1", "", true, bgp),
+		// addresses stored in the field of the other family are rejected
+		newTestEIP("eip-v4-in-v6", "", "192.168.1.2", true, bgp),
+		newTestEIP("eip-v6-in-v4", "2001:db8::3", "", true, bgp),
+		newTestEIP("eip-v4-mapped-in-v6", "", "::ffff:192.168.1.4", true, bgp),
+		newTestEIP("eip-swapped", "2001:db8::5", "192.168.1.5", true, bgp),
+		newTestEIP("eip-dual", "192.168.1.6", "2001:db8::6", true, bgp),
+	}
+
+	prefixes, _ := getEIPExpectedPrefixes(eips, routeAttributes{})
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "192.168.1.6/32"}, prefixes[api.Family_AFI_IP].UnsortedList())
+	require.ElementsMatch(t, []string{"2001:db8::6/128"}, prefixes[api.Family_AFI_IP6].UnsortedList())
+}
+
+func TestGetEIPExpectedPrefixesStatusAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		specIP   string
+		statusIP string
+		expected []string
+	}{
+		{name: "spec only", specIP: "192.168.1.1", expected: []string{"192.168.1.1/32"}},
+		{name: "status only", statusIP: "192.168.1.2", expected: []string{"192.168.1.2/32"}},
+		{name: "both populated", specIP: "192.168.1.1", statusIP: "192.168.1.2", expected: []string{"192.168.1.2/32"}},
+		{name: "none populated yet", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eip := newTestEIP("eip", tt.specIP, "", true, map[string]string{util.BgpAnnotation: "true"})
+			eip.Status.IP = tt.statusIP
+			prefixes, _ := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{eip}, routeAttributes{})
+			require.Equal(t, tt.expected, expectedPrefixList(prefixes))
+		})
+	}
+}
+
+func TestEnqueueUpdateEIPAddressPopulated(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	c := &Controller{config: &Configuration{NatGwMode: true}, reconcileCh: make(chan struct{}, 1)}
+
+	eip := newTestEIP("eip", "", "", true, map[string]string{util.BgpAnnotation: "true"})
+	eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+	eip.Spec.NatGwDp = "gw1"
+	populated := eip.DeepCopy()
+	populated.Status.IP = "192.168.1.1"
+
+	// The EIP of another gateway getting its address does not trigger a reconciliation
+	other, otherPopulated := eip.DeepCopy(), populated.DeepCopy()
+	other.Spec.NatGwDp, otherPopulated.Spec.NatGwDp = "gw2", "gw2"
+	other.Labels[util.VpcNatGatewayNameLabel], otherPopulated.Labels[util.VpcNatGatewayNameLabel] = "gw2", "gw2"
+	c.enqueueUpdateEIP(other, otherPopulated)
+	require.Empty(t, c.reconcileCh)
+
+	c.enqueueUpdateEIP(eip, populated)
+	require.Len(t, c.reconcileCh, 1)
+}
+
+func TestEnqueueUpdateEIPReadyChanged(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	c := &Controller{config: &Configuration{NatGwMode: true}, reconcileCh: make(chan struct{}, 1)}
+
+	eip := newTestEIP("eip", "192.168.1.1", "", false, map[string]string{util.BgpAnnotation: "true"})
+	eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+	eip.Spec.NatGwDp = "gw1"
+	ready := eip.DeepCopy()
+	ready.Status.Ready = true
+
+	// Updates which do not flip the readiness of the EIP do not trigger a reconciliation
+	updated := ready.DeepCopy()
+	updated.Status.Redo = "redo"
+	c.enqueueUpdateEIP(ready, updated)
+	require.Empty(t, c.reconcileCh)
+
+	// nor do the readiness flips of the EIPs of another gateway, of the EIPs not labeled with our gateway yet,
+	// or of EIPs not announced
+	other, otherReady := eip.DeepCopy(), ready.DeepCopy()
+	other.Spec.NatGwDp, otherReady.Spec.NatGwDp = "gw2", "gw2"
+	other.Labels[util.VpcNatGatewayNameLabel], otherReady.Labels[util.VpcNatGatewayNameLabel] = "gw2", "gw2"
+	c.enqueueUpdateEIP(other, otherReady)
+	unlabeled, unlabeledReady := eip.DeepCopy(), ready.DeepCopy()
+	unlabeled.Labels, unlabeledReady.Labels = nil, nil
+	c.enqueueUpdateEIP(unlabeled, unlabeledReady)
+	notAnnounced, notAnnouncedReady := eip.DeepCopy(), ready.DeepCopy()
+	notAnnounced.Annotations, notAnnouncedReady.Annotations = nil, nil
+	c.enqueueUpdateEIP(notAnnounced, notAnnouncedReady)
+	require.Empty(t, c.reconcileCh)
+
+	// The EIP becoming ready or not ready anymore is reconciled right away, even before its gateway is set
+	c.enqueueUpdateEIP(eip, ready)
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+	c.enqueueUpdateEIP(ready, eip)
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+	noGateway, noGatewayReady := eip.DeepCopy(), ready.DeepCopy()
+	noGateway.Spec.NatGwDp, noGatewayReady.Spec.NatGwDp = "", ""
+	c.enqueueUpdateEIP(noGateway, noGatewayReady)
+	require.Len(t, c.reconcileCh, 1)
+}
+
+func TestEnqueueUpdateEIPExternalSubnetChanged(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	c := &Controller{
+		config:      &Configuration{NatGwMode: true, ExternalSubnetFilter: []string{"ext1"}},
+		reconcileCh: make(chan struct{}, 1),
+	}
+
+	eip := newTestEIP("eip", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"})
+	eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+	eip.Spec.NatGwDp, eip.Spec.ExternalSubnet = "gw1", "ext1"
+	moved := eip.DeepCopy()
+	moved.Spec.ExternalSubnet = "ext2"
+
+	// The EIP of another gateway moving to another external subnet does not trigger a reconciliation
+	other, otherMoved := eip.DeepCopy(), moved.DeepCopy()
+	other.Spec.NatGwDp, otherMoved.Spec.NatGwDp = "gw2", "gw2"
+	other.Labels[util.VpcNatGatewayNameLabel], otherMoved.Labels[util.VpcNatGatewayNameLabel] = "gw2", "gw2"
+	c.enqueueUpdateEIP(other, otherMoved)
+	require.Empty(t, c.reconcileCh)
+
+	// The EIP moving out of the selected external subnets is reconciled so that its route is withdrawn
+	c.enqueueUpdateEIP(eip, moved)
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+
+	// The EIP moving back is reconciled so that its route is announced again
+	c.enqueueUpdateEIP(moved, eip)
+	require.Len(t, c.reconcileCh, 1)
+}
+
+func TestSyncEIPRoutesWithdrawDeletedEIP(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	eip1 := newTestEIP("eip1", "192.168.1.1", "", true, bgpAnnotation)
+	eip2 := newTestEIP("eip2", "192.168.1.2", "", true, bgpAnnotation)
+	for _, eip := range []*kubeovnv1.IptablesEIP{eip1, eip2} {
+		eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+	}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, eipIndexer.Add(eip1))
+	require.NoError(t, eipIndexer.Add(eip2))
+
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			NatGwMode:              true,
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+		eipsWithoutAddress: set.New[string](),
+	}
+
+	require.NoError(t, c.syncEIPRoutes())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+	require.True(t, a.isRouteAnnounced("192.168.1.2/32"))
+	require.Equal(t, float64(2), testutil.ToFloat64(metricLocalEIPs))
+
+	// The route of a deleted EIP is withdrawn, the other ones are left untouched
+	require.NoError(t, eipIndexer.Delete(eip1))
+	a.calls = nil
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, float64(1), testutil.ToFloat64(metricLocalEIPs))
+	require.Equal(t, []fakeAnnouncerCall{{withdraw: true, prefixes: []string{"192.168.1.1/32"}}}, a.calls)
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+	require.True(t, a.isRouteAnnounced("192.168.1.2/32"))
+	require.Equal(t, []string{"192.168.1.2/32"}, c.announced.List())
+
+	// Nothing is announced nor withdrawn once the routes are reconciled
+	a.calls = nil
+	require.NoError(t, c.syncEIPRoutes())
+	require.Empty(t, a.calls)
+}
+
+func TestSyncEIPRoutesWithdrawDeletingGateway(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	eip := newTestEIP("eip1", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"})
+	eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, eipIndexer.Add(eip))
+	gw := &kubeovnv1.VpcNatGateway{ObjectMeta: metav1.ObjectMeta{Name: "gw1"}}
+	gwIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, gwIndexer.Add(gw))
+
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			NatGwMode:              true,
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(gwIndexer),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+		eipsWithoutAddress: set.New[string](),
+		reconcileCh:        make(chan struct{}, 1),
+	}
+
+	require.NoError(t, c.syncEIPRoutes())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The EIPs of a GW being deleted are withdrawn right away, its deletion triggering a reconciliation
+	deleting := gw.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	require.NoError(t, gwIndexer.Update(deleting))
+	c.enqueueUpdateNatGateway(gw, deleting)
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+	require.True(t, c.isEIPGatewayDeleting(eip))
+	require.NoError(t, c.syncEIPRoutes())
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+	require.Empty(t, c.announced.List())
+
+	// Further updates of the GW do not trigger a reconciliation, nor do the updates of other GWs
+	c.enqueueUpdateNatGateway(deleting, deleting.DeepCopy())
+	other := &kubeovnv1.VpcNatGateway{ObjectMeta: metav1.ObjectMeta{Name: "gw2"}}
+	otherDeleting := other.DeepCopy()
+	otherDeleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	c.enqueueUpdateNatGateway(other, otherDeleting)
+	require.Empty(t, c.reconcileCh)
+
+	// EIPs whose GW cannot be resolved are announced
+	require.NoError(t, gwIndexer.Delete(deleting))
+	require.False(t, c.isEIPGatewayDeleting(eip))
+	require.NoError(t, c.syncEIPRoutes())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+}
+
+func TestEIPLocalPref(t *testing.T) {
+	localPrefAnnotations := func(localPref string) map[string]string {
+		return map[string]string{util.BgpAnnotation: "true", util.BgpLocalPrefAnnotation: localPref}
+	}
+	eips := []*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-default", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"}),
+		newTestEIP("eip-override", "192.168.1.2", "", true, localPrefAnnotations("200")),
+		newTestEIP("eip-zero", "192.168.1.3", "", true, localPrefAnnotations("0")),
+		newTestEIP("eip-invalid", "192.168.1.4", "", true, localPrefAnnotations("high")),
+	}
+
+	// The LOCAL_PREF of an EIP takes precedence over the default one
+	_, attrs := getEIPExpectedPrefixes(eips, routeAttributes{hasLocalPref: true, localPref: 50})
+	for prefix, expected := range map[string]uint32{"192.168.1.1/32": 50, "192.168.1.2/32": 200, "192.168.1.3/32": 0, "192.168.1.4/32": 50} {
+		require.True(t, attrs[prefix].hasLocalPref, prefix)
+		require.Equal(t, expected, attrs[prefix].localPref, prefix)
+	}
+
+	// Without default, only the EIPs with a valid LOCAL_PREF are announced with one
+	_, attrs = getEIPExpectedPrefixes(eips, routeAttributes{})
+	require.False(t, attrs["192.168.1.1/32"].hasLocalPref)
+	require.True(t, attrs["192.168.1.2/32"].hasLocalPref)
+	require.Equal(t, uint32(200), attrs["192.168.1.2/32"].localPref)
+	require.False(t, attrs["192.168.1.4/32"].hasLocalPref)
+}
+
+func TestEIPFamilyMED(t *testing.T) {
+	eips := []*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-default", "192.168.1.1", "2001:db8::1", true, map[string]string{util.BgpAnnotation: "true"}),
+		newTestEIP("eip-both", "192.168.1.2", "2001:db8::2", true, map[string]string{
+			util.BgpAnnotation: "true", util.BgpMEDv4Annotation: "100", util.BgpMEDv6Annotation: "200",
+		}),
+		newTestEIP("eip-v6", "192.168.1.3", "2001:db8::3", true, map[string]string{util.BgpAnnotation: "true", util.BgpMEDv6Annotation: "0"}),
+		newTestEIP("eip-invalid", "192.168.1.4", "2001:db8::4", true, map[string]string{util.BgpAnnotation: "true", util.BgpMEDv4Annotation: "-1"}),
+	}
+
+	// The MED of each family of an EIP takes precedence over the MED of its GW
+	_, attrs := getEIPExpectedPrefixes(eips, routeAttributes{hasMED: true, med: 50})
+	for prefix, expected := range map[string]uint32{
+		"192.168.1.1/32": 50, "2001:db8::1/128": 50,
+		"192.168.1.2/32": 100, "2001:db8::2/128": 200,
+		"192.168.1.3/32": 50, "2001:db8::3/128": 0,
+		"192.168.1.4/32": 50, "2001:db8::4/128": 50,
+	} {
+		require.True(t, attrs[prefix].hasMED, prefix)
+		require.Equal(t, expected, attrs[prefix].med, prefix)
+	}
+
+	// Without MED of the GW, only the families with a valid MED are announced with one
+	_, attrs = getEIPExpectedPrefixes(eips, routeAttributes{})
+	require.False(t, attrs["192.168.1.1/32"].hasMED)
+	require.False(t, attrs["192.168.1.3/32"].hasMED)
+	require.True(t, attrs["2001:db8::3/128"].hasMED)
+	require.Equal(t, uint32(0), attrs["2001:db8::3/128"].med)
+	require.False(t, attrs["192.168.1.4/32"].hasMED)
+}
+
+func TestIsExternalSubnetSelected(t *testing.T) {
+	newSubnetEIP := func(subnet string) *kubeovnv1.IptablesEIP {
+		eip := newTestEIP("eip", "192.168.1.1", "", true, nil)
+		eip.Spec.ExternalSubnet = subnet
+		return eip
+	}
+
+	tests := []struct {
+		name     string
+		filter   []string
+		subnet   string
+		expected bool
+	}{
+		{name: "every subnet is selected without filter", subnet: "external1", expected: true},
+		{name: "subnet in the filter", filter: []string{"external1", "external2"}, subnet: "external2", expected: true},
+		{name: "subnet not in the filter", filter: []string{"external1"}, subnet: "external2", expected: false},
+		{name: "default external subnet in the filter", filter: []string{util.GetExternalNetwork("")}, subnet: "", expected: true},
+		{name: "default external subnet not in the filter", filter: []string{"external1"}, subnet: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{config: &Configuration{ExternalSubnetFilter: tt.filter}}
+			require.Equal(t, tt.expected, c.isExternalSubnetSelected(newSubnetEIP(tt.subnet)))
+		})
+	}
+}
+
+func TestSyncEIPRoutesExternalSubnetFilter(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for i, subnet := range []string{"external1", "external2"} {
+		eip := newTestEIP(subnet, fmt.Sprintf("192.168.%d.1", i+1), "", true, bgpAnnotation)
+		eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+		eip.Spec.ExternalSubnet = subnet
+		require.NoError(t, eipIndexer.Add(eip))
+	}
+
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:            true,
+			ExternalSubnetFilter: []string{"external2"},
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t, "external1", "external2"),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		eipsWithoutAddress: set.New[string](),
+	}
+
+	expected, _, err := c.getEIPDesiredRoutes()
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.168.2.1/32"}, expectedPrefixList(expected))
+}
+
+func TestIsVpcAllowed(t *testing.T) {
+	gwIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, vpc := range map[string]string{"gw1": "vpc1", "gw2": "vpc2", "gw-no-vpc": ""} {
+		require.NoError(t, gwIndexer.Add(&kubeovnv1.VpcNatGateway{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: kubeovnv1.VpcNatGatewaySpec{Vpc: vpc}}))
+	}
+	newGatewayEIP := func(label, natGwDp string) *kubeovnv1.IptablesEIP {
+		eip := newTestEIP("eip", "192.168.1.1", "", true, nil)
+		if label != "" {
+			eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: label}
+		}
+		eip.Spec.NatGwDp = natGwDp
+		return eip
+	}
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		eip       *kubeovnv1.IptablesEIP
+		expected  bool
+	}{
+		{name: "every VPC is allowed without allowlist", eip: newGatewayEIP("", ""), expected: true},
+		{name: "VPC in the allowlist", allowlist: []string{"vpc1", "vpc2"}, eip: newGatewayEIP("gw2", "gw2"), expected: true},
+		{name: "VPC not in the allowlist", allowlist: []string{"vpc1"}, eip: newGatewayEIP("gw2", "gw2"), expected: false},
+		{name: "gateway resolved from the label", allowlist: []string{"vpc1"}, eip: newGatewayEIP("gw1", ""), expected: true},
+		{name: "gateway of the spec preferred to the label", allowlist: []string{"vpc1"}, eip: newGatewayEIP("gw1", "gw2"), expected: false},
+		{name: "EIP without gateway", allowlist: []string{"vpc1"}, eip: newGatewayEIP("", ""), expected: false},
+		{name: "gateway not found", allowlist: []string{"vpc1"}, eip: newGatewayEIP("gw-unknown", "gw-unknown"), expected: false},
+		{name: "gateway without VPC", allowlist: []string{"vpc1"}, eip: newGatewayEIP("gw-no-vpc", "gw-no-vpc"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{
+				config:           &Configuration{VpcAllowlist: tt.allowlist},
+				natgatewayLister: kubeovnlister.NewVpcNatGatewayLister(gwIndexer),
+			}
+			require.Equal(t, tt.expected, c.isVpcAllowed(tt.eip))
+		})
+	}
+}
+
+func TestSyncEIPRoutesVpcAllowlist(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	eip := newTestEIP("eip", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"})
+	eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, eipIndexer.Add(eip))
+	gw := &kubeovnv1.VpcNatGateway{ObjectMeta: metav1.ObjectMeta{Name: "gw1"}, Spec: kubeovnv1.VpcNatGatewaySpec{Vpc: "vpc1"}}
+	gwIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, gwIndexer.Add(gw))
+
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:              true,
+			VpcAllowlist:           []string{"vpc1"},
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(gwIndexer),
+		eipsWithoutAddress: set.New[string](),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+	}
+
+	require.NoError(t, c.syncEIPRoutes())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The EIP is withdrawn once its VPC falls out of the allowlist
+	c.config.VpcAllowlist = []string{"vpc2"}
+	require.NoError(t, c.syncEIPRoutes())
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The EIP is not announced either while the VPC of its gateway cannot be resolved
+	c.config.VpcAllowlist = []string{"vpc1"}
+	require.NoError(t, gwIndexer.Delete(gw))
+	require.NoError(t, c.syncEIPRoutes())
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	require.NoError(t, gwIndexer.Add(gw))
+	require.NoError(t, c.syncEIPRoutes())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+}
+
+func TestDeleteExternalSubnet(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, eip := range []struct{ name, ip, subnet, gw string }{
+		{"eip1", "192.168.1.1", "external1", "gw1"},
+		{"eip2", "192.168.1.2", "external1", "gw1"},
+		{"eip3", "192.168.2.1", "external2", "gw1"},
+		{"eip-default", "192.168.3.1", "", "gw1"},
+		{"eip-other-gw", "192.168.1.3", "external1", "gw2"},
+	} {
+		e := newTestEIP(eip.name, eip.ip, "", true, bgpAnnotation)
+		e.Labels = map[string]string{util.VpcNatGatewayNameLabel: eip.gw}
+		e.Spec.ExternalSubnet = eip.subnet
+		require.NoError(t, eipIndexer.Add(e))
+	}
+	subnetIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	subnets := make(map[string]*kubeovnv1.Subnet)
+	for _, name := range []string{"external1", "external2", util.GetExternalNetwork(""), "internal"} {
+		subnets[name] = &kubeovnv1.Subnet{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		require.NoError(t, subnetIndexer.Add(subnets[name]))
+	}
+
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:              true,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      kubeovnlister.NewSubnetLister(subnetIndexer),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		eipsWithoutAddress: set.New[string](),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+		reconcileCh:        make(chan struct{}, 1),
+	}
+
+	// Only the EIPs of our gateway on the deleted external subnet are affected
+	eipNames := func(subnet string) []string {
+		eips, err := c.getExternalSubnetEIPs(subnet)
+		require.NoError(t, err)
+		var names []string
+		for _, eip := range eips {
+			names = append(names, eip.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+	require.Equal(t, []string{"eip1", "eip2"}, eipNames("external1"))
+	require.Equal(t, []string{"eip3"}, eipNames("external2"))
+	require.Equal(t, []string{"eip-default"}, eipNames(util.GetExternalNetwork("")))
+	require.Empty(t, eipNames("internal"))
+
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.2.1/32", "192.168.3.1/32"}, c.announced.List())
+
+	// Deleting a subnet without EIPs does not trigger a reconciliation
+	require.NoError(t, subnetIndexer.Delete(subnets["internal"]))
+	c.enqueueDeleteSubnet(subnets["internal"])
+	require.Empty(t, c.reconcileCh)
+
+	// Deleting an external subnet withdraws the routes of its EIPs right away, even when the deletion was missed
+	require.NoError(t, subnetIndexer.Delete(subnets["external1"]))
+	c.enqueueDeleteSubnet(cache.DeletedFinalStateUnknown{Key: "external1", Obj: subnets["external1"]})
+	require.Len(t, c.reconcileCh, 1)
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, []string{"192.168.2.1/32", "192.168.3.1/32"}, c.announced.List())
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+	require.False(t, a.isRouteAnnounced("192.168.1.2/32"))
+
+	// The EIPs are announced again once their external subnet is created again
+	require.NoError(t, subnetIndexer.Add(subnets["external1"]))
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.2.1/32", "192.168.3.1/32"}, c.announced.List())
+}
+
+func TestAnnounceOnCondition(t *testing.T) {
+	newConditionEIP := func(status corev1.ConditionStatus, observedGeneration int64) *kubeovnv1.IptablesEIP {
+		eip := newTestEIP("eip", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"})
+		eip.Generation = 2
+		if status != "" {
+			eip.Status.Conditions = []kubeovnv1.Condition{{Type: "AppReady", Status: status, ObservedGeneration: observedGeneration}}
+		}
+		return eip
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		eip       *kubeovnv1.IptablesEIP
+		expected  bool
+	}{
+		{name: "no condition configured", eip: newConditionEIP("", 0), expected: true},
+		{name: "condition true", condition: "AppReady", eip: newConditionEIP(corev1.ConditionTrue, 2), expected: true},
+		{name: "condition false", condition: "AppReady", eip: newConditionEIP(corev1.ConditionFalse, 2), expected: false},
+		{name: "condition missing", condition: "AppReady", eip: newConditionEIP("", 0), expected: false},
+		{name: "condition of an older generation", condition: "AppReady", eip: newConditionEIP(corev1.ConditionTrue, 1), expected: false},
+		{name: "other condition configured", condition: "Validated", eip: newConditionEIP(corev1.ConditionTrue, 2), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{config: &Configuration{AnnounceOnCondition: tt.condition}}
+			require.Equal(t, tt.expected, c.isAnnounceConditionTrue(tt.eip))
+		})
+	}
+}
+
+func TestSyncEIPRoutesAnnounceOnCondition(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	eip := newTestEIP("eip", "192.168.1.1", "", true, map[string]string{util.BgpAnnotation: "true"})
+	eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+	eip.Spec.NatGwDp = "gw1"
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, eipIndexer.Add(eip))
+
+	c := &Controller{
+		config:             &Configuration{NatGwMode: true, AnnounceOnCondition: "AppReady"},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		eipsWithoutAddress: set.New[string](),
+		reconcileCh:        make(chan struct{}, 1),
+	}
+
+	// A ready EIP is not announced until its condition is true
+	expected, _, err := c.getEIPDesiredRoutes()
+	require.NoError(t, err)
+	require.Empty(t, expectedPrefixList(expected))
+
+	appReady := eip.DeepCopy()
+	appReady.Status.Conditions = []kubeovnv1.Condition{{Type: "AppReady", Status: corev1.ConditionTrue}}
+	require.NoError(t, eipIndexer.Update(appReady))
+	c.enqueueUpdateEIP(eip, appReady)
+	require.Len(t, c.reconcileCh, 1)
+
+	expected, _, err = c.getEIPDesiredRoutes()
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.168.1.1/32"}, expectedPrefixList(expected))
+}
@@ -0,0 +1,189 @@
+package speaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	routeEventAnnounce = "announce"
+	routeEventWithdraw = "withdraw"
+
+	// routeEventsBufferSize is the number of events queued for each subscriber, the oldest events of a subscriber
+	// not reading them fast enough are dropped
+	routeEventsBufferSize = 1024
+)
+
+// routeEvent is the announcement or the withdrawal of a route, published to the subscribers of the route events socket
+type routeEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Prefix string    `json:"prefix"`
+}
+
+// marshal serializes the event as a line of newline-delimited JSON
+func (e routeEvent) marshal() ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s event of route %s: %w", e.Action, e.Prefix, err)
+	}
+	return append(b, '\n'), nil
+}
+
+// routeEventSubscriber is a connection to the route events socket and the events queued for it
+type routeEventSubscriber struct {
+	conn   net.Conn
+	events chan routeEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// queue queues an event for the subscriber, dropping its oldest queued event if its queue is full.
+// It returns whether an event was dropped.
+func (s *routeEventSubscriber) queue(event routeEvent) bool {
+	dropped := false
+	for {
+		select {
+		case s.events <- event:
+			return dropped
+		default:
+		}
+		select {
+		case <-s.events:
+			dropped = true
+		default:
+		}
+	}
+}
+
+// close closes the connection of the subscriber and stops writing events to it
+func (s *routeEventSubscriber) close() {
+	s.once.Do(func() {
+		close(s.done)
+		if err := s.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			klog.Errorf("failed to close route events subscriber connection: %v", err)
+		}
+	})
+}
+
+// routeEventPublisher publishes the route events as newline-delimited JSON to the subscribers connected to
+// a UNIX socket. Subscribers only receive the events published after they connected.
+type routeEventPublisher struct {
+	listener net.Listener
+
+	mutex       sync.Mutex
+	subscribers map[*routeEventSubscriber]struct{}
+}
+
+// newRouteEventPublisher listens for subscribers on a UNIX socket, replacing the socket left by a previous run if any
+func newRouteEventPublisher(path string) (*routeEventPublisher, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove route events socket %s: %w", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on route events socket %s: %w", path, err)
+	}
+	return &routeEventPublisher{
+		listener:    listener,
+		subscribers: make(map[*routeEventSubscriber]struct{}),
+	}, nil
+}
+
+// serve accepts subscribers until stopCh is closed, the socket and the subscriber connections are then closed
+func (p *routeEventPublisher) serve(stopCh <-chan struct{}) {
+	go func() {
+		<-stopCh
+		if err := p.listener.Close(); err != nil {
+			klog.Errorf("failed to close route events socket: %v", err)
+		}
+	}()
+
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			klog.Errorf("failed to accept route events subscriber: %v", err)
+			continue
+		}
+		p.subscribe(conn)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for s := range p.subscribers {
+		s.close()
+		delete(p.subscribers, s)
+	}
+}
+
+// subscribe starts writing the published events to a connection
+func (p *routeEventPublisher) subscribe(conn net.Conn) *routeEventSubscriber {
+	s := &routeEventSubscriber{
+		conn:   conn,
+		events: make(chan routeEvent, routeEventsBufferSize),
+		done:   make(chan struct{}),
+	}
+	p.mutex.Lock()
+	p.subscribers[s] = struct{}{}
+	p.mutex.Unlock()
+
+	go p.write(s)
+	return s
+}
+
+// write writes the events queued for a subscriber to its connection until writing fails or the subscriber
+// is closed, the subscriber is then unsubscribed
+func (p *routeEventPublisher) write(s *routeEventSubscriber) {
+	defer func() {
+		p.mutex.Lock()
+		delete(p.subscribers, s)
+		p.mutex.Unlock()
+		s.close()
+	}()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.events:
+			b, err := event.marshal()
+			if err != nil {
+				klog.Error(err)
+				continue
+			}
+			if _, err = s.conn.Write(b); err != nil {
+				klog.V(3).Infof("route events subscriber disconnected: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// publish queues an event for every route for each subscriber, the publisher may be nil if the route events
+// socket is not configured
+func (p *routeEventPublisher) publish(now time.Time, action string, routes []string) {
+	if p == nil || len(routes) == 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for s := range p.subscribers {
+		for _, route := range routes {
+			if s.queue(routeEvent{Time: now, Action: action, Prefix: route}) {
+				metricRouteEventsDropped.Inc()
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package speaker
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteEventMarshal(t *testing.T) {
+	event := routeEvent{
+		Time:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Action: routeEventAnnounce,
+		Prefix: "192.168.1.1/32",
+	}
+	b, err := event.marshal()
+	require.NoError(t, err)
+	require.Equal(t, `{"time":"2024-05-01T10:00:00Z","action":"announce","prefix":"192.168.1.1/32"}`+"\n", string(b))
+}
+
+func TestRouteEventSubscriberQueue(t *testing.T) {
+	s := &routeEventSubscriber{events: make(chan routeEvent, 2)}
+	require.False(t, s.queue(routeEvent{Prefix: "192.168.1.1/32"}))
+	require.False(t, s.queue(routeEvent{Prefix: "192.168.1.2/32"}))
+
+	// The oldest event is dropped once the queue is full
+	require.True(t, s.queue(routeEvent{Prefix: "192.168.1.3/32"}))
+	require.Equal(t, "192.168.1.2/32", (<-s.events).Prefix)
+	require.Equal(t, "192.168.1.3/32", (<-s.events).Prefix)
+}
+
+func TestRouteEventPublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	p, err := newRouteEventPublisher(path)
+	require.NoError(t, err)
+	stopCh := make(chan struct{})
+	served := make(chan struct{})
+	go func() {
+		p.serve(stopCh)
+		close(served)
+	}()
+
+	subscribersCount := func() int {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		return len(p.subscribers)
+	}
+	connect := func() (net.Conn, *bufio.Scanner) {
+		conn, err := net.Dial("unix", path)
+		require.NoError(t, err)
+		return conn, bufio.NewScanner(conn)
+	}
+	readEvent := func(scanner *bufio.Scanner) routeEvent {
+		require.True(t, scanner.Scan())
+		var event routeEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		return event
+	}
+
+	// Events published without subscribers are not kept
+	p.publish(time.Now(), routeEventAnnounce, []string{"10.0.0.1/32"})
+
+	conn, scanner := connect()
+	require.Eventually(t, func() bool { return subscribersCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	c := &Controller{config: &Configuration{}, announced: newAnnouncedStore(), events: p}
+	require.NoError(t, c.addRoutes([]string{"192.168.1.1/32", "192.168.1.2/32"}, nil).err())
+	require.NoError(t, c.delRoutes([]string{"192.168.1.1/32"}).err())
+	for _, expected := range []routeEvent{
+		{Action: routeEventAnnounce, Prefix: "192.168.1.1/32"},
+		{Action: routeEventAnnounce, Prefix: "192.168.1.2/32"},
+		{Action: routeEventWithdraw, Prefix: "192.168.1.1/32"},
+	} {
+		event := readEvent(scanner)
+		require.False(t, event.Time.IsZero())
+		event.Time = time.Time{}
+		require.Equal(t, expected, event)
+	}
+
+	// A disconnected subscriber is unsubscribed, and receives the new events once reconnected
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool {
+		p.publish(time.Now(), routeEventWithdraw, []string{"192.168.1.2/32"})
+		return subscribersCount() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	conn, scanner = connect()
+	defer conn.Close()
+	require.Eventually(t, func() bool { return subscribersCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+	p.publish(time.Now(), routeEventAnnounce, []string{"192.168.1.3/32"})
+	require.Equal(t, "192.168.1.3/32", readEvent(scanner).Prefix)
+
+	// The subscribers are closed once stopped
+	close(stopCh)
+	<-served
+	require.Zero(t, subscribersCount())
+	require.False(t, scanner.Scan())
+}
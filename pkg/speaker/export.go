@@ -0,0 +1,179 @@
+package speaker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/set"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+const (
+	// ExportFormatBIRD renders the routes as BIRD 2 static protocols
+	ExportFormatBIRD = "bird"
+	// ExportFormatFRR renders the routes as FRR bgpd network statements
+	ExportFormatFRR = "frr"
+)
+
+// ParseExportFlags parses the flags of the export command, which only needs the flags selecting the routes the
+// speaker would announce. The BGP server is not started.
+func ParseExportFlags(args []string) (*Configuration, string, error) {
+	config, format, err := parseExportFlags(args)
+	if err != nil {
+		return nil, "", err
+	}
+	if err = config.initKubeClient(); err != nil {
+		return nil, "", fmt.Errorf("failed to init kube client, %w", err)
+	}
+	return config, format, nil
+}
+
+// parseExportFlags parses and validates the flags of the export command. The flags selecting the routes are the
+// same as the ones of the speaker.
+func parseExportFlags(args []string) (*Configuration, string, error) {
+	flags := pflag.NewFlagSet("export", pflag.ContinueOnError)
+	var (
+		argFormat                = flags.String("format", ExportFormatFRR, "Format the routes are exported in, \"bird\" or \"frr\"")
+		argClusterAs             = flags.Uint32("cluster-as", 0, "The AS number of the local BGP speaker, required by the frr format")
+		argAnnounceClusterIP     = flags.Bool("announce-cluster-ip", false, "The Cluster IP of the service to announce to the BGP peers.")
+		argNatGwMode             = flags.Bool("nat-gw-mode", false, "Export the EIPs announced from inside a NAT gateway, Pod IP/Service/Subnet announcements will be disabled")
+		argGatewayName           = flags.String("gateway-name", os.Getenv(util.EnvGatewayName), "Name of the VPC NAT gateway whose EIPs are exported with --nat-gw-mode, the GATEWAY_NAME env by default")
+		argNodeName              = flags.String("node-name", os.Getenv(util.EnvNodeName), "Name of the node the routes are exported for.")
+		argKubeConfigFile        = flags.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information. If not set use the inCluster token.")
+		argExternalSubnetFilter  = flags.StringSlice("external-subnet-filter", nil, "Comma separated names of the external subnets whose EIPs are exported. The EIPs of every external subnet are exported if empty")
+		argVpcAllowlist          = flags.StringSlice("vpc-allowlist", nil, "Comma separated names of the VPCs whose EIPs are exported, the VPC of an EIP being the VPC of its NAT gateway. The EIPs of every VPC are exported if empty")
+		argAnnounceOnCondition   = flags.String("announce-on-condition", "", "Type of the status condition of the EIPs which must also be True for them to be exported. EIPs are exported once ready if empty")
+		argPointToPointSubnets   = flags.StringSlice("point-to-point-subnets", nil, "Comma separated external subnets whose EIPs egress through /31 or /127 point-to-point links, the routes of their EIPs being exported with the prefix of the link rather than a /32 or /128")
+		argStaticAnnounceCIDRs   = flags.IPNetSlice("static-announce-cidrs", nil, "Comma separated CIDRs always exported in addition to the routes of the pods, services, subnets or EIPs")
+		argAnnounceNodeAddress   = flags.Bool("announce-node-address", false, "Export the addresses of the node as host routes. The global addresses of the loopback interface are exported unless --node-announce-addresses is set")
+		argNodeAnnounceAddresses = flags.IPSlice("node-announce-addresses", nil, "Comma separated addresses of the node exported with --announce-node-address instead of the addresses of the loopback interface")
+		argSRv6Locator           = flags.String("announce-srv6-locator", "", "IPv6 SRv6 locator prefix of the node always exported along with the routes of the EIPs. Not exported if empty")
+	)
+	if err := flags.Parse(args); err != nil {
+		return nil, "", err
+	}
+
+	if *argFormat != ExportFormatBIRD && *argFormat != ExportFormatFRR {
+		return nil, "", fmt.Errorf("unknown export format %q, must be %q or %q", *argFormat, ExportFormatBIRD, ExportFormatFRR)
+	}
+	if *argFormat == ExportFormatFRR && *argClusterAs == 0 {
+		return nil, "", errors.New("--cluster-as must be specified with the frr format")
+	}
+	if !*argNatGwMode && *argNodeName == "" {
+		return nil, "", errors.New("--node-name must be specified (usually via NODE_NAME env from downward API)")
+	}
+	if *argNatGwMode {
+		if *argGatewayName == "" {
+			return nil, "", fmt.Errorf("--gateway-name must be specified with --nat-gw-mode (usually via %s env)", util.EnvGatewayName)
+		}
+		// The gateway of the speaker is always looked up in the environment
+		if err := os.Setenv(util.EnvGatewayName, *argGatewayName); err != nil {
+			return nil, "", fmt.Errorf("failed to set gateway name: %w", err)
+		}
+	}
+
+	config := &Configuration{
+		ClusterAs:            *argClusterAs,
+		AnnounceClusterIP:    *argAnnounceClusterIP,
+		NatGwMode:            *argNatGwMode,
+		NodeName:             *argNodeName,
+		KubeConfigFile:       *argKubeConfigFile,
+		ExternalSubnetFilter: *argExternalSubnetFilter,
+		VpcAllowlist:         *argVpcAllowlist,
+		AnnounceOnCondition:  *argAnnounceOnCondition,
+		PointToPointSubnets:  set.New(*argPointToPointSubnets...),
+		StaticAnnounceCIDRs:  ipNetsToPrefixes(*argStaticAnnounceCIDRs),
+	}
+	var err error
+	if err = validateStaticAnnounceCIDRs(config.StaticAnnounceCIDRs); err != nil {
+		return nil, "", err
+	}
+	if config.SRv6Locator, err = parseSRv6Locator(*argSRv6Locator); err != nil {
+		return nil, "", err
+	}
+	if *argAnnounceNodeAddress {
+		if config.NodeAnnounceAddresses, err = getNodeAnnounceAddresses(*argNodeAnnounceAddresses); err != nil {
+			return nil, "", err
+		}
+	} else if len(*argNodeAnnounceAddresses) != 0 {
+		return nil, "", errors.New("--node-announce-addresses requires --announce-node-address")
+	}
+	return config, *argFormat, nil
+}
+
+// ExportRoutes writes the routes the speaker would announce as a static configuration of another BGP daemon,
+// so that the announcements can be migrated off the speaker
+func ExportRoutes(config *Configuration, format string, w io.Writer, stopCh <-chan struct{}) error {
+	// The routes are only computed, no event is recorded
+	c := newController(config, &record.FakeRecorder{})
+	c.informerFactory.Start(stopCh)
+	c.podInformerFactory.Start(stopCh)
+	c.kubeovnInformerFactory.Start(stopCh)
+	if err := c.waitForCacheSync(stopCh); err != nil {
+		return fmt.Errorf("failed to wait for caches to sync: %w", err)
+	}
+
+	prefixes, _, err := c.getDesiredRoutes()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, renderRoutes(format, prefixes, config.ClusterAs))
+	return err
+}
+
+// renderRoutes renders prefixes in an export format
+func renderRoutes(format string, prefixes prefixMap, clusterAs uint32) string {
+	if format == ExportFormatBIRD {
+		return renderBIRD(prefixes)
+	}
+	return renderFRR(prefixes, clusterAs)
+}
+
+// renderBIRD renders prefixes as a BIRD 2 static protocol per address family. Routes are blackholes, they only
+// originate the prefixes and must not be exported to the kernel.
+func renderBIRD(prefixes prefixMap) string {
+	var b strings.Builder
+	for _, family := range []struct {
+		afi     api.Family_Afi
+		channel string
+	}{{api.Family_AFI_IP, "ipv4"}, {api.Family_AFI_IP6, "ipv6"}} {
+		if prefixes[family.afi].Len() == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "protocol static kube_ovn_speaker_%s {\n\t%s;\n", family.channel, family.channel)
+		for _, prefix := range prefixes[family.afi].SortedList() {
+			fmt.Fprintf(&b, "\troute %s blackhole;\n", prefix)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// renderFRR renders prefixes as network statements of the FRR BGP router of the cluster AS. The import check
+// is disabled so that the prefixes are advertised without being present in the RIB of the router.
+func renderFRR(prefixes prefixMap, clusterAs uint32) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "router bgp %d\n no bgp network import-check\n", clusterAs)
+	for _, family := range []struct {
+		afi  api.Family_Afi
+		name string
+	}{{api.Family_AFI_IP, "ipv4"}, {api.Family_AFI_IP6, "ipv6"}} {
+		if prefixes[family.afi].Len() == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, " address-family %s unicast\n", family.name)
+		for _, prefix := range prefixes[family.afi].SortedList() {
+			fmt.Fprintf(&b, "  network %s\n", prefix)
+		}
+		b.WriteString(" exit-address-family\n")
+	}
+	b.WriteString("exit\n")
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package speaker
+
+import (
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestParseExportFlags(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "")
+	t.Setenv(util.EnvNodeName, "")
+
+	config, format, err := parseExportFlags([]string{
+		"--format=bird",
+		"--nat-gw-mode",
+		"--gateway-name=gw1",
+		"--external-subnet-filter=external1,external2",
+		"--vpc-allowlist=vpc1",
+		"--announce-on-condition=AppReady",
+		"--point-to-point-subnets=external2",
+		"--static-announce-cidrs=10.0.0.0/24",
+		"--announce-node-address",
+		"--node-announce-addresses=172.18.0.2",
+		"--announce-srv6-locator=fd00:0:1::/48",
+	})
+	require.NoError(t, err)
+	require.Equal(t, ExportFormatBIRD, format)
+	require.True(t, config.NatGwMode)
+	require.Equal(t, "gw1", getGatewayName())
+	require.Equal(t, []string{"external1", "external2"}, config.ExternalSubnetFilter)
+	require.Equal(t, []string{"vpc1"}, config.VpcAllowlist)
+	require.Equal(t, "AppReady", config.AnnounceOnCondition)
+	require.Equal(t, set.New("external2"), config.PointToPointSubnets)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, config.StaticAnnounceCIDRs)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("172.18.0.2")}, config.NodeAnnounceAddresses)
+	require.Equal(t, netip.MustParsePrefix("fd00:0:1::/48"), config.SRv6Locator)
+
+	for _, args := range [][]string{
+		{"--format=bird", "--nat-gw-mode"},
+		{"--format=bird", "--node-name=node1", "--node-announce-addresses=172.18.0.2"},
+		{"--format=bird", "--node-name=node1", "--static-announce-cidrs=0.0.0.0/0"},
+		{"--format=bird", "--node-name=node1", "--announce-srv6-locator=10.0.0.0/24"},
+		{"--format=json", "--node-name=node1"},
+		{"--node-name=node1"},
+	} {
+		require.NoError(t, os.Unsetenv(util.EnvGatewayName))
+		_, _, err = parseExportFlags(args)
+		require.Error(t, err, args)
+	}
+}
+
+func TestRenderRoutes(t *testing.T) {
+	prefixes := prefixMap{
+		api.Family_AFI_IP:  set.New("192.168.1.2/32", "10.16.0.0/16"),
+		api.Family_AFI_IP6: set.New("2001:db8::1/128"),
+	}
+
+	require.Equal(t, `protocol static kube_ovn_speaker_ipv4 {
+	ipv4;
+	route 10.16.0.0/16 blackhole;
+	route 192.168.1.2/32 blackhole;
+}
+protocol static kube_ovn_speaker_ipv6 {
+	ipv6;
+	route 2001:db8::1/128 blackhole;
+}
+`, renderRoutes(ExportFormatBIRD, prefixes, 65000))
+
+	require.Equal(t, `router bgp 65000
+ no bgp network import-check
+ address-family ipv4 unicast
+  network 10.16.0.0/16
+  network 192.168.1.2/32
+ exit-address-family
+ address-family ipv6 unicast
+  network 2001:db8::1/128
+ exit-address-family
+exit
+`, renderRoutes(ExportFormatFRR, prefixes, 65000))
+}
+
+func TestRenderRoutesSingleFamily(t *testing.T) {
+	prefixes := prefixMap{api.Family_AFI_IP6: set.New("2001:db8::1/128")}
+
+	require.Equal(t, `protocol static kube_ovn_speaker_ipv6 {
+	ipv6;
+	route 2001:db8::1/128 blackhole;
+}
+`, renderRoutes(ExportFormatBIRD, prefixes, 65000))
+
+	require.Equal(t, `router bgp 65000
+ no bgp network import-check
+ address-family ipv6 unicast
+  network 2001:db8::1/128
+ exit-address-family
+exit
+`, renderRoutes(ExportFormatFRR, prefixes, 65000))
+
+	require.Empty(t, renderRoutes(ExportFormatBIRD, prefixMap{}, 65000))
+}
@@ -0,0 +1,24 @@
+package speaker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// announceGateReady is the content of the announce gate file allowing announcements
+const announceGateReady = "ready"
+
+// isAnnounceGateOpen returns whether the announce gate file allows announcements, that is
+// whether it exists and contains "ready", surrounding whitespaces being ignored
+func isAnnounceGateOpen(path string) (bool, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read announce gate file %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(content)) == announceGateReady, nil
+}
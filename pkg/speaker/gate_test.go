@@ -0,0 +1,58 @@
+package speaker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func TestIsAnnounceGateOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		open    bool
+	}{
+		{name: "missing file", content: nil, open: false},
+		{name: "ready", content: ptr.To("ready"), open: true},
+		{name: "ready with trailing newline", content: ptr.To("ready\n"), open: true},
+		{name: "empty file", content: ptr.To(""), open: false},
+		{name: "not ready", content: ptr.To("not ready"), open: false},
+		{name: "case sensitive", content: ptr.To("READY"), open: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "gate")
+			if tt.content != nil {
+				require.NoError(t, os.WriteFile(path, []byte(*tt.content), 0o600))
+			}
+			open, err := isAnnounceGateOpen(path)
+			require.NoError(t, err)
+			require.Equal(t, tt.open, open)
+		})
+	}
+
+	// the gate is closed when the file cannot be read
+	open, err := isAnnounceGateOpen(t.TempDir())
+	require.Error(t, err)
+	require.False(t, open)
+}
+
+func TestAnnouncementSuppressedByGateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gate")
+	c := &Controller{config: &Configuration{}}
+	require.Empty(t, c.announcementSuppressedReason(time.Now()))
+
+	c.config.AnnounceGateFile = path
+	require.NotEmpty(t, c.announcementSuppressedReason(time.Now()))
+
+	require.NoError(t, os.WriteFile(path, []byte("ready"), 0o600))
+	require.Empty(t, c.announcementSuppressedReason(time.Now()))
+
+	require.NoError(t, os.Remove(path))
+	require.NotEmpty(t, c.announcementSuppressedReason(time.Now()))
+}
@@ -0,0 +1,44 @@
+package speaker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"k8s.io/klog/v2"
+)
+
+// initialAnnounceHoldReason returns why the routes are not announced yet after the startup of the speaker with
+// --initial-announce-delay, so that they are not announced while the BGP sessions are still being established.
+// The hold ends once the delay elapsed or the sessions with every neighbor are established, whichever comes first,
+// and does not apply anymore afterwards.
+func (c *Controller) initialAnnounceHoldReason(now time.Time) string {
+	if c.config.InitialAnnounceDelay == 0 || c.initialAnnounceReleased.Load() {
+		return ""
+	}
+
+	switch {
+	case now.Sub(c.startTime) >= c.config.InitialAnnounceDelay:
+		klog.Infof("initial announce delay of %s elapsed, announcing routes", c.config.InitialAnnounceDelay)
+	case c.allSessionsEstablished():
+		klog.Info("BGP sessions with every neighbor are established, announcing routes")
+	default:
+		return fmt.Sprintf("holding the initial announcements for up to %s until the BGP sessions are established", c.config.InitialAnnounceDelay)
+	}
+	c.initialAnnounceReleased.Store(true)
+	return ""
+}
+
+// allSessionsEstablished returns whether the BGP sessions with every neighbor are established, false without neighbors
+func (c *Controller) allSessionsEstablished() bool {
+	neighbors := c.config.allNeighborAddresses()
+	if len(neighbors) == 0 {
+		return false
+	}
+	for _, neighbor := range neighbors {
+		if c.sessions.State(neighbor.String()) != bgp.BGP_FSM_ESTABLISHED {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestInitialAnnounceHold(t *testing.T) {
+	ipv4Neighbor, ipv6Neighbor := net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")
+	newController := func() (*Controller, *fakeAnnouncer) {
+		a := newFakeAnnouncer()
+		return &Controller{
+			config: &Configuration{
+				NeighborAddresses:     []net.IP{ipv4Neighbor},
+				NeighborIPv6Addresses: []net.IP{ipv6Neighbor},
+				NeighborLocalAddresses: map[string]net.IP{
+					ipv4Neighbor.String(): net.ParseIP("10.32.32.2"),
+					ipv6Neighbor.String(): net.ParseIP("fd00::2"),
+				},
+				InitialAnnounceDelay: time.Minute,
+			},
+			announced:   newAnnouncedStore(),
+			announcer:   a,
+			sessions:    newSessionTracker(),
+			reconcileCh: make(chan struct{}, 1),
+			startTime:   time.Now(),
+		}, a
+	}
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32")}
+
+	// The announcements are held until the sessions with every neighbor are established
+	c, a := newController()
+	require.NotEmpty(t, c.initialAnnounceHoldReason(time.Now()))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.Empty(t, a.announced)
+
+	c.handleSessionState(ipv4Neighbor.String(), bgp.BGP_FSM_ESTABLISHED)
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.Empty(t, a.announced)
+
+	c.handleSessionState(ipv6Neighbor.String(), bgp.BGP_FSM_ESTABLISHED)
+	require.Len(t, c.reconcileCh, 1)
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// Once ended, the hold does not apply again when a session goes down
+	c.handleSessionState(ipv6Neighbor.String(), bgp.BGP_FSM_IDLE)
+	require.Empty(t, c.initialAnnounceHoldReason(time.Now()))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The announcements are held at most for the delay
+	c, a = newController()
+	require.NotEmpty(t, c.initialAnnounceHoldReason(c.startTime.Add(time.Minute-time.Second)))
+	require.Empty(t, c.initialAnnounceHoldReason(c.startTime.Add(time.Minute)))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The announcements are not held without delay
+	c, _ = newController()
+	c.config.InitialAnnounceDelay = 0
+	require.Empty(t, c.initialAnnounceHoldReason(time.Now()))
+}
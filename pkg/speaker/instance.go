@@ -0,0 +1,58 @@
+package speaker
+
+import (
+	"k8s.io/klog/v2"
+
+	v1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// bgpInstance selects the neighbors a route is advertised to, routes are leaked between BGP instances
+// by advertising them to the neighbors of both instances
+type bgpInstance string
+
+const (
+	// bgpInstancePrimary advertises a route to the primary neighbors, as routes without instance
+	bgpInstancePrimary bgpInstance = "primary"
+	// bgpInstanceSecondary advertises a route to the secondary neighbors only
+	bgpInstanceSecondary bgpInstance = "secondary"
+	// bgpInstanceAll advertises a route to the neighbors of both instances
+	bgpInstanceAll bgpInstance = "all"
+)
+
+// includes returns whether a route of the instance is advertised to a neighbor, depending on whether it is a secondary one
+func (i bgpInstance) includes(secondaryNeighbor bool) bool {
+	switch i {
+	case bgpInstanceAll:
+		return true
+	case bgpInstanceSecondary:
+		return secondaryNeighbor
+	default:
+		return !secondaryNeighbor
+	}
+}
+
+// getEIPInstance returns the BGP instance selected with the BGP instance annotation of an EIP, if any
+func getEIPInstance(eip *v1.IptablesEIP) bgpInstance {
+	switch instance := bgpInstance(eip.Annotations[util.BgpInstanceAnnotation]); instance {
+	case "", bgpInstancePrimary, bgpInstanceSecondary, bgpInstanceAll:
+		return instance
+	default:
+		klog.Warningf("invalid annotation %s=%s on EIP %s, announcing it to the primary instance", util.BgpInstanceAnnotation, instance, eip.Name)
+		return ""
+	}
+}
+
+// addInstanceFilters prevents routes from being advertised to the neighbors which are not part of their BGP instance
+func (c *Controller) addInstanceFilters(expectedPrefixes prefixMap, attrs prefixAttributes, filters neighborFilters) {
+	for _, prefixes := range expectedPrefixes {
+		for prefix := range prefixes {
+			instance := attrs[prefix].instance
+			for _, neighbor := range c.getRouteNeighbors(prefix) {
+				if !instance.includes(c.config.isSecondaryNeighbor(neighbor)) {
+					filters.add(neighbor.String(), prefix)
+				}
+			}
+		}
+	}
+}
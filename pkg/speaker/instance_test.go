@@ -0,0 +1,94 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestBgpInstanceIncludes(t *testing.T) {
+	tests := []struct {
+		instance  bgpInstance
+		primary   bool
+		secondary bool
+	}{
+		{instance: "", primary: true, secondary: false},
+		{instance: bgpInstancePrimary, primary: true, secondary: false},
+		{instance: bgpInstanceSecondary, primary: false, secondary: true},
+		{instance: bgpInstanceAll, primary: true, secondary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.instance), func(t *testing.T) {
+			require.Equal(t, tt.primary, tt.instance.includes(false))
+			require.Equal(t, tt.secondary, tt.instance.includes(true))
+		})
+	}
+}
+
+func TestGetEIPInstance(t *testing.T) {
+	tests := []struct {
+		annotation string
+		expected   bgpInstance
+	}{
+		{annotation: "", expected: ""},
+		{annotation: "primary", expected: bgpInstancePrimary},
+		{annotation: "secondary", expected: bgpInstanceSecondary},
+		{annotation: "all", expected: bgpInstanceAll},
+		{annotation: "tertiary", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.annotation, func(t *testing.T) {
+			eip := newTestEIP("eip", "192.168.1.1", "", true, map[string]string{util.BgpInstanceAnnotation: tt.annotation})
+			require.Equal(t, tt.expected, getEIPInstance(eip))
+		})
+	}
+}
+
+func TestInstanceExportFilters(t *testing.T) {
+	primary, secondary := net.ParseIP("10.32.32.1"), net.ParseIP("10.33.33.1")
+	primaryIPv6, secondaryIPv6 := net.ParseIP("fd00::1"), net.ParseIP("fd01::1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:              []net.IP{primary},
+			NeighborIPv6Addresses:          []net.IP{primaryIPv6},
+			SecondaryNeighborAddresses:     []net.IP{secondary},
+			SecondaryNeighborIPv6Addresses: []net.IP{secondaryIPv6},
+		},
+		announced:         newAnnouncedStore(),
+		prefixesOverLimit: make(map[string]int),
+		recorder:          record.NewFakeRecorder(10),
+	}
+	require.Equal(t, []net.IP{primary, secondary}, c.getRouteNeighbors("192.168.1.1/32"))
+	require.Equal(t, []net.IP{primaryIPv6, secondaryIPv6}, c.getRouteNeighbors("2001:db8::1/128"))
+
+	bgpAnnotations := func(instance string) map[string]string {
+		return map[string]string{util.BgpAnnotation: "true", util.BgpInstanceAnnotation: instance}
+	}
+	expected, attrs := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-default", "192.168.1.1", "2001:db8::1", true, map[string]string{util.BgpAnnotation: "true"}),
+		newTestEIP("eip-primary", "192.168.1.2", "", true, bgpAnnotations("primary")),
+		newTestEIP("eip-secondary", "192.168.1.3", "2001:db8::3", true, bgpAnnotations("secondary")),
+		newTestEIP("eip-all", "192.168.1.4", "", true, bgpAnnotations("all")),
+	}, routeAttributes{})
+
+	require.Equal(t, neighborFilters{
+		primary.String():       set.New("192.168.1.3/32"),
+		primaryIPv6.String():   set.New("2001:db8::3/128"),
+		secondary.String():     set.New("192.168.1.1/32", "192.168.1.2/32"),
+		secondaryIPv6.String(): set.New("2001:db8::1/128"),
+	}, c.getExportFilters(expected, attrs))
+
+	// Prefixes of another instance do not count in the prefix limit of a neighbor
+	c.config.NeighborMaxPrefixes = map[string]int{secondary.String(): 2}
+	require.Equal(t, set.New("192.168.1.1/32", "192.168.1.2/32"), c.getExportFilters(expected, attrs)[secondary.String()])
+	c.config.NeighborMaxPrefixes = map[string]int{secondary.String(): 1}
+	require.Equal(t, set.New("192.168.1.1/32", "192.168.1.2/32", "192.168.1.4/32"), c.getExportFilters(expected, attrs)[secondary.String()])
+}
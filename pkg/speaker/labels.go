@@ -0,0 +1,47 @@
+package speaker
+
+import (
+	"net/netip"
+
+	"github.com/osrg/gobgp/v4/api"
+)
+
+// implicitNullLabel is the label advertised with the prefixes announced as labeled unicast (RFC 3032). The routes
+// being originated by the speaker, the upstream routers pop it and forward the traffic to the next hop as IP, so
+// that no MPLS route is needed on the speaker side.
+const implicitNullLabel = 3
+
+// getPrefixNLRI returns the NLRI announcing a prefix, along with its SAFI. With --enable-bgp-lu, prefixes are
+// announced as labeled unicast with the implicit null label, and as unicast otherwise.
+func (c *Controller) getPrefixNLRI(prefix netip.Prefix) (*api.NLRI, api.Family_Safi) {
+	prefixLen := uint32(prefix.Bits()) // #nosec G115
+	if !c.config.EnableBgpLU {
+		return &api.NLRI{Nlri: &api.NLRI_Prefix{Prefix: &api.IPAddressPrefix{
+			Prefix:    prefix.Addr().String(),
+			PrefixLen: prefixLen,
+		}}}, api.Family_SAFI_UNICAST
+	}
+	return &api.NLRI{Nlri: &api.NLRI_LabeledPrefix{LabeledPrefix: &api.LabeledIPAddressPrefix{
+		Labels:    []uint32{implicitNullLabel},
+		Prefix:    prefix.Addr().String(),
+		PrefixLen: prefixLen,
+	}}}, api.Family_SAFI_MPLS_LABEL
+}
+
+// addPeerLabeledUnicast enables the labeled unicast SAFI of the unicast address families of a peer, its native
+// address family when none is configured. The unicast SAFIs are kept.
+func addPeerLabeledUnicast(peer *api.Peer, native api.Family_Afi) {
+	var afis []api.Family_Afi
+	for _, afiSafi := range peer.AfiSafis {
+		if family := afiSafi.GetConfig().GetFamily(); family.GetSafi() == api.Family_SAFI_UNICAST {
+			afis = append(afis, family.GetAfi())
+		}
+	}
+	if len(afis) == 0 {
+		addPeerAfiSafi(peer, native)
+		afis = append(afis, native)
+	}
+	for _, afi := range afis {
+		addPeerFamily(peer, afi, api.Family_SAFI_MPLS_LABEL)
+	}
+}
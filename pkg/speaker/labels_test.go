@@ -0,0 +1,114 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestAddPeerLabeledUnicast(t *testing.T) {
+	families := func(peer *api.Peer) []string {
+		var families []string
+		for _, afiSafi := range peer.AfiSafis {
+			family := afiSafi.Config.Family
+			families = append(families, bgp.NewFamily(uint16(family.Afi), uint8(family.Safi)).String())
+		}
+		return families
+	}
+
+	peer := &api.Peer{}
+	addPeerLabeledUnicast(peer, api.Family_AFI_IP)
+	require.Equal(t, []string{"ipv4-unicast", "ipv4-labelled-unicast"}, families(peer))
+
+	peer = &api.Peer{}
+	addPeerAfiSafi(peer, api.Family_AFI_IP6)
+	addPeerAfiSafi(peer, api.Family_AFI_IP)
+	addPeerLabeledUnicast(peer, api.Family_AFI_IP6)
+	require.Equal(t, []string{"ipv6-unicast", "ipv4-unicast", "ipv6-labelled-unicast", "ipv4-labelled-unicast"}, families(peer))
+}
+
+func TestAnnounceLabeledUnicast(t *testing.T) {
+	s := newTestBgpServer(t)
+	ipv4Neighbor, ipv6Neighbor := net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")
+	ipv4Local, ipv6Local := net.ParseIP("10.32.32.2"), net.ParseIP("fd00::2")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:             s,
+			NeighborAddresses:     []net.IP{ipv4Neighbor},
+			NeighborIPv6Addresses: []net.IP{ipv6Neighbor},
+			NeighborLocalAddresses: map[string]net.IP{
+				ipv4Neighbor.String(): ipv4Local,
+				ipv6Neighbor.String(): ipv6Local,
+			},
+			EnableBgpLU: true,
+		},
+		announced: newAnnouncedStore(),
+	}
+	expected := prefixMap{
+		api.Family_AFI_IP:  set.New("192.168.1.1/32", "192.168.1.2/32"),
+		api.Family_AFI_IP6: set.New("2001:db8::1/128"),
+	}
+
+	// The routes are announced as labeled unicast only
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_MPLS))
+	require.Equal(t, []string{"2001:db8::1/128"}, listTestBgpServerPrefixes(t, s, bgp.RF_IPv6_MPLS))
+	require.Empty(t, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+
+	announced, err := c.getAnnouncer().IsRouteAnnounced("2001:db8::1/128")
+	require.NoError(t, err)
+	require.True(t, announced)
+
+	// The routes are withdrawn as labeled unicast
+	delete(expected, api.Family_AFI_IP6)
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.Empty(t, listTestBgpServerPrefixes(t, s, bgp.RF_IPv6_MPLS))
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_MPLS))
+}
+
+func TestLabeledUnicastForwarding(t *testing.T) {
+	s := newTestBgpServer(t)
+	ipv4Neighbor, ipv6Neighbor := net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")
+	ipv4Local, ipv6Local := net.ParseIP("10.32.32.2"), net.ParseIP("fd00::2")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:             s,
+			NeighborAddresses:     []net.IP{ipv4Neighbor},
+			NeighborIPv6Addresses: []net.IP{ipv6Neighbor},
+			NeighborLocalAddresses: map[string]net.IP{
+				ipv4Neighbor.String(): ipv4Local,
+				ipv6Neighbor.String(): ipv6Local,
+			},
+			EnableBgpLU: true,
+		},
+		announced: newAnnouncedStore(),
+	}
+	require.Empty(t, c.reconcileRoutes(prefixMap{
+		api.Family_AFI_IP:  set.New("192.168.1.1/32"),
+		api.Family_AFI_IP6: set.New("2001:db8::1/128"),
+	}, nil).failed())
+
+	// The speaker programs no MPLS route: every labeled path in the RIB carries the implicit null label, so that
+	// the upstream router pops it and forwards the traffic as IP to the speaker, the next hop of the path
+	for family, nextHop := range map[bgp.Family]net.IP{bgp.RF_IPv4_MPLS: ipv4Local, bgp.RF_IPv6_MPLS: ipv6Local} {
+		var paths int
+		require.NoError(t, s.ListPath(apiutil.ListPathRequest{
+			TableType: api.TableType_TABLE_TYPE_GLOBAL,
+			Family:    family,
+		}, func(nlri bgp.NLRI, nlriPaths []*apiutil.Path) {
+			labeled, ok := nlri.(*bgp.LabeledIPAddrPrefix)
+			require.True(t, ok, nlri.String())
+			require.Equal(t, []uint32{implicitNullLabel}, labeled.Labels.Labels, nlri.String())
+			for _, path := range nlriPaths {
+				require.True(t, nextHop.Equal(getNextHopFromPathAttributes(path.Attrs)), nlri.String())
+				paths++
+			}
+		}))
+		require.Equal(t, 1, paths, family.String())
+	}
+}
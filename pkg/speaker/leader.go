@@ -0,0 +1,94 @@
+package speaker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+const (
+	leaderLeaseDuration = 30 * time.Second
+	leaderRenewDeadline = 20 * time.Second
+	leaderRetryPeriod   = 6 * time.Second
+)
+
+// getLeaderElectionIdentity returns the identity of the speaker holding the Lease, the name of its pod,
+// or its hostname when not running in a pod
+func getLeaderElectionIdentity() (string, error) {
+	if name := os.Getenv(util.EnvPodName); name != "" {
+		return name, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the leader election identity: %w", err)
+	}
+	return hostname, nil
+}
+
+// isLeading returns whether the speaker holds the Lease, always true without leader election
+func (c *Controller) isLeading() bool {
+	return !c.config.EnableLeaderElection || c.leading.Load()
+}
+
+// onStartedLeading announces the routes once the speaker holds the Lease
+func (c *Controller) onStartedLeading(_ context.Context) {
+	klog.Infof("acquired leader lease %s/%s, announcing routes", c.config.LeaderElectionNamespace, c.config.LeaderElectionLease)
+	c.leading.Store(true)
+	c.requestReconcile()
+}
+
+// onStoppedLeading withdraws the routes once the speaker lost the Lease
+func (c *Controller) onStoppedLeading() {
+	if !c.leading.Swap(false) {
+		return
+	}
+	klog.Warningf("lost leader lease %s/%s, withdrawing routes", c.config.LeaderElectionNamespace, c.config.LeaderElectionLease)
+	c.requestReconcile()
+}
+
+// newLeaderElector returns the leader elector campaigning for the Lease of the configuration
+func (c *Controller) newLeaderElector(leaseDuration, renewDeadline, retryPeriod time.Duration) (*leaderelection.LeaderElector, error) {
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock,
+		c.config.LeaderElectionNamespace,
+		c.config.LeaderElectionLease,
+		nil,
+		c.config.KubeClient.CoordinationV1(),
+		resourcelock.ResourceLockConfig{
+			Identity:      c.config.LeaderElectionIdentity,
+			EventRecorder: c.recorder,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leader lock: %w", err)
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: c.onStartedLeading,
+			OnStoppedLeading: c.onStoppedLeading,
+			OnNewLeader: func(identity string) {
+				klog.Infof("speaker %s holds leader lease %s/%s", identity, c.config.LeaderElectionNamespace, c.config.LeaderElectionLease)
+			},
+		},
+		ReleaseOnCancel: true,
+		Name:            c.config.LeaderElectionLease,
+	})
+}
+
+// runLeaderElection campaigns for the Lease until stopCh is closed. Unlike the controller, the speaker does not
+// exit when losing the Lease: its routes are withdrawn and it campaigns again.
+func (c *Controller) runLeaderElection(elector *leaderelection.LeaderElector, stopCh <-chan struct{}) {
+	ctx := wait.ContextForChannel(stopCh)
+	wait.UntilWithContext(ctx, elector.Run, leaderRetryPeriod)
+}
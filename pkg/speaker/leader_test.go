@@ -0,0 +1,112 @@
+package speaker
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/set"
+)
+
+func TestGetLeaderElectionIdentity(t *testing.T) {
+	t.Setenv("POD_NAME", "kube-ovn-speaker-abcde")
+	identity, err := getLeaderElectionIdentity()
+	require.NoError(t, err)
+	require.Equal(t, "kube-ovn-speaker-abcde", identity)
+
+	t.Setenv("POD_NAME", "")
+	identity, err = getLeaderElectionIdentity()
+	require.NoError(t, err)
+	require.NotEmpty(t, identity)
+}
+
+func TestLeadershipGatesAnnouncements(t *testing.T) {
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:       []net.IP{neighbor},
+			NeighborLocalAddresses:  map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			EnableLeaderElection:    true,
+			LeaderElectionNamespace: "kube-system",
+			LeaderElectionLease:     "kube-ovn-speaker",
+		},
+		announced:   newAnnouncedStore(),
+		announcer:   a,
+		reconcileCh: make(chan struct{}, 1),
+	}
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32")}
+
+	// Nothing is announced before holding the lease
+	require.NotEmpty(t, c.announcementSuppressedReason(time.Now()))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The routes are announced once leading
+	c.onStartedLeading(context.Background())
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+	require.Empty(t, c.announcementSuppressedReason(time.Now()))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The routes are withdrawn once the lease is lost
+	c.onStoppedLeading()
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+	require.NotEmpty(t, c.announcementSuppressedReason(time.Now()))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+	require.Empty(t, c.announced.List())
+
+	// Stopping without having led does not request a reconciliation
+	c.onStoppedLeading()
+	require.Empty(t, c.reconcileCh)
+
+	// Announcements are not gated without leader election
+	c.config.EnableLeaderElection = false
+	require.Empty(t, c.announcementSuppressedReason(time.Now()))
+}
+
+func TestLeaderElector(t *testing.T) {
+	client := fake.NewClientset()
+	c := &Controller{
+		config: &Configuration{
+			KubeClient:              client,
+			EnableLeaderElection:    true,
+			LeaderElectionNamespace: "kube-system",
+			LeaderElectionLease:     "kube-ovn-speaker",
+			LeaderElectionIdentity:  "speaker-1",
+		},
+		reconcileCh: make(chan struct{}, 1),
+		recorder:    record.NewFakeRecorder(10),
+	}
+	elector, err := c.newLeaderElector(2*time.Second, time.Second, 100*time.Millisecond)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, c.isLeading, 5*time.Second, 10*time.Millisecond)
+
+	lease, err := client.CoordinationV1().Leases("kube-system").Get(context.Background(), "kube-ovn-speaker", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "speaker-1", *lease.Spec.HolderIdentity)
+
+	// Leadership is lost, and the lease released, once stopped
+	cancel()
+	<-done
+	require.False(t, c.isLeading())
+	lease, err = client.CoordinationV1().Leases("kube-system").Get(context.Background(), "kube-ovn-speaker", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Empty(t, lease.Spec.HolderIdentity)
+}
@@ -0,0 +1,69 @@
+package speaker
+
+import (
+	"maps"
+	"net"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+// addPrefixLimitFilters prevents the prefixes exceeding the prefix limit of a neighbor from being advertised to it
+func (c *Controller) addPrefixLimitFilters(expectedPrefixes prefixMap, filters neighborFilters) {
+	for _, neighbor := range slices.Sorted(maps.Keys(c.config.NeighborMaxPrefixes)) {
+		limit := c.config.NeighborMaxPrefixes[neighbor]
+		addr := net.ParseIP(neighbor)
+
+		// Prefixes we are expected to advertise to this neighbor
+		prefixes := set.New[string]()
+		for _, afiPrefixes := range expectedPrefixes {
+			for prefix := range afiPrefixes {
+				// Prefixes not advertised to the neighbor for another reason do not count
+				if slices.ContainsFunc(c.getRouteNeighbors(prefix), addr.Equal) && !filters[neighbor].Has(prefix) {
+					prefixes.Insert(prefix)
+				}
+			}
+		}
+
+		// Prefixes currently advertised to this neighbor
+		advertised := set.KeySet(c.getNeighborRoutes(addr)).Difference(c.exportFilters[neighbor])
+
+		overLimit := capNeighborPrefixes(prefixes, advertised, limit)
+		for prefix := range overLimit {
+			filters.add(neighbor, prefix)
+		}
+		c.reportPrefixLimit(neighbor, limit, overLimit.Len())
+	}
+}
+
+// capNeighborPrefixes returns the prefixes which must not be advertised to a neighbor so that at most limit prefixes
+// are advertised to it. Prefixes already advertised to the neighbor are kept first, so that reaching the limit only
+// prevents additional prefixes from being advertised, the other ones are kept in sorted order.
+func capNeighborPrefixes(prefixes, advertised set.Set[string], limit int) set.Set[string] {
+	kept := prefixes.Intersection(advertised).SortedList()
+	kept = append(kept, prefixes.Difference(advertised).SortedList()...)
+	if len(kept) <= limit {
+		return set.New[string]()
+	}
+	return set.New(kept[limit:]...)
+}
+
+// reportPrefixLimit reports the number of prefixes not advertised to a neighbor because of its prefix limit,
+// an event being recorded when this number changes
+func (c *Controller) reportPrefixLimit(neighbor string, limit, overLimit int) {
+	metricPrefixesOverLimit.WithLabelValues(neighbor).Set(float64(overLimit))
+	if c.prefixesOverLimit[neighbor] == overLimit {
+		return
+	}
+	c.prefixesOverLimit[neighbor] = overLimit
+
+	if overLimit == 0 {
+		klog.Infof("prefixes advertised to neighbor %s are back under its limit of %d prefixes", neighbor, limit)
+		return
+	}
+	klog.Warningf("%d prefixes exceed the limit of %d prefixes of neighbor %s and are not advertised to it", overLimit, limit, neighbor)
+	c.recordEvent(corev1.EventTypeWarning, "PrefixLimitExceeded",
+		"%d prefixes exceed the limit of %d prefixes of neighbor %s and are not advertised to it", overLimit, limit, neighbor)
+}
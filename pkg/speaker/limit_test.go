@@ -0,0 +1,156 @@
+package speaker
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/set"
+)
+
+func TestCapNeighborPrefixes(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefixes   []string
+		advertised []string
+		limit      int
+		overLimit  []string
+	}{
+		{name: "under the limit", prefixes: []string{"192.168.1.1/32", "192.168.1.2/32"}, limit: 3},
+		{name: "at the limit", prefixes: []string{"192.168.1.1/32", "192.168.1.2/32"}, limit: 2},
+		{
+			name:      "over the limit keeps sorted prefixes",
+			prefixes:  []string{"192.168.1.3/32", "192.168.1.1/32", "192.168.1.2/32"},
+			limit:     2,
+			overLimit: []string{"192.168.1.3/32"},
+		},
+		{
+			name:       "over the limit keeps advertised prefixes first",
+			prefixes:   []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32"},
+			advertised: []string{"192.168.1.3/32", "192.168.1.4/32"},
+			limit:      2,
+			overLimit:  []string{"192.168.1.2/32"},
+		},
+		{
+			name:      "zero limit",
+			prefixes:  []string{"192.168.1.1/32", "2001:db8::1/128"},
+			limit:     0,
+			overLimit: []string{"192.168.1.1/32", "2001:db8::1/128"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overLimit := capNeighborPrefixes(set.New(tt.prefixes...), set.New(tt.advertised...), tt.limit)
+			require.ElementsMatch(t, tt.overLimit, overLimit.UnsortedList())
+		})
+	}
+}
+
+func TestAddPrefixLimitFilters(t *testing.T) {
+	limited, unlimited := net.ParseIP("10.32.32.1"), net.ParseIP("10.32.32.3")
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		config: &Configuration{
+			NodeName:            "node1",
+			NeighborAddresses:   []net.IP{limited, unlimited},
+			NeighborMaxPrefixes: map[string]int{limited.String(): 2},
+		},
+		announced:         newAnnouncedStore(),
+		prefixesOverLimit: make(map[string]int),
+		recorder:          recorder,
+	}
+	c.announced.Add("192.168.1.4/32", routeAttributes{})
+
+	expected := prefixMap{}
+	for _, ip := range []string{"192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.1.4", "2001:db8::1"} {
+		addExpectedPrefix(ip, expected)
+	}
+
+	// The prefix already advertised is kept, and ipv6 prefixes are not advertised to ipv4 neighbors
+	filters := make(neighborFilters)
+	c.addPrefixLimitFilters(expected, filters)
+	require.Equal(t, neighborFilters{limited.String(): set.New("192.168.1.2/32", "192.168.1.3/32")}, filters)
+	require.Equal(t, float64(2), testutil.ToFloat64(metricPrefixesOverLimit.WithLabelValues(limited.String())))
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "PrefixLimitExceeded")
+
+	// The event is only recorded when the number of prefixes over the limit changes
+	c.exportFilters = filters
+	filters = make(neighborFilters)
+	c.addPrefixLimitFilters(expected, filters)
+	require.Equal(t, neighborFilters{limited.String(): set.New("192.168.1.2/32", "192.168.1.3/32")}, filters)
+	require.Empty(t, recorder.Events)
+
+	// Filtered prefixes are advertised once there is room for them
+	expected[api.Family_AFI_IP].Delete("192.168.1.1/32", "192.168.1.2/32")
+	filters = make(neighborFilters)
+	c.addPrefixLimitFilters(expected, filters)
+	require.Empty(t, filters)
+	require.Zero(t, testutil.ToFloat64(metricPrefixesOverLimit.WithLabelValues(limited.String())))
+}
+
+func TestApplyExportFilters(t *testing.T) {
+	// Nothing is configured until a prefix has to be filtered
+	c := &Controller{config: &Configuration{}}
+	require.NoError(t, c.applyExportFilters(neighborFilters{}))
+	require.False(t, c.exportPolicyAssigned)
+
+	s := newTestBgpServer(t)
+	c.config.BgpServer = s
+	listStatements := func() []string {
+		var statements []string
+		require.NoError(t, s.ListPolicy(context.Background(), &api.ListPolicyRequest{Name: exportPolicyName}, func(p *api.Policy) {
+			for _, statement := range p.Statements {
+				statements = append(statements, statement.Name)
+			}
+		}))
+		return statements
+	}
+
+	filters := neighborFilters{
+		"10.32.32.1": set.New("192.168.1.1/32", "192.168.1.2/32"),
+		"fd00::1":    set.New("192.168.1.1/32", "2001:db8::1/128"),
+	}
+	require.NoError(t, c.applyExportFilters(filters))
+	require.True(t, c.exportPolicyAssigned)
+	require.Equal(t, []string{
+		exportPolicyName + "-AFI_IP-0",
+		exportPolicyName + "-AFI_IP-1",
+		exportPolicyName + "-AFI_IP6-1",
+	}, listStatements())
+
+	var assignments []*api.PolicyAssignment
+	require.NoError(t, s.ListPolicyAssignment(context.Background(), &api.ListPolicyAssignmentRequest{
+		Name:      "global",
+		Direction: api.PolicyDirection_POLICY_DIRECTION_EXPORT,
+	}, func(a *api.PolicyAssignment) { assignments = append(assignments, a) }))
+	require.Len(t, assignments, 1)
+	require.Equal(t, api.RouteAction_ROUTE_ACTION_ACCEPT, assignments[0].DefaultAction)
+	require.Len(t, assignments[0].Policies, 1)
+	require.Equal(t, exportPolicyName, assignments[0].Policies[0].Name)
+
+	// Removing the filters keeps the policy without any statement
+	require.NoError(t, c.applyExportFilters(neighborFilters{}))
+	require.Empty(t, listStatements())
+}
+
+func TestNeighborFiltersChangedNeighbors(t *testing.T) {
+	old := neighborFilters{
+		"10.32.32.1": set.New("192.168.1.1/32"),
+		"10.32.32.2": set.New("192.168.1.1/32"),
+		"10.32.32.3": set.New("192.168.1.1/32"),
+	}
+	filters := neighborFilters{
+		"10.32.32.1": set.New("192.168.1.1/32"),
+		"10.32.32.2": set.New("192.168.1.2/32"),
+		"10.32.32.4": set.New("192.168.1.1/32"),
+	}
+	require.Equal(t, []string{"10.32.32.2", "10.32.32.3", "10.32.32.4"}, filters.changedNeighbors(old))
+	require.True(t, filters.equal(filters))
+	require.False(t, filters.equal(old))
+}
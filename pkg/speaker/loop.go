@@ -0,0 +1,64 @@
+package speaker
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+// findLoopedPrefixes returns the announced prefixes also received from each neighbor, sorted, which indicates a
+// routing loop or a misconfiguration. The neighbors which did not send back any announced prefix are omitted.
+func findLoopedPrefixes(announced []string, received map[string][]receivedRoute) map[string][]string {
+	announcedPrefixes := set.New[string]()
+	for _, route := range announced {
+		if prefix, err := netip.ParsePrefix(route); err == nil {
+			announcedPrefixes.Insert(prefix.Masked().String())
+		}
+	}
+
+	looped := make(map[string][]string)
+	for neighbor, routes := range received {
+		prefixes := set.New[string]()
+		for _, route := range routes {
+			if prefix, err := netip.ParsePrefix(route.Prefix); err == nil && announcedPrefixes.Has(prefix.Masked().String()) {
+				prefixes.Insert(prefix.Masked().String())
+			}
+		}
+		if prefixes.Len() != 0 {
+			looped[neighbor] = prefixes.SortedList()
+		}
+	}
+	return looped
+}
+
+// detectRouteLoops warns about the announced prefixes received back from a neighbor with --detect-route-loops,
+// whenever they change, and publishes their number by neighbor.
+// It is called at the end of each reconciliation, once the announced routes are up to date.
+func (c *Controller) detectRouteLoops() {
+	if !c.config.DetectRouteLoops {
+		return
+	}
+
+	received, err := c.getReceivedRoutes()
+	if err != nil {
+		klog.Errorf("failed to detect route loops: %v", err)
+		return
+	}
+	looped := findLoopedPrefixes(c.announced.List(), received)
+	for neighbor := range received {
+		prefixes := looped[neighbor]
+		metricLoopedPrefixes.WithLabelValues(neighbor).Set(float64(len(prefixes)))
+		if slices.Equal(prefixes, c.loopedPrefixes[neighbor]) {
+			continue
+		}
+		if len(prefixes) != 0 {
+			klog.Warningf("announced prefixes %s are also received from neighbor %s, there may be a routing loop or a misconfiguration", strings.Join(prefixes, ", "), neighbor)
+		} else {
+			klog.Infof("announced prefixes are not received from neighbor %s anymore", neighbor)
+		}
+	}
+	c.loopedPrefixes = looped
+}
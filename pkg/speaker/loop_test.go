@@ -0,0 +1,81 @@
+package speaker
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindLoopedPrefixes(t *testing.T) {
+	announced := []string{"192.168.1.1/32", "192.168.2.0/24", "2001:db8::1/128"}
+
+	tests := []struct {
+		name     string
+		received map[string][]receivedRoute
+		expected map[string][]string
+	}{
+		{name: "nothing received", received: map[string][]receivedRoute{"10.32.32.1": {}}, expected: map[string][]string{}},
+		{
+			name: "no overlap",
+			received: map[string][]receivedRoute{
+				"10.32.32.1": {{Prefix: "0.0.0.0/0"}, {Prefix: "192.168.1.0/24"}, {Prefix: "192.168.2.0/25"}},
+			},
+			expected: map[string][]string{},
+		},
+		{
+			name: "overlap",
+			received: map[string][]receivedRoute{
+				"10.32.32.1": {{Prefix: "0.0.0.0/0"}, {Prefix: "192.168.2.0/24"}, {Prefix: "192.168.1.1/32", Filtered: true}},
+				"10.32.32.2": {{Prefix: "0.0.0.0/0"}},
+				"fd00::1":    {{Prefix: "2001:db8:0:0::1/128"}},
+			},
+			expected: map[string][]string{
+				"10.32.32.1": {"192.168.1.1/32", "192.168.2.0/24"},
+				"fd00::1":    {"2001:db8::1/128"},
+			},
+		},
+		{
+			name: "prefix received several times",
+			received: map[string][]receivedRoute{
+				"10.32.32.1": {{Prefix: "192.168.1.1/32", NextHop: "10.32.32.1"}, {Prefix: "192.168.1.1/32", NextHop: "10.32.32.3"}},
+			},
+			expected: map[string][]string{"10.32.32.1": {"192.168.1.1/32"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, findLoopedPrefixes(announced, tt.received))
+		})
+	}
+
+	require.Empty(t, findLoopedPrefixes(nil, map[string][]receivedRoute{"10.32.32.1": {{Prefix: "192.168.1.1/32"}}}))
+}
+
+func TestDetectRouteLoops(t *testing.T) {
+	s := newTestBgpServer(t)
+	require.NoError(t, s.AddPeer(context.Background(), &api.AddPeerRequest{Peer: &api.Peer{
+		Conf:      &api.PeerConf{NeighborAddress: "10.32.32.1", PeerAsn: 65001},
+		Transport: &api.Transport{PassiveMode: true},
+	}}))
+	c := &Controller{
+		config:    &Configuration{BgpServer: s, NeighborAddresses: []net.IP{net.ParseIP("10.32.32.1")}},
+		announced: newAnnouncedStore(),
+	}
+	c.announced.Add("192.168.1.1/32", routeAttributes{})
+	c.loopedPrefixes = map[string][]string{"10.32.32.1": {"192.168.1.1/32"}}
+
+	// Route loops are not detected without --detect-route-loops
+	c.detectRouteLoops()
+	require.Equal(t, map[string][]string{"10.32.32.1": {"192.168.1.1/32"}}, c.loopedPrefixes)
+
+	// The prefixes not received anymore are cleared
+	c.config.DetectRouteLoops = true
+	c.detectRouteLoops()
+	require.Empty(t, c.loopedPrefixes)
+	require.Zero(t, testutil.ToFloat64(metricLoopedPrefixes.WithLabelValues("10.32.32.1")))
+}
@@ -0,0 +1,96 @@
+package speaker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	metricUnallowedOriginAnnouncements = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_speaker_unallowed_origin_announcements_total",
+			Help: "The number of announcements of prefixes outside of the CIDRs the cluster AS is allowed to originate, by protocol of the prefixes",
+		},
+		[]string{"protocol"},
+	)
+
+	metricPrefixesOverLimit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_speaker_neighbor_prefixes_over_limit",
+			Help: "The number of prefixes not advertised to a neighbor because they exceed its prefix limit",
+		},
+		[]string{"neighbor"},
+	)
+
+	metricLoopedPrefixes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_speaker_looped_prefixes",
+			Help: "The number of announced prefixes also received from a neighbor, which indicates a routing loop or a misconfiguration",
+		},
+		[]string{"neighbor"},
+	)
+
+	metricEIPNoAddress = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_speaker_eip_no_address_total",
+			Help: "The number of times a ready EIP without any address was found",
+		},
+	)
+
+	metricLocalEIPs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_speaker_local_eips",
+			Help: "The number of EIPs attached to the NAT gateway of the speaker, updated on each reconciliation",
+		},
+	)
+
+	metricRoutesAnnounced = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_speaker_routes_announced_total",
+			Help: "The number of routes announced by the reconciliations, by origin of the reconciliation: startup, event when requested by an event, or reconcile when periodic",
+		},
+		[]string{"origin"},
+	)
+
+	metricReconcileResults = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_speaker_reconcile_result_total",
+			Help: "The results of the reconciliations of the routes of the EIPs, by type: announced and withdrawn routes, skipped EIPs without any route to announce, and error for the routes which failed to be announced or withdrawn",
+		},
+		[]string{"type"},
+	)
+
+	metricAnnounceQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_speaker_announce_queue_depth",
+			Help: "The number of routes left pending to announce by the last reconciliation, because of the announce queue size or of a failed announcement",
+		},
+	)
+
+	metricRoutesLastRefresh = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kube_ovn_speaker_routes_last_refresh_timestamp_seconds",
+			Help: "The time the announced routes were last advertised again to every neighbor, in seconds since the epoch",
+		},
+	)
+
+	metricRouteEventsDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "kube_ovn_speaker_route_events_dropped_total",
+			Help: "The number of route events dropped because a subscriber of the route events socket did not read them fast enough",
+		},
+	)
+)
+
+func InitMetrics() {
+	metrics.Registry.MustRegister(metricUnallowedOriginAnnouncements)
+	metrics.Registry.MustRegister(metricPrefixesOverLimit)
+	metrics.Registry.MustRegister(metricLoopedPrefixes)
+	metrics.Registry.MustRegister(metricEIPNoAddress)
+	metrics.Registry.MustRegister(metricLocalEIPs)
+	metrics.Registry.MustRegister(metricRoutesAnnounced)
+	metrics.Registry.MustRegister(metricAnnounceQueueDepth)
+	metrics.Registry.MustRegister(metricReconcileResults)
+	metrics.Registry.MustRegister(metricRoutesLastRefresh)
+	metrics.Registry.MustRegister(metricRouteEventsDropped)
+}
@@ -0,0 +1,61 @@
+package speaker
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	v1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// getEIPNeighbors returns the sorted comma separated list of the addresses of the neighbors selected with the
+// BGP neighbor annotation of an EIP, the only neighbors its routes are advertised to. It returns an empty string
+// if the EIP is not restricted to some neighbors.
+func getEIPNeighbors(eip *v1.IptablesEIP) (string, error) {
+	annotation := eip.Annotations[util.BgpNeighborAnnotation]
+	if annotation == "" {
+		return "", nil
+	}
+
+	var neighbors []string
+	for s := range strings.SplitSeq(annotation, ",") {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip == nil {
+			return "", fmt.Errorf("%q is not a neighbor address", s)
+		}
+		neighbors = append(neighbors, ip.String())
+	}
+	slices.Sort(neighbors)
+	return strings.Join(slices.Compact(neighbors), ","), nil
+}
+
+// addSubnetNeighbors restricts the routes of the EIPs of the external subnets mapped to some neighbors to these
+// neighbors, the routes of the EIPs of an external subnet being announced in the batch named after the subnet.
+// Routes already restricted by the BGP neighbor annotation of their EIP are left untouched.
+func (c *Controller) addSubnetNeighbors(attrs prefixAttributes) {
+	for prefix, prefixAttrs := range attrs {
+		if neighbors := c.config.SubnetNeighbors[prefixAttrs.batch]; neighbors != "" && prefixAttrs.neighbors == "" {
+			prefixAttrs.neighbors = neighbors
+			attrs[prefix] = prefixAttrs
+		}
+	}
+}
+
+// addNeighborFilters prevents routes restricted to some neighbors from being advertised to the other neighbors
+func (c *Controller) addNeighborFilters(expectedPrefixes prefixMap, attrs prefixAttributes, filters neighborFilters) {
+	for _, prefixes := range expectedPrefixes {
+		for prefix := range prefixes {
+			if attrs[prefix].neighbors == "" {
+				continue
+			}
+			selected := strings.Split(attrs[prefix].neighbors, ",")
+			for _, neighbor := range c.getRouteNeighbors(prefix) {
+				if !slices.Contains(selected, neighbor.String()) {
+					filters.add(neighbor.String(), prefix)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package speaker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/osrg/gobgp/v4/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// asRange is an inclusive range of AS numbers
+type asRange struct {
+	first, last uint32
+}
+
+// parseASRange parses a range of AS numbers, e.g. "64512-65534" or "65001", an empty range containing every AS
+func parseASRange(s string) (asRange, error) {
+	if s == "" {
+		return asRange{first: 1, last: math.MaxUint32}, nil
+	}
+
+	first, last, found := strings.Cut(s, "-")
+	if !found {
+		last = first
+	}
+	firstAs, err := strconv.ParseUint(strings.TrimSpace(first), 10, 32)
+	if err != nil {
+		return asRange{}, fmt.Errorf("invalid AS range %q: %w", s, err)
+	}
+	lastAs, err := strconv.ParseUint(strings.TrimSpace(last), 10, 32)
+	if err != nil {
+		return asRange{}, fmt.Errorf("invalid AS range %q: %w", s, err)
+	}
+	if firstAs == 0 || firstAs > lastAs {
+		return asRange{}, fmt.Errorf("invalid AS range %q: the first AS must be positive and not greater than the last one", s)
+	}
+	return asRange{first: uint32(firstAs), last: uint32(lastAs)}, nil
+}
+
+// contains returns whether an AS number is in the range
+func (r asRange) contains(asn uint32) bool {
+	return asn >= r.first && asn <= r.last
+}
+
+func (r asRange) String() string {
+	return fmt.Sprintf("%d-%d", r.first, r.last)
+}
+
+// isNeighborAsAllowed returns whether the AS a neighbor advertised in its OPEN message is accepted. The AS of
+// neighbors configured with an AS number is checked by the BGP server, only the AS of the neighbors whose AS
+// is discovered must be in the range of the automatic neighbor AS.
+func (c *Controller) isNeighborAsAllowed(configuredAs, advertisedAs uint32) bool {
+	if configuredAs != 0 || c.config.AutoNeighborAs == nil {
+		return true
+	}
+	return c.config.AutoNeighborAs.contains(advertisedAs)
+}
+
+// checkNeighborAs disables the session with a neighbor whose discovered AS is not allowed, so that it stops
+// receiving our routes until the speaker is restarted with a configuration allowing it
+func (c *Controller) checkNeighborAs(neighbor string, configuredAs, advertisedAs uint32) {
+	if c.isNeighborAsAllowed(configuredAs, advertisedAs) {
+		return
+	}
+
+	klog.Errorf("neighbor %s advertised AS %d outside of the allowed range %s, disabling its session", neighbor, advertisedAs, c.config.AutoNeighborAs)
+	c.recordEvent(corev1.EventTypeWarning, "NeighborAsNotAllowed",
+		"neighbor %s advertised AS %d outside of the allowed range %s, its session is disabled", neighbor, advertisedAs, c.config.AutoNeighborAs)
+	if err := c.config.BgpServer.DisablePeer(context.Background(), &api.DisablePeerRequest{
+		Address:       neighbor,
+		Communication: fmt.Sprintf("AS %d is not allowed", advertisedAs),
+	}); err != nil {
+		klog.Errorf("failed to disable the session with neighbor %s: %v", neighbor, err)
+	}
+}
@@ -0,0 +1,103 @@
+package speaker
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestParseASRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected asRange
+		wantErr  bool
+	}{
+		{input: "", expected: asRange{first: 1, last: math.MaxUint32}},
+		{input: "65001", expected: asRange{first: 65001, last: 65001}},
+		{input: "64512-65534", expected: asRange{first: 64512, last: 65534}},
+		{input: "4200000000-4294967294", expected: asRange{first: 4200000000, last: 4294967294}},
+		{input: "65534-64512", wantErr: true},
+		{input: "0-100", wantErr: true},
+		{input: "64512-", wantErr: true},
+		{input: "a-b", wantErr: true},
+		{input: "4294967296", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := parseASRange(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, r)
+		})
+	}
+}
+
+func TestIsNeighborAsAllowed(t *testing.T) {
+	tests := []struct {
+		name           string
+		autoNeighborAs *asRange
+		configuredAs   uint32
+		advertisedAs   uint32
+		expected       bool
+	}{
+		{name: "configured AS", configuredAs: 65001, advertisedAs: 65001, expected: true},
+		{name: "configured AS with automatic AS", autoNeighborAs: &asRange{first: 64512, last: 64520}, configuredAs: 65001, advertisedAs: 65001, expected: true},
+		{name: "advertised AS in range", autoNeighborAs: &asRange{first: 64512, last: 65534}, advertisedAs: 65001, expected: true},
+		{name: "advertised AS first of range", autoNeighborAs: &asRange{first: 64512, last: 65534}, advertisedAs: 64512, expected: true},
+		{name: "advertised AS last of range", autoNeighborAs: &asRange{first: 64512, last: 65534}, advertisedAs: 65534, expected: true},
+		{name: "advertised AS below range", autoNeighborAs: &asRange{first: 64512, last: 65534}, advertisedAs: 64511, expected: false},
+		{name: "advertised AS above range", autoNeighborAs: &asRange{first: 64512, last: 65534}, advertisedAs: 65535, expected: false},
+		{name: "any advertised AS", autoNeighborAs: &asRange{first: 1, last: math.MaxUint32}, advertisedAs: 4200000000, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{config: &Configuration{AutoNeighborAs: tt.autoNeighborAs}}
+			require.Equal(t, tt.expected, c.isNeighborAsAllowed(tt.configuredAs, tt.advertisedAs))
+		})
+	}
+}
+
+func TestCheckNeighborAs(t *testing.T) {
+	s := newTestBgpServer(t)
+	neighbor := "10.32.32.1"
+	require.NoError(t, s.AddPeer(context.Background(), &api.AddPeerRequest{
+		Peer: &api.Peer{
+			Conf:      &api.PeerConf{NeighborAddress: neighbor},
+			Transport: &api.Transport{PassiveMode: true},
+		},
+	}))
+	adminState := func() api.PeerState_AdminState {
+		var state api.PeerState_AdminState
+		require.NoError(t, s.ListPeer(context.Background(), &api.ListPeerRequest{Address: neighbor}, func(p *api.Peer) {
+			state = p.State.AdminState
+		}))
+		return state
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		config:   &Configuration{BgpServer: s, NodeName: "node1", AutoNeighborAs: &asRange{first: 64512, last: 65534}},
+		recorder: recorder,
+	}
+
+	// The session with a neighbor advertising an allowed AS is kept
+	c.checkNeighborAs(neighbor, 0, 65001)
+	require.Equal(t, api.PeerState_ADMIN_STATE_UP, adminState())
+	require.Empty(t, recorder.Events)
+
+	// and disabled if the AS is not allowed
+	c.checkNeighborAs(neighbor, 0, 65535)
+	require.Eventually(t, func() bool { return adminState() == api.PeerState_ADMIN_STATE_DOWN }, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "NeighborAsNotAllowed")
+}
@@ -0,0 +1,110 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestGetEIPNeighbors(t *testing.T) {
+	tests := []struct {
+		annotation string
+		expected   string
+		wantErr    bool
+	}{
+		{annotation: "", expected: ""},
+		{annotation: "10.32.32.1", expected: "10.32.32.1"},
+		{annotation: "10.32.32.2, 10.32.32.1,10.32.32.2", expected: "10.32.32.1,10.32.32.2"},
+		{annotation: "fd00:0::1,10.32.32.1", expected: "10.32.32.1,fd00::1"},
+		{annotation: "10.32.32.1,upstream", wantErr: true},
+		{annotation: "10.32.32.0/24", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.annotation, func(t *testing.T) {
+			eip := newTestEIP("eip", "192.168.1.1", "", true, map[string]string{util.BgpNeighborAnnotation: tt.annotation})
+			neighbors, err := getEIPNeighbors(eip)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, neighbors)
+		})
+	}
+}
+
+func TestNeighborExportFilters(t *testing.T) {
+	neighbor1, neighbor2 := net.ParseIP("10.32.32.1"), net.ParseIP("10.32.32.5")
+	ipv6Neighbor := net.ParseIP("fd00::1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:     []net.IP{neighbor1, neighbor2},
+			NeighborIPv6Addresses: []net.IP{ipv6Neighbor},
+		},
+		announced:         newAnnouncedStore(),
+		prefixesOverLimit: make(map[string]int),
+		recorder:          record.NewFakeRecorder(10),
+	}
+
+	bgpAnnotations := func(neighbors string) map[string]string {
+		return map[string]string{util.BgpAnnotation: "true", util.BgpNeighborAnnotation: neighbors}
+	}
+	expected, attrs := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-any", "192.168.1.1", "2001:db8::1", true, map[string]string{util.BgpAnnotation: "true"}),
+		newTestEIP("eip-neighbor1", "192.168.1.2", "2001:db8::2", true, bgpAnnotations(neighbor1.String())),
+		newTestEIP("eip-both", "192.168.1.3", "", true, bgpAnnotations("10.32.32.1,10.32.32.5")),
+		newTestEIP("eip-invalid", "192.168.1.4", "", true, bgpAnnotations("upstream")),
+	}, routeAttributes{hasMED: true, med: 10})
+
+	// EIPs with an invalid annotation are not announced
+	require.ElementsMatch(t, []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32", "2001:db8::1/128", "2001:db8::2/128"}, expectedPrefixList(expected))
+	require.Equal(t, routeAttributes{hasMED: true, med: 10, neighbors: "10.32.32.1"}, attrs["192.168.1.2/32"])
+
+	// The routes of an EIP restricted to some neighbors are not advertised to the other ones
+	require.Equal(t, neighborFilters{
+		neighbor2.String():    set.New("192.168.1.2/32"),
+		ipv6Neighbor.String(): set.New("2001:db8::2/128"),
+	}, c.getExportFilters(expected, attrs))
+}
+
+func TestSubnetNeighbors(t *testing.T) {
+	neighbor1, neighbor2 := net.ParseIP("10.32.32.1"), net.ParseIP("10.32.32.5")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses: []net.IP{neighbor1, neighbor2},
+			SubnetNeighbors:   map[string]string{"external1": "10.32.32.1"},
+		},
+		announced:         newAnnouncedStore(),
+		prefixesOverLimit: make(map[string]int),
+		recorder:          record.NewFakeRecorder(10),
+	}
+
+	newSubnetEIP := func(name, ip, subnet string, annotations map[string]string) *kubeovnv1.IptablesEIP {
+		eip := newTestEIP(name, ip, "", true, annotations)
+		eip.Spec.ExternalSubnet = subnet
+		return eip
+	}
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	expected, attrs := getEIPExpectedPrefixes([]*kubeovnv1.IptablesEIP{
+		newSubnetEIP("eip-mapped", "192.168.1.1", "external1", bgpAnnotation),
+		newSubnetEIP("eip-annotated", "192.168.1.2", "external1", map[string]string{util.BgpAnnotation: "true", util.BgpNeighborAnnotation: neighbor2.String()}),
+		newSubnetEIP("eip-unmapped", "192.168.2.1", "external2", bgpAnnotation),
+	}, routeAttributes{})
+	c.addSubnetNeighbors(attrs)
+
+	// The EIPs of a mapped subnet are only advertised to its neighbors, unless their annotation selects others
+	require.Equal(t, "10.32.32.1", attrs["192.168.1.1/32"].neighbors)
+	require.Equal(t, "10.32.32.5", attrs["192.168.1.2/32"].neighbors)
+	require.Empty(t, attrs["192.168.2.1/32"].neighbors)
+	require.Equal(t, neighborFilters{
+		neighbor1.String(): set.New("192.168.1.2/32"),
+		neighbor2.String(): set.New("192.168.1.1/32"),
+	}, c.getExportFilters(expected, attrs))
+}
@@ -0,0 +1,102 @@
+package speaker
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/vishvananda/netlink"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// addNextHopFilters prevents the prefixes from being advertised to the neighbors which cannot reach directly the next
+// hop advertised to them, when the reachability of the next hops is validated. Some neighbors drop the routes whose
+// next hop is not directly connected rather than resolving it recursively.
+func (c *Controller) addNextHopFilters(expectedPrefixes prefixMap, filters neighborFilters) {
+	if !c.config.ValidateNextHopReachability {
+		return
+	}
+
+	for _, neighbor := range c.config.allNeighborAddresses() {
+		err := c.checkNextHopReachability(neighbor)
+		c.reportNextHopReachability(neighbor.String(), err)
+		if err == nil {
+			continue
+		}
+		for _, afiPrefixes := range expectedPrefixes {
+			for prefix := range afiPrefixes {
+				if slices.ContainsFunc(c.getRouteNeighbors(prefix), neighbor.Equal) {
+					filters.add(neighbor.String(), prefix)
+				}
+			}
+		}
+	}
+}
+
+// checkNextHopReachability looks up the route to a neighbor on the node and checks that the next hop advertised
+// to the neighbor is directly reachable from it
+func (c *Controller) checkNextHopReachability(neighbor net.IP) error {
+	nextHop := c.getNextHopAttribute(neighbor)
+	routes, err := netlink.RouteGet(neighbor)
+	if err != nil {
+		return fmt.Errorf("failed to look up the route to neighbor %s: %w", neighbor, err)
+	}
+
+	var addrs []netlink.Addr
+	if len(routes) != 0 {
+		link, err := netlink.LinkByIndex(routes[0].LinkIndex)
+		if err != nil {
+			return fmt.Errorf("failed to get the link of the route to neighbor %s: %w", neighbor, err)
+		}
+		if addrs, err = netlink.AddrList(link, netlink.FAMILY_ALL); err != nil {
+			return fmt.Errorf("failed to list the addresses of link %s: %w", link.Attrs().Name, err)
+		}
+	}
+	return validateNextHopReachability(neighbor, nextHop, routes, addrs)
+}
+
+// validateNextHopReachability returns why a next hop is not directly reachable from a neighbor, given the routes
+// to the neighbor found on the node and the addresses of the link of the first route. The next hop is reachable if
+// the neighbor is directly connected, without gateway, and the next hop is an address of the link whose network
+// holds the neighbor.
+func validateNextHopReachability(neighbor, nextHop net.IP, routes []netlink.Route, linkAddrs []netlink.Addr) error {
+	if len(routes) == 0 {
+		return fmt.Errorf("no route to neighbor %s", neighbor)
+	}
+	if routes[0].Gw != nil {
+		return fmt.Errorf("neighbor %s is reached through gateway %s, it is not directly connected", neighbor, routes[0].Gw)
+	}
+	if nextHop == nil {
+		return errors.New("no next hop")
+	}
+	for _, addr := range linkAddrs {
+		if addr.IPNet != nil && addr.IP.Equal(nextHop) && addr.Contains(neighbor) {
+			return nil
+		}
+	}
+	return fmt.Errorf("next hop %s is not an address of the network of neighbor %s on the link reaching it", nextHop, neighbor)
+}
+
+// reportNextHopReachability reports whether the next hop advertised to a neighbor is reachable from it, an event
+// being recorded when the next hop becomes unreachable
+func (c *Controller) reportNextHopReachability(neighbor string, err error) {
+	previous, wasUnreachable := c.unreachableNextHops[neighbor]
+	if err == nil {
+		if wasUnreachable {
+			delete(c.unreachableNextHops, neighbor)
+			klog.Infof("next hop advertised to neighbor %s is reachable again, advertising the routes to it", neighbor)
+		}
+		return
+	}
+
+	reason := err.Error()
+	if wasUnreachable && previous == reason {
+		return
+	}
+	c.unreachableNextHops[neighbor] = reason
+	klog.Warningf("not advertising the routes to neighbor %s, its next hop is unreachable: %s", neighbor, reason)
+	c.recordEvent(corev1.EventTypeWarning, "NextHopUnreachable",
+		"not advertising the routes to neighbor %s, its next hop is unreachable: %s", neighbor, reason)
+}
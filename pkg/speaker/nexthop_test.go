@@ -0,0 +1,150 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestValidateNextHopReachability(t *testing.T) {
+	parseAddr := func(cidr string) netlink.Addr {
+		addr, err := netlink.ParseAddr(cidr)
+		require.NoError(t, err)
+		return *addr
+	}
+	neighbor := net.ParseIP("10.32.32.1")
+	connected := []netlink.Route{{Dst: &net.IPNet{IP: neighbor, Mask: net.CIDRMask(32, 32)}, Src: net.ParseIP("10.32.32.2")}}
+	linkAddrs := []netlink.Addr{parseAddr("10.32.32.2/24"), parseAddr("192.168.0.2/24")}
+
+	tests := []struct {
+		name      string
+		neighbor  net.IP
+		nextHop   net.IP
+		routes    []netlink.Route
+		linkAddrs []netlink.Addr
+		expected  string
+	}{
+		{
+			name:      "directly connected neighbor",
+			neighbor:  neighbor,
+			nextHop:   net.ParseIP("10.32.32.2"),
+			routes:    connected,
+			linkAddrs: linkAddrs,
+		},
+		{
+			name:     "no route to the neighbor",
+			neighbor: neighbor,
+			nextHop:  net.ParseIP("10.32.32.2"),
+			expected: "no route to neighbor 10.32.32.1",
+		},
+		{
+			name:      "neighbor reached through a gateway",
+			neighbor:  neighbor,
+			nextHop:   net.ParseIP("192.168.0.2"),
+			routes:    []netlink.Route{{Gw: net.ParseIP("192.168.0.1"), Src: net.ParseIP("192.168.0.2")}},
+			linkAddrs: linkAddrs,
+			expected:  "neighbor 10.32.32.1 is reached through gateway 192.168.0.1, it is not directly connected",
+		},
+		{
+			name:      "next hop in another network of the link",
+			neighbor:  neighbor,
+			nextHop:   net.ParseIP("192.168.0.2"),
+			routes:    connected,
+			linkAddrs: linkAddrs,
+			expected:  "next hop 192.168.0.2 is not an address of the network of neighbor 10.32.32.1 on the link reaching it",
+		},
+		{
+			name:      "next hop not on the link",
+			neighbor:  neighbor,
+			nextHop:   net.ParseIP("172.16.0.2"),
+			routes:    connected,
+			linkAddrs: linkAddrs,
+			expected:  "next hop 172.16.0.2 is not an address of the network of neighbor 10.32.32.1 on the link reaching it",
+		},
+		{
+			name:      "IPv6 neighbor",
+			neighbor:  net.ParseIP("fd00::1"),
+			nextHop:   net.ParseIP("fd00::2"),
+			routes:    []netlink.Route{{Src: net.ParseIP("fd00::2")}},
+			linkAddrs: []netlink.Addr{parseAddr("fd00::2/64")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNextHopReachability(tt.neighbor, tt.nextHop, tt.routes, tt.linkAddrs)
+			if tt.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReportNextHopReachability(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		config:              &Configuration{},
+		unreachableNextHops: make(map[string]string),
+		recorder:            recorder,
+	}
+	unreachable := validateNextHopReachability(net.ParseIP("10.32.32.1"), nil, nil, nil)
+
+	// An event is only recorded when the next hop of a neighbor becomes unreachable
+	c.reportNextHopReachability("10.32.32.1", nil)
+	c.reportNextHopReachability("10.32.32.1", unreachable)
+	c.reportNextHopReachability("10.32.32.1", unreachable)
+	require.Len(t, recorder.Events, 1)
+	require.Contains(t, <-recorder.Events, "NextHopUnreachable")
+	require.Contains(t, c.unreachableNextHops, "10.32.32.1")
+
+	c.reportNextHopReachability("10.32.32.1", nil)
+	require.Empty(t, c.unreachableNextHops)
+	c.reportNextHopReachability("10.32.32.1", unreachable)
+	require.Len(t, recorder.Events, 1)
+}
+
+func TestGetPathRequestConfiguredNextHops(t *testing.T) {
+	ipv4Neighbor, ipv6Neighbor := net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")
+	ipv4Local, ipv6Local := net.ParseIP("10.32.32.2"), net.ParseIP("fd00::2")
+
+	c := &Controller{config: &Configuration{
+		NeighborAddresses:     []net.IP{ipv4Neighbor},
+		NeighborIPv6Addresses: []net.IP{ipv6Neighbor},
+		NeighborLocalAddresses: map[string]net.IP{
+			ipv4Neighbor.String(): ipv4Local,
+			ipv6Neighbor.String(): ipv6Local,
+		},
+		ExtendedNexthop: true,
+	}}
+	nextHops := func(route string) []string {
+		paths, err := c.getPathRequest(route, routeAttributes{})
+		require.NoError(t, err)
+		var nextHops []string
+		for _, p := range paths {
+			nextHops = append(nextHops, getNextHopFromPathAttributes(p[0].Attrs).String())
+		}
+		return nextHops
+	}
+
+	// The next hops are derived from the neighbors by default
+	require.Equal(t, []string{"10.32.32.2", "fd00::2"}, nextHops("192.168.1.1"))
+
+	// The configured next hop of the family of each neighbor overrides the derived one, whatever the prefix
+	c.config.NextHopIPv4 = net.ParseIP("172.16.0.1")
+	require.Equal(t, []string{"172.16.0.1", "fd00::2"}, nextHops("192.168.1.1"))
+	c.config.NextHopIPv6 = net.ParseIP("fd01::1")
+	require.Equal(t, []string{"172.16.0.1", "fd01::1"}, nextHops("192.168.1.1"))
+	require.Equal(t, []string{"172.16.0.1", "fd01::1"}, nextHops("2001:db8::1"))
+}
+
+func TestValidateNextHops(t *testing.T) {
+	require.NoError(t, validateNextHops(nil, nil))
+	require.NoError(t, validateNextHops(net.ParseIP("172.16.0.1"), net.ParseIP("fd01::1")))
+	require.Error(t, validateNextHops(net.ParseIP("fd01::1"), nil))
+	require.Error(t, validateNextHops(nil, net.ParseIP("172.16.0.1")))
+}
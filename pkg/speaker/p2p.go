@@ -0,0 +1,49 @@
+package speaker
+
+import (
+	"net/netip"
+
+	"k8s.io/klog/v2"
+)
+
+// addPointToPointPrefixes replaces the host routes of the EIPs of the point-to-point external subnets with the
+// routes of the /31 or /127 point-to-point link holding them, so that the routes match the external links the
+// EIPs egress through. The routes of the EIPs of an external subnet are announced in the batch named after the
+// subnet, the two EIPs of a link sharing its route.
+func (c *Controller) addPointToPointPrefixes(expectedPrefixes prefixMap, attrs prefixAttributes) {
+	if len(c.config.PointToPointSubnets) == 0 {
+		return
+	}
+
+	// Collect the routes first, the routes of the links being added to the attributes while replacing them
+	var hostPrefixes []string
+	for prefix, prefixAttrs := range attrs {
+		if c.config.PointToPointSubnets.Has(prefixAttrs.batch) {
+			hostPrefixes = append(hostPrefixes, prefix)
+		}
+	}
+
+	for _, prefix := range hostPrefixes {
+		prefixAttrs := attrs[prefix]
+		linkPrefix, err := toPointToPointPrefix(prefix)
+		if err != nil {
+			klog.Errorf("failed to get the point-to-point prefix of route %s: %v", prefix, err)
+			continue
+		}
+
+		afi := prefixToAFI(linkPrefix)
+		expectedPrefixes[afi].Delete(prefix)
+		delete(attrs, prefix)
+		expectedPrefixes[afi].Insert(linkPrefix.String())
+		attrs[linkPrefix.String()] = prefixAttrs
+	}
+}
+
+// toPointToPointPrefix returns the /31 or /127 prefix of the point-to-point link of a host prefix
+func toPointToPointPrefix(prefix string) (netip.Prefix, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return p.Addr().Prefix(p.Addr().BitLen() - 1)
+}
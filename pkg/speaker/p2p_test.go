@@ -0,0 +1,46 @@
+package speaker
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestAddPointToPointPrefixes(t *testing.T) {
+	bgp := map[string]string{util.BgpAnnotation: "true"}
+	newEIP := func(name, v4ip, v6ip, subnet string) *kubeovnv1.IptablesEIP {
+		eip := newTestEIP(name, v4ip, v6ip, true, bgp)
+		eip.Spec.ExternalSubnet = subnet
+		return eip
+	}
+	eips := []*kubeovnv1.IptablesEIP{
+		newEIP("eip1", "192.168.1.1", "fd00::1", "p2p"),
+		newEIP("eip2", "192.168.1.4", "", "p2p"),
+		newEIP("eip3", "192.168.1.5", "", "p2p"),
+		newEIP("eip4", "172.16.0.1", "fd01::1", "external"),
+	}
+	expected, attrs := getEIPExpectedPrefixes(eips, routeAttributes{})
+
+	// Routes are left untouched without point-to-point subnets
+	c := &Controller{config: &Configuration{}}
+	c.addPointToPointPrefixes(expected, attrs)
+	require.ElementsMatch(t, []string{
+		"192.168.1.1/32", "192.168.1.4/32", "192.168.1.5/32", "172.16.0.1/32", "fd00::1/128", "fd01::1/128",
+	}, expectedPrefixList(expected))
+
+	// The EIPs of the same link share its route, the routes of the other subnets are left untouched
+	c.config.PointToPointSubnets = set.New("p2p")
+	c.addPointToPointPrefixes(expected, attrs)
+	require.ElementsMatch(t, []string{
+		"192.168.1.0/31", "192.168.1.4/31", "172.16.0.1/32", "fd00::/127", "fd01::1/128",
+	}, expectedPrefixList(expected))
+	require.ElementsMatch(t, expectedPrefixList(expected), slices.Collect(maps.Keys(attrs)))
+	require.Equal(t, "p2p", attrs["192.168.1.4/31"].batch)
+	require.Equal(t, "external", attrs["172.16.0.1/32"].batch)
+}
@@ -0,0 +1,143 @@
+package speaker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/osrg/gobgp/v4/api"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+// exportPolicyName is the name of the global export policy preventing prefixes from being advertised to some neighbors
+const exportPolicyName = "kube-ovn-speaker-export"
+
+// neighborFilters associates the address of a neighbor with the announced prefixes which must not be advertised to it
+type neighborFilters map[string]set.Set[string]
+
+// add prevents a prefix from being advertised to a neighbor
+func (f neighborFilters) add(neighbor, prefix string) {
+	if f[neighbor] == nil {
+		f[neighbor] = set.New[string]()
+	}
+	f[neighbor].Insert(prefix)
+}
+
+// equal returns whether two filters prevent the same prefixes from being advertised to the same neighbors
+func (f neighborFilters) equal(other neighborFilters) bool {
+	return maps.EqualFunc(f, other, func(a, b set.Set[string]) bool { return a.Equal(b) })
+}
+
+// changedNeighbors returns the sorted list of neighbors whose filtered prefixes differ between two filters
+func (f neighborFilters) changedNeighbors(other neighborFilters) []string {
+	changed := set.New[string]()
+	for neighbor, prefixes := range f {
+		if !prefixes.Equal(other[neighbor]) {
+			changed.Insert(neighbor)
+		}
+	}
+	for neighbor, prefixes := range other {
+		if !prefixes.Equal(f[neighbor]) {
+			changed.Insert(neighbor)
+		}
+	}
+	return changed.SortedList()
+}
+
+// applyExportFilters configures the export policy of the BGP server so that the prefixes of the filters are not
+// advertised to their neighbors, and resends the routes of the neighbors whose filters changed. Nothing is
+// configured until a prefix has to be filtered.
+func (c *Controller) applyExportFilters(filters neighborFilters) error {
+	if filters.equal(c.exportFilters) || (len(filters) == 0 && !c.exportPolicyAssigned) {
+		return nil
+	}
+
+	if err := c.config.BgpServer.SetPolicies(context.Background(), getExportPolicyRequest(filters)); err != nil {
+		return fmt.Errorf("failed to set export policy: %w", err)
+	}
+	if !c.exportPolicyAssigned {
+		if err := c.config.BgpServer.AddPolicyAssignment(context.Background(), &api.AddPolicyAssignmentRequest{
+			Assignment: &api.PolicyAssignment{
+				Name:          "global",
+				Direction:     api.PolicyDirection_POLICY_DIRECTION_EXPORT,
+				Policies:      []*api.Policy{{Name: exportPolicyName}},
+				DefaultAction: api.RouteAction_ROUTE_ACTION_ACCEPT,
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to assign export policy: %w", err)
+		}
+		c.exportPolicyAssigned = true
+	}
+
+	// The export policy only applies to the routes sent after it changed, send the routes of the neighbors again
+	for _, neighbor := range filters.changedNeighbors(c.exportFilters) {
+		klog.Infof("export filters of neighbor %s changed, sending its routes again", neighbor)
+		if err := c.config.BgpServer.ResetPeer(context.Background(), &api.ResetPeerRequest{
+			Address:   neighbor,
+			Soft:      true,
+			Direction: api.ResetPeerRequest_DIRECTION_OUT,
+		}); err != nil {
+			klog.Errorf("failed to send the routes of neighbor %s again: %v", neighbor, err)
+		}
+	}
+
+	c.exportFilters = filters
+	return nil
+}
+
+// getExportPolicyRequest returns the request configuring the export policy rejecting the prefixes of the filters
+// when advertised to their neighbors. A prefix set only holds prefixes of a single address family, so each neighbor
+// has a statement per address family.
+func getExportPolicyRequest(filters neighborFilters) *api.SetPoliciesRequest {
+	req := &api.SetPoliciesRequest{}
+	policy := &api.Policy{Name: exportPolicyName}
+	for i, neighbor := range slices.Sorted(maps.Keys(filters)) {
+		byFamily := make(map[api.Family_Afi][]*api.Prefix, 2)
+		for _, route := range filters[neighbor].SortedList() {
+			prefix, err := parsePrefix(route)
+			if err != nil {
+				klog.Errorf("failed to parse filtered prefix %q: %v", route, err)
+				continue
+			}
+			afi := prefixToAFI(prefix)
+			byFamily[afi] = append(byFamily[afi], &api.Prefix{
+				IpPrefix:      prefix.String(),
+				MaskLengthMin: uint32(prefix.Bits()), // #nosec G115
+				MaskLengthMax: uint32(prefix.Bits()), // #nosec G115
+			})
+		}
+		if len(byFamily) == 0 {
+			continue
+		}
+
+		neighborSet := fmt.Sprintf("%s-neighbor-%d", exportPolicyName, i)
+		req.DefinedSets = append(req.DefinedSets, &api.DefinedSet{
+			DefinedType: api.DefinedType_DEFINED_TYPE_NEIGHBOR,
+			Name:        neighborSet,
+			List:        []string{neighbor},
+		})
+		for _, afi := range []api.Family_Afi{api.Family_AFI_IP, api.Family_AFI_IP6} {
+			if len(byFamily[afi]) == 0 {
+				continue
+			}
+			prefixSet := fmt.Sprintf("%s-%s-%d", exportPolicyName, afi, i)
+			req.DefinedSets = append(req.DefinedSets, &api.DefinedSet{
+				DefinedType: api.DefinedType_DEFINED_TYPE_PREFIX,
+				Name:        prefixSet,
+				Prefixes:    byFamily[afi],
+			})
+			policy.Statements = append(policy.Statements, &api.Statement{
+				Name: prefixSet,
+				Conditions: &api.Conditions{
+					NeighborSet: &api.MatchSet{Type: api.MatchSet_TYPE_ANY, Name: neighborSet},
+					PrefixSet:   &api.MatchSet{Type: api.MatchSet_TYPE_ANY, Name: prefixSet},
+				},
+				Actions: &api.Actions{RouteAction: api.RouteAction_ROUTE_ACTION_REJECT},
+			})
+		}
+	}
+	req.Policies = []*api.Policy{policy}
+	return req
+}
@@ -0,0 +1,58 @@
+package speaker
+
+import (
+	"k8s.io/klog/v2"
+)
+
+// announceQueue bounds the number of routes submitted to the BGP server by a reconciliation, so that a BGP server
+// backed up by a slow neighbor is not flooded with announcements. The routes over the bound are left pending: they
+// are not recorded as announced and are announced by the next reconciliations. Once an announcement failed, the
+// BGP server is considered stalled and every other route of the reconciliation is left pending.
+type announceQueue struct {
+	// size is the maximum number of routes announced by a reconciliation, announcements are not bounded if zero
+	size     int
+	admitted int
+	pending  int
+	stalled  bool
+}
+
+func newAnnounceQueue(size int) *announceQueue {
+	return &announceQueue{size: size}
+}
+
+// admit returns the routes of a batch to announce now, the other ones being left pending
+func (q *announceQueue) admit(batch []string) []string {
+	if q.size == 0 {
+		return batch
+	}
+	n := 0
+	if !q.stalled {
+		n = min(len(batch), max(q.size-q.admitted, 0))
+	}
+	q.admitted += n
+	q.pending += len(batch) - n
+	if n < len(batch) {
+		klog.V(5).Infof("routes left pending to announce: %v", batch[n:])
+	}
+	return batch[:n]
+}
+
+// stall leaves every route not admitted yet pending, when announcements are bounded
+func (q *announceQueue) stall() {
+	q.stalled = q.size != 0
+}
+
+// reportAnnounceQueue publishes the number of routes left pending by a reconciliation and, if there are any,
+// requests another reconciliation to announce them
+func (c *Controller) reportAnnounceQueue(q *announceQueue) {
+	metricAnnounceQueueDepth.Set(float64(q.pending))
+	if q.pending == 0 {
+		return
+	}
+	if q.stalled {
+		klog.Warningf("the BGP server failed to accept announcements, %d routes left pending to announce", q.pending)
+	} else {
+		klog.Infof("%d routes left pending to announce, at most %d routes are announced by a reconciliation", q.pending, q.size)
+	}
+	c.requestReconcile()
+}
@@ -0,0 +1,94 @@
+package speaker
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestAnnounceQueueAdmit(t *testing.T) {
+	batch := []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32"}
+
+	// Announcements are not bounded by default, even once stalled
+	q := newAnnounceQueue(0)
+	q.stall()
+	require.Equal(t, batch, q.admit(batch))
+	require.Equal(t, batch, q.admit(batch))
+	require.Zero(t, q.pending)
+
+	// Routes over the size are left pending
+	q = newAnnounceQueue(4)
+	require.Equal(t, batch, q.admit(batch))
+	require.Equal(t, batch[:1], q.admit(batch))
+	require.Empty(t, q.admit(batch))
+	require.Equal(t, 5, q.pending)
+
+	// Once stalled, every route is left pending
+	q = newAnnounceQueue(4)
+	require.Equal(t, batch[:2], q.admit(batch[:2]))
+	q.stall()
+	require.Empty(t, q.admit(batch))
+	require.Equal(t, 3, q.pending)
+}
+
+func TestReconcileRoutesAnnounceQueue(t *testing.T) {
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			AnnounceQueueSize:      4,
+		},
+		announced:   newAnnouncedStore(),
+		announcer:   a,
+		reconcileCh: make(chan struct{}, 1),
+	}
+
+	expected := set.New[string]()
+	for i := range 10 {
+		expected.Insert(fmt.Sprintf("192.168.1.%d/32", i+1))
+	}
+	sorted := expected.SortedList()
+	// Each route is announced in a batch of its own
+	attrs := make(prefixAttributes)
+	for i, route := range sorted {
+		attrs[route] = routeAttributes{batch: fmt.Sprint(i)}
+	}
+
+	// The routes over the size are left pending and another reconciliation is requested
+	require.Empty(t, c.reconcileRoutes(prefixMap{api.Family_AFI_IP: expected}, attrs).failed())
+	require.Equal(t, sorted[:4], c.announced.List())
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+
+	// The stalled BGP server fails the announcement of a batch, the remaining routes are left pending
+	c.config.AnnounceQueueSize = 2
+	a.failing.Insert(sorted[4])
+	a.calls = nil
+	results := c.reconcileRoutes(prefixMap{api.Family_AFI_IP: expected}, attrs)
+	require.Equal(t, []string{sorted[4]}, results.failed())
+	require.Len(t, results, 1)
+	require.Len(t, a.calls, 1)
+	require.Equal(t, sorted[:4], c.announced.List())
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+
+	// The pending routes are announced once the BGP server recovers, no more reconciliation being requested
+	// once every route is announced
+	a.failing.Clear()
+	c.config.AnnounceQueueSize = 10
+	require.Empty(t, c.reconcileRoutes(prefixMap{api.Family_AFI_IP: expected}, attrs).failed())
+	require.Equal(t, sorted, c.announced.List())
+	require.Empty(t, c.reconcileCh)
+
+	// Withdrawals are not bounded
+	c.config.AnnounceQueueSize = 1
+	require.Empty(t, c.reconcileRoutes(make(prefixMap), nil).failed())
+	require.Empty(t, c.announced.List())
+	require.Empty(t, a.announced)
+}
@@ -0,0 +1,70 @@
+package speaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"k8s.io/klog/v2"
+)
+
+// ReceivedRoutesPath is the path of the debug endpoint serving the routes received from the neighbors
+const ReceivedRoutesPath = "/debug/routes"
+
+// receivedRoute is a route received from a neighbor, as stored before import policies are applied
+type receivedRoute struct {
+	Prefix  string `json:"prefix"`
+	NextHop string `json:"nextHop,omitempty"`
+	// Filtered is whether the route is rejected by the import policies
+	Filtered bool `json:"filtered,omitempty"`
+}
+
+// getReceivedRoutes returns the routes received from each neighbor. The BGP server keeps the routes received
+// from a neighbor in its Adj-RIB-In before applying import policies, so they are listed without resetting the
+// session, like with soft reconfiguration inbound.
+func (c *Controller) getReceivedRoutes() (map[string][]receivedRoute, error) {
+	routes := make(map[string][]receivedRoute)
+	for _, neighbor := range c.config.allNeighborAddresses() {
+		neighborRoutes := []receivedRoute{}
+		for _, family := range []bgp.Family{bgp.RF_IPv4_UC, bgp.RF_IPv6_UC} {
+			if err := c.config.BgpServer.ListPath(apiutil.ListPathRequest{
+				TableType:      api.TableType_TABLE_TYPE_ADJ_IN,
+				Name:           neighbor.String(),
+				Family:         family,
+				SortType:       api.ListPathRequest_SORT_TYPE_PREFIX,
+				EnableFiltered: true,
+			}, func(prefix bgp.NLRI, paths []*apiutil.Path) {
+				for _, path := range paths {
+					route := receivedRoute{Prefix: prefix.String(), Filtered: path.Filtered}
+					if nextHop := getNextHopFromPathAttributes(path.Attrs); nextHop != nil {
+						route.NextHop = nextHop.String()
+					}
+					neighborRoutes = append(neighborRoutes, route)
+				}
+			}); err != nil {
+				return nil, fmt.Errorf("failed to list the %s routes received from neighbor %s: %w", family, neighbor, err)
+			}
+		}
+		routes[neighbor.String()] = neighborRoutes
+	}
+	return routes, nil
+}
+
+// ReceivedRoutesHandler returns the handler serving the routes received from each neighbor as JSON
+func (c *Controller) ReceivedRoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		routes, err := c.getReceivedRoutes()
+		if err != nil {
+			klog.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(routes); err != nil {
+			klog.Errorf("failed to write received routes: %v", err)
+		}
+	})
+}
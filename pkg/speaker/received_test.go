@@ -0,0 +1,78 @@
+package speaker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	gobgp "github.com/osrg/gobgp/v4/pkg/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceivedRoutesHandler(t *testing.T) {
+	// Find a free port for the neighbor to listen on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	// The neighbor advertises a route to the speaker
+	neighbor := gobgp.NewBgpServer()
+	go neighbor.Serve()
+	t.Cleanup(neighbor.Stop)
+	require.NoError(t, neighbor.StartBgp(context.Background(), &api.StartBgpRequest{
+		Global: &api.Global{Asn: 65001, RouterId: "10.32.32.1", ListenPort: int32(port), ListenAddresses: []string{"127.0.0.1"}}, // #nosec G115
+	}))
+	require.NoError(t, neighbor.AddPeer(context.Background(), &api.AddPeerRequest{Peer: &api.Peer{
+		Conf:      &api.PeerConf{NeighborAddress: "127.0.0.1", PeerAsn: 65000},
+		Transport: &api.Transport{PassiveMode: true},
+	}}))
+	neighborController := &Controller{config: &Configuration{
+		NeighborAddresses:      []net.IP{net.ParseIP("127.0.0.1")},
+		NeighborLocalAddresses: map[string]net.IP{"127.0.0.1": net.ParseIP("10.32.32.1")},
+	}}
+	paths, err := neighborController.getPathRequest("192.168.0.0/24", routeAttributes{})
+	require.NoError(t, err)
+	_, err = neighbor.AddPath(apiutil.AddPathRequest{Paths: paths[0]})
+	require.NoError(t, err)
+
+	s := newTestBgpServer(t)
+	require.NoError(t, s.AddPeer(context.Background(), &api.AddPeerRequest{Peer: &api.Peer{
+		Conf:      &api.PeerConf{NeighborAddress: "127.0.0.1", PeerAsn: 65001},
+		Transport: &api.Transport{RemotePort: uint32(port)}, // #nosec G115
+	}}))
+	c := &Controller{config: &Configuration{BgpServer: s, NeighborAddresses: []net.IP{net.ParseIP("127.0.0.1")}}}
+
+	var routes map[string][]receivedRoute
+	require.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		c.ReceivedRoutesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReceivedRoutesPath, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &routes))
+		return len(routes["127.0.0.1"]) != 0
+	}, 30*time.Second, 100*time.Millisecond)
+	require.Equal(t, map[string][]receivedRoute{
+		"127.0.0.1": {{Prefix: "192.168.0.0/24", NextHop: "10.32.32.1"}},
+	}, routes)
+}
+
+func TestReceivedRoutesHandlerNoRoute(t *testing.T) {
+	s := newTestBgpServer(t)
+	require.NoError(t, s.AddPeer(context.Background(), &api.AddPeerRequest{Peer: &api.Peer{
+		Conf:      &api.PeerConf{NeighborAddress: "10.32.32.1", PeerAsn: 65001},
+		Transport: &api.Transport{PassiveMode: true},
+	}}))
+	c := &Controller{config: &Configuration{BgpServer: s, NeighborAddresses: []net.IP{net.ParseIP("10.32.32.1")}}}
+
+	rec := httptest.NewRecorder()
+	c.ReceivedRoutesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReceivedRoutesPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"10.32.32.1": []}`, rec.Body.String())
+}
@@ -0,0 +1,40 @@
+package speaker
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// refreshRoutesIfDue advertises again all the announced routes to every neighbor if the routes refresh interval
+// elapsed since they were last refreshed.
+//
+// Refreshing routes lets the neighbors, or any system monitoring the RIB they share, expire the routes of a speaker
+// which crashed or hung instead of keeping them forever: a route not refreshed for more than the interval is stale.
+// The speaker itself exposes the time of its last refresh with the
+// kube_ovn_speaker_routes_last_refresh_timestamp_seconds metric, a monitoring system detects a stale speaker when
+// "time() - kube_ovn_speaker_routes_last_refresh_timestamp_seconds" exceeds a few intervals.
+//
+// The routes are only considered refreshed once they were all advertised again to every neighbor, they are
+// otherwise refreshed again by the next reconciliation.
+//
+// It is called at the end of each reconciliation, so that routes are never refreshed while being withdrawn.
+func (c *Controller) refreshRoutesIfDue(now time.Time) {
+	if c.config.RoutesRefreshInterval == 0 || now.Sub(c.lastRoutesRefresh) < c.config.RoutesRefreshInterval {
+		return
+	}
+
+	klog.V(3).Infof("refreshing %d announced routes", len(c.announced.List()))
+	refreshed := true
+	for _, neighbor := range c.config.allNeighborAddresses() {
+		if err := c.readvertiseRoutes(neighbor); err != nil {
+			klog.Errorf("failed to refresh the routes advertised to neighbor %s: %v", neighbor, err)
+			refreshed = false
+		}
+	}
+	if !refreshed {
+		return
+	}
+	c.lastRoutesRefresh = now
+	metricRoutesLastRefresh.Set(float64(now.Unix()))
+}
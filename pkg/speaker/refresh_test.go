@@ -0,0 +1,86 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshRoutesIfDue(t *testing.T) {
+	s := newTestBgpServer(t)
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:              s,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced: newAnnouncedStore(),
+	}
+	require.NoError(t, c.addRoutes([]string{"192.168.1.1/32"}, nil).err())
+
+	// Lose the path without the speaker knowing, as a neighbor expiring a route which was not refreshed would
+	paths, err := c.getPathRequest("192.168.1.1/32", routeAttributes{})
+	require.NoError(t, err)
+	require.NoError(t, s.DeletePath(apiutil.DeletePathRequest{Paths: paths[0]}))
+	require.Empty(t, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+
+	// Routes are not refreshed when disabled
+	now := time.Now()
+	c.refreshRoutesIfDue(now)
+	require.Empty(t, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+
+	// Routes are advertised again once the interval elapsed
+	c.config.RoutesRefreshInterval = time.Minute
+	c.refreshRoutesIfDue(now)
+	require.Equal(t, []string{"192.168.1.1/32"}, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+	require.Equal(t, now, c.lastRoutesRefresh)
+	require.Equal(t, float64(now.Unix()), testutil.ToFloat64(metricRoutesLastRefresh))
+
+	// and not before the next interval elapsed
+	require.NoError(t, s.DeletePath(apiutil.DeletePathRequest{Paths: paths[0]}))
+	c.refreshRoutesIfDue(now.Add(30 * time.Second))
+	require.Empty(t, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+	c.refreshRoutesIfDue(now.Add(time.Minute))
+	require.Equal(t, []string{"192.168.1.1/32"}, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+	require.Equal(t, now.Add(time.Minute), c.lastRoutesRefresh)
+}
+
+func TestRefreshRoutesIfDueFailure(t *testing.T) {
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			RoutesRefreshInterval:  time.Minute,
+		},
+		announced: newAnnouncedStore(),
+		announcer: a,
+	}
+	c.announced.Add("192.168.1.1/32", routeAttributes{})
+	c.announced.Add("192.168.1.2/32", routeAttributes{})
+	lastRefresh := testutil.ToFloat64(metricRoutesLastRefresh)
+
+	// A route failing to be refreshed does not prevent the others from being refreshed,
+	// but the routes are not considered refreshed
+	a.failing.Insert("192.168.1.1/32")
+	now := time.Now()
+	c.refreshRoutesIfDue(now)
+	require.Equal(t, []string{"192.168.1.2/32"}, a.announced.SortedList())
+	require.Len(t, a.calls, 2)
+	require.True(t, c.lastRoutesRefresh.IsZero())
+	require.Equal(t, lastRefresh, testutil.ToFloat64(metricRoutesLastRefresh))
+
+	// The routes are refreshed again by the next reconciliation
+	a.failing.Clear()
+	c.refreshRoutesIfDue(now.Add(5 * time.Second))
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, a.announced.SortedList())
+	require.Equal(t, now.Add(5*time.Second), c.lastRoutesRefresh)
+	require.Equal(t, float64(now.Add(5*time.Second).Unix()), testutil.ToFloat64(metricRoutesLastRefresh))
+}
@@ -0,0 +1,38 @@
+package speaker
+
+import (
+	"net/netip"
+)
+
+const (
+	rpkiStateValid   = "valid"
+	rpkiStateUnknown = "unknown"
+)
+
+// rpkiCommunities are the communities routes are tagged with according to their origin validation state
+type rpkiCommunities struct {
+	// valid and unknown are the communities of the valid routes and of the routes of unknown state, not tagged if zero
+	valid   uint32
+	unknown uint32
+}
+
+// getRPKIValidationState returns the origin validation state of a prefix: valid if it is covered by one of
+// the validated prefixes, unknown otherwise
+func getRPKIValidationState(prefix netip.Prefix, validated []netip.Prefix) string {
+	if len(validated) != 0 && isPrefixOriginAllowed(prefix, validated) {
+		return rpkiStateValid
+	}
+	return rpkiStateUnknown
+}
+
+// getCommunity returns the community a prefix is tagged with according to its origin validation state against
+// the validated prefixes, or zero if it is not tagged
+func (r rpkiCommunities) getCommunity(prefix netip.Prefix, validated []netip.Prefix) uint32 {
+	if len(validated) == 0 {
+		return 0
+	}
+	if getRPKIValidationState(prefix, validated) == rpkiStateValid {
+		return r.valid
+	}
+	return r.unknown
+}
@@ -0,0 +1,76 @@
+package speaker
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRPKIValidationState(t *testing.T) {
+	validated := []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16"), netip.MustParsePrefix("2001:db8::/32")}
+
+	tests := []struct {
+		prefix    string
+		validated []netip.Prefix
+		expected  string
+	}{
+		{prefix: "192.168.1.1/32", validated: validated, expected: rpkiStateValid},
+		{prefix: "192.168.0.0/16", validated: validated, expected: rpkiStateValid},
+		{prefix: "2001:db8::1/128", validated: validated, expected: rpkiStateValid},
+		{prefix: "10.0.0.1/32", validated: validated, expected: rpkiStateUnknown},
+		{prefix: "192.0.0.0/8", validated: validated, expected: rpkiStateUnknown},
+		{prefix: "192.168.1.1/32", expected: rpkiStateUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			require.Equal(t, tt.expected, getRPKIValidationState(netip.MustParsePrefix(tt.prefix), tt.validated))
+		})
+	}
+}
+
+func TestRPKICommunities(t *testing.T) {
+	valid, unknown := netip.MustParsePrefix("192.168.1.1/32"), netip.MustParsePrefix("10.0.0.1/32")
+	validated := []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}
+
+	require.Zero(t, rpkiCommunities{valid: 65000<<16 | 1, unknown: 65000<<16 | 2}.getCommunity(valid, nil))
+
+	r := rpkiCommunities{valid: 65000<<16 | 1, unknown: 65000<<16 | 2}
+	require.Equal(t, uint32(65000<<16|1), r.getCommunity(valid, validated))
+	require.Equal(t, uint32(65000<<16|2), r.getCommunity(unknown, validated))
+
+	// Only the valid routes are tagged without unknown community
+	r = rpkiCommunities{valid: 65000<<16 | 1}
+	require.Equal(t, uint32(65000<<16|1), r.getCommunity(valid, validated))
+	require.Zero(t, r.getCommunity(unknown, validated))
+}
+
+func TestRPKICommunityPath(t *testing.T) {
+	c := &Controller{config: &Configuration{
+		NeighborAddresses:      []net.IP{net.ParseIP("10.32.32.1")},
+		NeighborLocalAddresses: map[string]net.IP{"10.32.32.1": net.ParseIP("10.32.32.2")},
+		OriginAllowedCIDRs:     []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+		RPKICommunities:        rpkiCommunities{valid: 65000<<16 | 1, unknown: 65000<<16 | 2},
+	}}
+	getCommunities := func(route string, attrs routeAttributes) []uint32 {
+		paths, err := c.getPathRequest(route, attrs)
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		for _, attr := range paths[0][0].Attrs {
+			if a, ok := attr.(*bgp.PathAttributeCommunities); ok {
+				return a.Value
+			}
+		}
+		return nil
+	}
+
+	require.Equal(t, []uint32{65000<<16 | 1}, getCommunities("192.168.1.1", routeAttributes{}))
+	require.Equal(t, []uint32{65000<<16 | 2}, getCommunities("10.0.0.1", routeAttributes{}))
+
+	// The validation state community is advertised along with the draining community
+	drained := routeAttributes{draining: true, drainingCommunity: 65000<<16 | 100}
+	require.Equal(t, []uint32{65000<<16 | 100, 65000<<16 | 1}, getCommunities("192.168.1.1", drained))
+}
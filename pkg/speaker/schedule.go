@@ -0,0 +1,127 @@
+package speaker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// announceWindow is a daily time window during which routes are announced.
+// A window whose end is before its start spans midnight and belongs to the day it starts on.
+type announceWindow struct {
+	days       [7]bool
+	start, end time.Duration
+}
+
+// announceSchedule is a list of windows, announcements are active when any of them matches.
+// An empty schedule is always active.
+type announceSchedule []announceWindow
+
+// parseAnnounceSchedule parses a comma separated list of windows formatted as "[<day>[-<day>]] HH:MM-HH:MM",
+// e.g. "Mon-Fri 08:00-20:00,Sat 10:00-14:00,22:00-02:00". Windows without days apply to every day.
+func parseAnnounceSchedule(s string) (announceSchedule, error) {
+	var schedule announceSchedule
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		window := announceWindow{}
+		fields := strings.Fields(entry)
+		switch len(fields) {
+		case 1:
+			for i := range window.days {
+				window.days[i] = true
+			}
+		case 2:
+			if err := window.parseDays(fields[0]); err != nil {
+				return nil, fmt.Errorf("invalid announce schedule window %q: %w", entry, err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid announce schedule window %q", entry)
+		}
+
+		start, end, ok := strings.Cut(fields[len(fields)-1], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid announce schedule window %q: time range must be HH:MM-HH:MM", entry)
+		}
+		var err error
+		if window.start, err = parseClock(start); err != nil {
+			return nil, fmt.Errorf("invalid announce schedule window %q: %w", entry, err)
+		}
+		if window.end, err = parseClock(end); err != nil {
+			return nil, fmt.Errorf("invalid announce schedule window %q: %w", entry, err)
+		}
+		if window.start == window.end {
+			return nil, fmt.Errorf("invalid announce schedule window %q: start and end must differ", entry)
+		}
+		schedule = append(schedule, window)
+	}
+	return schedule, nil
+}
+
+// parseDays parses a single day ("Mon") or a range of days ("Mon-Fri", "Fri-Mon")
+func (w *announceWindow) parseDays(s string) error {
+	from, to, isRange := strings.Cut(strings.ToLower(s), "-")
+	first, ok := weekdayNames[from]
+	if !ok {
+		return fmt.Errorf("unknown day %q", from)
+	}
+	last := first
+	if isRange {
+		if last, ok = weekdayNames[to]; !ok {
+			return fmt.Errorf("unknown day %q", to)
+		}
+	}
+	for d := first; ; d = (d + 1) % 7 {
+		w.days[d] = true
+		if d == last {
+			return nil
+		}
+	}
+}
+
+// parseClock parses a HH:MM time of the day into the duration elapsed since midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, must be HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// isActive returns whether the given time falls in one of the windows of the schedule.
+// Window starts are inclusive and window ends are exclusive.
+func (s announceSchedule) isActive(now time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	sinceMidnight := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second + time.Duration(now.Nanosecond())
+	today, yesterday := now.Weekday(), (now.Weekday()+6)%7
+	for _, w := range s {
+		if w.start < w.end {
+			if w.days[today] && sinceMidnight >= w.start && sinceMidnight < w.end {
+				return true
+			}
+			continue
+		}
+		// The window spans midnight: it is either in its first part today or in its second part started yesterday
+		if (w.days[today] && sinceMidnight >= w.start) || (w.days[yesterday] && sinceMidnight < w.end) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package speaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnnounceSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		schedule    string
+		expectedLen int
+		expectError bool
+	}{
+		{name: "empty schedule", schedule: ""},
+		{name: "every day window", schedule: "08:00-20:00", expectedLen: 1},
+		{name: "multiple windows", schedule: "Mon-Fri 08:00-20:00, Sat 10:00-14:00", expectedLen: 2},
+		{name: "window spanning midnight", schedule: "22:00-02:00", expectedLen: 1},
+		{name: "unknown day", schedule: "Funday 08:00-20:00", expectError: true},
+		{name: "missing time range", schedule: "Mon", expectError: true},
+		{name: "invalid time", schedule: "08:00-25:00", expectError: true},
+		{name: "empty window", schedule: "08:00-08:00", expectError: true},
+		{name: "too many fields", schedule: "Mon Tue 08:00-20:00", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseAnnounceSchedule(tt.schedule)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, schedule, tt.expectedLen)
+		})
+	}
+}
+
+func TestAnnounceScheduleIsActive(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day int, hour, minute, second int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, second, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		now      time.Time
+		active   bool
+	}{
+		{name: "empty schedule is always active", schedule: "", now: at(1, 3, 0, 0), active: true},
+		{name: "window start is inclusive", schedule: "Mon-Fri 08:00-20:00", now: at(1, 8, 0, 0), active: true},
+		{name: "just before window start", schedule: "Mon-Fri 08:00-20:00", now: at(1, 7, 59, 59), active: false},
+		{name: "just before window end", schedule: "Mon-Fri 08:00-20:00", now: at(5, 19, 59, 59), active: true},
+		{name: "window end is exclusive", schedule: "Mon-Fri 08:00-20:00", now: at(5, 20, 0, 0), active: false},
+		{name: "day outside of the window", schedule: "Mon-Fri 08:00-20:00", now: at(6, 12, 0, 0), active: false},
+		{name: "day range wrapping the week", schedule: "Sat-Mon 08:00-20:00", now: at(7, 12, 0, 0), active: true},
+		{name: "second matching window", schedule: "Mon 08:00-09:00,Sat 10:00-14:00", now: at(6, 10, 0, 0), active: true},
+		{name: "first part of a window spanning midnight", schedule: "Mon 22:00-02:00", now: at(1, 23, 30, 0), active: true},
+		{name: "second part of a window spanning midnight", schedule: "Mon 22:00-02:00", now: at(2, 1, 59, 59), active: true},
+		{name: "end of a window spanning midnight", schedule: "Mon 22:00-02:00", now: at(2, 2, 0, 0), active: false},
+		{name: "window spanning midnight started on another day", schedule: "Mon 22:00-02:00", now: at(1, 1, 0, 0), active: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseAnnounceSchedule(tt.schedule)
+			require.NoError(t, err)
+			require.Equal(t, tt.active, schedule.isActive(tt.now))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package speaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	gobgp "github.com/osrg/gobgp/v4/pkg/server"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+// sessionTracker keeps track of the state of the BGP session with every neighbor, and of the neighbors the routes
+// must be advertised again to. It is safe for concurrent use.
+type sessionTracker struct {
+	mutex       sync.RWMutex
+	states      map[string]bgp.FSMState
+	readvertise set.Set[string]
+}
+
+func newSessionTracker() *sessionTracker {
+	return &sessionTracker{states: make(map[string]bgp.FSMState), readvertise: set.New[string]()}
+}
+
+// Update records the new session state of a neighbor. It returns the previous state of the session
+// and whether the session has just been established.
+func (t *sessionTracker) Update(neighbor string, state bgp.FSMState) (previous bgp.FSMState, established bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	previous = t.states[neighbor]
+	t.states[neighbor] = state
+	return previous, state == bgp.BGP_FSM_ESTABLISHED && previous != bgp.BGP_FSM_ESTABLISHED
+}
+
+// State returns the session state of a neighbor, sessions never seen are considered idle
+func (t *sessionTracker) State(neighbor string) bgp.FSMState {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.states[neighbor]
+}
+
+// RequestReadvertise records that the routes must be advertised again to a neighbor
+func (t *sessionTracker) RequestReadvertise(neighbor string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.readvertise.Insert(neighbor)
+}
+
+// TakeReadvertise returns the sorted neighbors the routes must be advertised again to, and forgets them
+func (t *sessionTracker) TakeReadvertise() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	neighbors := t.readvertise.SortedList()
+	t.readvertise.Clear()
+	return neighbors
+}
+
+// watchSessions watches the state of the BGP sessions until the context is canceled
+func (c *Controller) watchSessions(ctx context.Context) error {
+	return c.config.BgpServer.WatchEvent(ctx, gobgp.WatchEventMessageCallbacks{
+		OnPeerUpdate: func(ev *apiutil.WatchEventMessage_PeerEvent, _ time.Time) {
+			if ev.Type != apiutil.PEER_EVENT_STATE {
+				return
+			}
+			neighbor := ev.Peer.Conf.NeighborAddress.String()
+			if ev.Peer.State.SessionState == bgp.BGP_FSM_ESTABLISHED {
+				c.checkNeighborAs(neighbor, ev.Peer.Conf.PeerASN, ev.Peer.State.PeerASN)
+			}
+			c.handleSessionState(neighbor, ev.Peer.State.SessionState)
+		},
+	}, gobgp.WatchPeer())
+}
+
+// handleSessionState records the new session state of a neighbor. When the session with a neighbor
+// is established again, the routes are reconciled so that none is silently lost after a flap, and, with
+// --readvertise-on-session-established, the routes announced to this neighbor are advertised again by
+// this reconciliation so that it does not keep stale state from a previous session.
+func (c *Controller) handleSessionState(neighbor string, state bgp.FSMState) {
+	previous, established := c.sessions.Update(neighbor, state)
+	if previous == state {
+		return
+	}
+	klog.Infof("BGP session with neighbor %s changed from %s to %s", neighbor, previous, state)
+	if !established {
+		return
+	}
+
+	if c.config.ReadvertiseOnEstablished {
+		c.sessions.RequestReadvertise(neighbor)
+	}
+	c.requestReconcile()
+}
+
+// readvertiseEstablishedSessions advertises again the announced routes to the neighbors whose session was
+// established again since the previous reconciliation, unless it went down since then.
+//
+// It is called at the end of each reconciliation rather than when the session is established, so that the routes
+// are never advertised again concurrently with their announcement or withdrawal.
+func (c *Controller) readvertiseEstablishedSessions() {
+	if !c.config.ReadvertiseOnEstablished {
+		return
+	}
+	for _, neighbor := range c.sessions.TakeReadvertise() {
+		if c.sessions.State(neighbor) != bgp.BGP_FSM_ESTABLISHED {
+			continue
+		}
+		if err := c.readvertiseRoutes(net.ParseIP(neighbor)); err != nil {
+			klog.Errorf("failed to advertise routes again to neighbor %s: %v", neighbor, err)
+		}
+	}
+}
+
+// readvertiseRoutes advertises again all the routes announced to a neighbor. A route failing to be advertised
+// again does not prevent the others from being advertised, the errors of all the routes being returned.
+func (c *Controller) readvertiseRoutes(neighbor net.IP) error {
+	routes := c.getNeighborRoutes(neighbor)
+	klog.Infof("advertising %d routes again to neighbor %s", len(routes), neighbor)
+	var errs []error
+	for route, attrs := range routes {
+		if err := c.readvertiseRoute(route, neighbor, attrs); err != nil {
+			errs = append(errs, fmt.Errorf("failed to advertise route %s again: %w", route, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readvertiseRoute advertises again a route announced to a neighbor
+func (c *Controller) readvertiseRoute(route string, neighbor net.IP, attrs routeAttributes) error {
+	prefix, err := parsePrefix(route)
+	if err != nil {
+		return err
+	}
+	path, err := c.getNeighborPath(prefix, neighbor, attrs)
+	if err != nil {
+		return err
+	}
+	return c.getAnnouncer().AnnouncePaths([]*apiutil.Path{path})
+}
+
+// getNeighborRoutes returns the announced routes which are advertised to a neighbor
+func (c *Controller) getNeighborRoutes(neighbor net.IP) prefixAttributes {
+	routes := c.announced.Attributes()
+	for route := range routes {
+		if !slices.ContainsFunc(c.getRouteNeighbors(route), neighbor.Equal) {
+			delete(routes, route)
+		}
+	}
+	return routes
+}
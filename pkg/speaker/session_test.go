@@ -0,0 +1,121 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTrackerUpdate(t *testing.T) {
+	tracker := newSessionTracker()
+	require.Equal(t, bgp.BGP_FSM_IDLE, tracker.State("10.32.32.1"))
+
+	steps := []struct {
+		state       bgp.FSMState
+		previous    bgp.FSMState
+		established bool
+	}{
+		{state: bgp.BGP_FSM_ACTIVE, previous: bgp.BGP_FSM_IDLE},
+		{state: bgp.BGP_FSM_ESTABLISHED, previous: bgp.BGP_FSM_ACTIVE, established: true},
+		{state: bgp.BGP_FSM_ESTABLISHED, previous: bgp.BGP_FSM_ESTABLISHED},
+		{state: bgp.BGP_FSM_IDLE, previous: bgp.BGP_FSM_ESTABLISHED},
+		{state: bgp.BGP_FSM_ESTABLISHED, previous: bgp.BGP_FSM_IDLE, established: true},
+	}
+	for _, step := range steps {
+		previous, established := tracker.Update("10.32.32.1", step.state)
+		require.Equal(t, step.previous, previous)
+		require.Equal(t, step.established, established)
+		require.Equal(t, step.state, tracker.State("10.32.32.1"))
+	}
+
+	// sessions are tracked per neighbor
+	require.Equal(t, bgp.BGP_FSM_IDLE, tracker.State("10.32.32.2"))
+}
+
+func TestGetNeighborRoutes(t *testing.T) {
+	ipv4Neighbor, ipv6Neighbor := net.ParseIP("10.32.32.1"), net.ParseIP("fd00::1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:     []net.IP{ipv4Neighbor},
+			NeighborIPv6Addresses: []net.IP{ipv6Neighbor},
+		},
+		announced: newAnnouncedStore(),
+	}
+	c.announced.Add("192.168.1.1/32", routeAttributes{hasMED: true, med: 100})
+	c.announced.Add("2001:db8::1/128", routeAttributes{})
+
+	require.Equal(t, prefixAttributes{"192.168.1.1/32": {hasMED: true, med: 100}}, c.getNeighborRoutes(ipv4Neighbor))
+	require.Equal(t, prefixAttributes{"2001:db8::1/128": {}}, c.getNeighborRoutes(ipv6Neighbor))
+	require.Empty(t, c.getNeighborRoutes(net.ParseIP("10.32.32.2")))
+}
+
+func TestReadvertiseOnSessionEstablished(t *testing.T) {
+	s := newTestBgpServer(t)
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:                s,
+			NeighborAddresses:        []net.IP{neighbor},
+			NeighborLocalAddresses:   map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			ReadvertiseOnEstablished: true,
+		},
+		announced:   newAnnouncedStore(),
+		sessions:    newSessionTracker(),
+		reconcileCh: make(chan struct{}, 1),
+	}
+
+	listPrefixes := func() []string {
+		return listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC)
+	}
+
+	require.NoError(t, c.addRoutes([]string{"192.168.1.1"}, nil).err())
+	require.Equal(t, []string{"192.168.1.1/32"}, listPrefixes())
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_ESTABLISHED)
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+
+	// lose the route without the speaker knowing about it, as a neighbor would after a session reset
+	paths, err := c.getPathRequest("192.168.1.1", routeAttributes{})
+	require.NoError(t, err)
+	require.NoError(t, s.DeletePath(apiutil.DeletePathRequest{Paths: paths[0]}))
+	require.Empty(t, listPrefixes())
+
+	// routes are not advertised again while the session is down
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_IDLE)
+	require.Empty(t, listPrefixes())
+	require.Empty(t, c.reconcileCh)
+
+	// routes are reconciled once the session is established again, and advertised again by the reconciliation only
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_ESTABLISHED)
+	require.Empty(t, listPrefixes())
+	require.Len(t, c.reconcileCh, 1)
+	<-c.reconcileCh
+	c.readvertiseEstablishedSessions()
+	require.Equal(t, []string{"192.168.1.1/32"}, listPrefixes())
+
+	// routes are not advertised again when the session went down again before the reconciliation
+	require.NoError(t, s.DeletePath(apiutil.DeletePathRequest{Paths: paths[0]}))
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_IDLE)
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_ESTABLISHED)
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_IDLE)
+	<-c.reconcileCh
+	c.readvertiseEstablishedSessions()
+	require.Empty(t, listPrefixes())
+	require.Empty(t, c.sessions.TakeReadvertise())
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_ESTABLISHED)
+	<-c.reconcileCh
+	c.readvertiseEstablishedSessions()
+	require.Equal(t, []string{"192.168.1.1/32"}, listPrefixes())
+
+	// without re-advertisement, routes are only reconciled after a flap
+	c.config.ReadvertiseOnEstablished = false
+	require.NoError(t, s.DeletePath(apiutil.DeletePathRequest{Paths: paths[0]}))
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_IDLE)
+	c.handleSessionState(neighbor.String(), bgp.BGP_FSM_ESTABLISHED)
+	c.readvertiseEstablishedSessions()
+	require.Empty(t, listPrefixes())
+	require.Len(t, c.reconcileCh, 1)
+}
@@ -0,0 +1,44 @@
+package speaker
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+// defaultGracefulShutdownCommunity is the well-known GRACEFUL_SHUTDOWN community 65535:0 of RFC 8326
+const defaultGracefulShutdownCommunity = "65535:0"
+
+// gracefulShutdown withdraws the announced routes in two phases when the speaker is stopped: the routes are first
+// announced again with the graceful shutdown community so that the neighbors lower their preference and move the
+// traffic to other paths, and are withdrawn once the graceful shutdown time elapsed.
+// Nothing is done if the graceful shutdown time is zero, the routes being then kept by graceful restart, if enabled,
+// or withdrawn by the neighbors when the session goes down.
+func (c *Controller) gracefulShutdown() {
+	if c.config.GracefulShutdownTime == 0 {
+		return
+	}
+
+	attrs := c.announced.Attributes()
+	if len(attrs) == 0 {
+		return
+	}
+	routes := set.New[string]()
+	for prefix, a := range attrs {
+		a.gracefulShutdownCommunity = c.config.GracefulShutdownCommunity
+		attrs[prefix] = a
+		routes.Insert(prefix)
+	}
+
+	klog.Infof("announcing %d routes with the graceful shutdown community, withdrawing them in %s", routes.Len(), c.config.GracefulShutdownTime)
+	if err := c.announceAndWithdraw(routes, set.New[string](), attrs, newAnnounceQueue(0)).err(); err != nil {
+		klog.Errorf("failed to announce routes with the graceful shutdown community: %v", err)
+	}
+	time.Sleep(c.config.GracefulShutdownTime)
+
+	klog.Infof("withdrawing %d routes", routes.Len())
+	if failed := c.announceAndWithdraw(set.New[string](), routes, nil, newAnnounceQueue(0)).failed(); len(failed) != 0 {
+		klog.Errorf("failed to withdraw routes %v on shutdown", failed)
+	}
+}
@@ -0,0 +1,77 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	s := newTestBgpServer(t)
+	neighbor := net.ParseIP("10.32.32.1")
+	community, err := parseCommunity(defaultGracefulShutdownCommunity)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0xFFFF0000), community)
+
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:                 s,
+			NeighborAddresses:         []net.IP{neighbor},
+			NeighborLocalAddresses:    map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			GracefulShutdownCommunity: community,
+		},
+		announced: newAnnouncedStore(),
+	}
+
+	// getCommunities returns the communities of the routes announced by the BGP server
+	getCommunities := func() map[string][]uint32 {
+		communities := make(map[string][]uint32)
+		require.NoError(t, s.ListPath(apiutil.ListPathRequest{
+			TableType: api.TableType_TABLE_TYPE_GLOBAL,
+			Family:    bgp.RF_IPv4_UC,
+		}, func(prefix bgp.NLRI, paths []*apiutil.Path) {
+			communities[prefix.String()] = nil
+			for _, attr := range paths[0].Attrs {
+				if a, ok := attr.(*bgp.PathAttributeCommunities); ok {
+					communities[prefix.String()] = a.Value
+				}
+			}
+		}))
+		return communities
+	}
+
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32", "192.168.1.2/32")}
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.Equal(t, map[string][]uint32{"192.168.1.1/32": nil, "192.168.1.2/32": nil}, getCommunities())
+
+	// Routes are kept on shutdown without graceful shutdown time
+	c.gracefulShutdown()
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, c.announced.List())
+	require.Len(t, getCommunities(), 2)
+
+	// Routes are first announced with the graceful shutdown community, then withdrawn once the time elapsed
+	c.config.GracefulShutdownTime = time.Second
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		c.gracefulShutdown()
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		communities := getCommunities()
+		return len(communities) == 2 &&
+			len(communities["192.168.1.1/32"]) == 1 && communities["192.168.1.1/32"][0] == community &&
+			len(communities["192.168.1.2/32"]) == 1 && communities["192.168.1.2/32"][0] == community
+	}, 5*time.Second, 10*time.Millisecond)
+
+	<-done
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+	require.Empty(t, c.announced.List())
+	require.Empty(t, getCommunities())
+}
@@ -0,0 +1,105 @@
+package speaker
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+// routesSnapshot is a point in time view of the routes the speaker should be announcing
+// and of the routes it is actually announcing
+type routesSnapshot struct {
+	time             time.Time
+	suppressedReason string
+	desired          []string
+	announced        []string
+	// missing are the desired routes which are not announced, or announced with other attributes
+	missing []string
+	// stale are the announced routes which are not desired anymore
+	stale []string
+}
+
+// newRoutesSnapshot compares the desired routes with the routes of the announced store
+func newRoutesSnapshot(now time.Time, suppressedReason string, desired prefixMap, attrs prefixAttributes, announced *announcedStore) *routesSnapshot {
+	desiredSet, missing, stale := set.New[string](), set.New[string](), set.New[string]()
+	for _, afi := range []api.Family_Afi{api.Family_AFI_IP, api.Family_AFI_IP6} {
+		desiredSet = desiredSet.Union(desired[afi])
+		toAdd, toDel := announced.Diff(afi, desired[afi], attrs)
+		missing, stale = missing.Union(toAdd), stale.Union(toDel)
+	}
+
+	return &routesSnapshot{
+		time:             now,
+		suppressedReason: suppressedReason,
+		desired:          desiredSet.SortedList(),
+		announced:        announced.List(),
+		missing:          missing.SortedList(),
+		stale:            stale.SortedList(),
+	}
+}
+
+// String formats the snapshot in a human readable way
+func (s *routesSnapshot) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "routes snapshot at %s\n", s.time.Format(time.RFC3339))
+	if s.suppressedReason != "" {
+		fmt.Fprintf(&b, "announcements suppressed: %s\n", s.suppressedReason)
+	}
+	fmt.Fprintf(&b, "desired (%d): %s\n", len(s.desired), strings.Join(s.desired, ", "))
+	fmt.Fprintf(&b, "announced (%d): %s\n", len(s.announced), strings.Join(s.announced, ", "))
+	fmt.Fprintf(&b, "missing (%d): %s\n", len(s.missing), strings.Join(s.missing, ", "))
+	fmt.Fprintf(&b, "stale (%d): %s\n", len(s.stale), strings.Join(s.stale, ", "))
+	return b.String()
+}
+
+// getRoutesSnapshot returns a snapshot of the desired and announced routes
+func (c *Controller) getRoutesSnapshot(now time.Time) (*routesSnapshot, error) {
+	desired, attrs, err := c.getDesiredRoutes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the desired routes: %w", err)
+	}
+	return newRoutesSnapshot(now, c.announcementSuppressedReason(now), desired, attrs, c.announced), nil
+}
+
+// dumpRoutesSnapshot logs a snapshot of the desired and announced routes, and writes it to the
+// snapshot file if one is configured
+func (c *Controller) dumpRoutesSnapshot() error {
+	snapshot, err := c.getRoutesSnapshot(time.Now())
+	if err != nil {
+		return err
+	}
+
+	klog.Info(snapshot.String())
+	if c.config.RoutesSnapshotFile == "" {
+		return nil
+	}
+	if err = os.WriteFile(c.config.RoutesSnapshotFile, []byte(snapshot.String()), 0o600); err != nil {
+		return fmt.Errorf("failed to write routes snapshot to %s: %w", c.config.RoutesSnapshotFile, err)
+	}
+	return nil
+}
+
+// handleSnapshotSignal dumps a snapshot of the routes every time SIGUSR1 is received, until stopCh is closed
+func (c *Controller) handleSnapshotSignal(stopCh <-chan struct{}) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-sigCh:
+			if err := c.dumpRoutesSnapshot(); err != nil {
+				klog.Errorf("failed to dump routes snapshot: %v", err)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package speaker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	kubeovnlister "github.com/kubeovn/kube-ovn/pkg/client/listers/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestRoutesSnapshot(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw")
+
+	bgp := map[string]string{util.BgpAnnotation: "true"}
+	eips := []*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-announced", "192.168.1.1", "", true, bgp),
+		newTestEIP("eip-missing", "192.168.1.2", "2001:db8::2", true, bgp),
+		newTestEIP("eip-other-gw", "192.168.1.3", "", true, bgp),
+	}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, eip := range eips {
+		eip.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw"}
+		if eip.Name == "eip-other-gw" {
+			eip.Labels[util.VpcNatGatewayNameLabel] = "other-gw"
+		}
+		require.NoError(t, eipIndexer.Add(eip))
+	}
+	gwIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, gwIndexer.Add(&kubeovnv1.VpcNatGateway{ObjectMeta: metav1.ObjectMeta{Name: "gw"}}))
+
+	snapshotFile := filepath.Join(t.TempDir(), "routes")
+	c := &Controller{
+		config:           &Configuration{NatGwMode: true, RoutesSnapshotFile: snapshotFile},
+		eipLister:        kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:    newTestSubnetLister(t),
+		natgatewayLister: kubeovnlister.NewVpcNatGatewayLister(gwIndexer),
+		announced:        newAnnouncedStore(),
+	}
+	c.announced.Add("192.168.1.1/32", routeAttributes{})
+	c.announced.Add("2001:db8::2/128", routeAttributes{hasMED: true, med: 10})
+	c.announced.Add("192.168.1.4/32", routeAttributes{})
+
+	snapshot, err := c.getRoutesSnapshot(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Empty(t, snapshot.suppressedReason)
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32", "2001:db8::2/128"}, snapshot.desired)
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.4/32", "2001:db8::2/128"}, snapshot.announced)
+	require.Equal(t, []string{"192.168.1.2/32", "2001:db8::2/128"}, snapshot.missing)
+	require.Equal(t, []string{"192.168.1.4/32"}, snapshot.stale)
+	require.Equal(t, `routes snapshot at 2024-01-01T12:00:00Z
+desired (3): 192.168.1.1/32, 192.168.1.2/32, 2001:db8::2/128
+announced (3): 192.168.1.1/32, 192.168.1.4/32, 2001:db8::2/128
+missing (2): 192.168.1.2/32, 2001:db8::2/128
+stale (1): 192.168.1.4/32
+`, snapshot.String())
+
+	require.NoError(t, c.dumpRoutesSnapshot())
+	content, err := os.ReadFile(snapshotFile)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "stale (1): 192.168.1.4/32\n")
+}
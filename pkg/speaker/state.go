@@ -0,0 +1,166 @@
+package speaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"k8s.io/klog/v2"
+)
+
+// announcedState is the content of the state file: the routes announced by the speaker
+type announcedState struct {
+	Announced []stateRoute `json:"announced"`
+}
+
+// stateRoute is a route of the state file, along with the attributes it is announced with
+type stateRoute struct {
+	Prefix                    string      `json:"prefix"`
+	MED                       *uint32     `json:"med,omitempty"`
+	LocalPref                 *uint32     `json:"localPref,omitempty"`
+	LinkBandwidth             float32     `json:"linkBandwidth,omitempty"`
+	Batch                     string      `json:"batch,omitempty"`
+	Instance                  bgpInstance `json:"instance,omitempty"`
+	Neighbors                 string      `json:"neighbors,omitempty"`
+	DrainingCommunity         uint32      `json:"drainingCommunity,omitempty"`
+	Draining                  bool        `json:"draining,omitempty"`
+	GracefulShutdownCommunity uint32      `json:"gracefulShutdownCommunity,omitempty"`
+	DegradedCommunity         uint32      `json:"degradedCommunity,omitempty"`
+}
+
+func newStateRoute(prefix string, attrs routeAttributes) stateRoute {
+	route := stateRoute{
+		Prefix:                    prefix,
+		LinkBandwidth:             attrs.linkBandwidth,
+		Batch:                     attrs.batch,
+		Instance:                  attrs.instance,
+		Neighbors:                 attrs.neighbors,
+		DrainingCommunity:         attrs.drainingCommunity,
+		Draining:                  attrs.draining,
+		GracefulShutdownCommunity: attrs.gracefulShutdownCommunity,
+		DegradedCommunity:         attrs.degradedCommunity,
+	}
+	if attrs.hasMED {
+		route.MED = &attrs.med
+	}
+	if attrs.hasLocalPref {
+		route.LocalPref = &attrs.localPref
+	}
+	return route
+}
+
+// attributes returns the attributes the route is announced with
+func (r stateRoute) attributes() routeAttributes {
+	attrs := routeAttributes{
+		linkBandwidth:             r.LinkBandwidth,
+		batch:                     r.Batch,
+		instance:                  r.Instance,
+		neighbors:                 r.Neighbors,
+		drainingCommunity:         r.DrainingCommunity,
+		draining:                  r.Draining,
+		gracefulShutdownCommunity: r.GracefulShutdownCommunity,
+		degradedCommunity:         r.DegradedCommunity,
+	}
+	if r.MED != nil {
+		attrs.hasMED, attrs.med = true, *r.MED
+	}
+	if r.LocalPref != nil {
+		attrs.hasLocalPref, attrs.localPref = true, *r.LocalPref
+	}
+	return attrs
+}
+
+// writeAnnouncedState writes the announced routes and their attributes to a state file. The file is replaced
+// atomically so that a crash while writing it never leaves it truncated.
+func writeAnnouncedState(path string, announced prefixAttributes) error {
+	state := announcedState{Announced: make([]stateRoute, 0, len(announced))}
+	for _, prefix := range slices.Sorted(maps.Keys(announced)) {
+		state.Announced = append(state.Announced, newStateRoute(prefix, announced[prefix]))
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal announced state: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", tmp, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace state file %s: %w", path, err)
+	}
+	return nil
+}
+
+// readAnnouncedState reads the announced routes and their attributes from a state file, none if it does not
+// exist yet
+func readAnnouncedState(path string) (prefixAttributes, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+	var state announcedState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	announced := make(prefixAttributes, len(state.Announced))
+	for _, route := range state.Announced {
+		announced[route.Prefix] = route.attributes()
+	}
+	return announced, nil
+}
+
+// saveAnnouncedState saves the announced routes to the state file when they or their attributes changed since
+// they were last saved. It is called at the end of each reconciliation.
+func (c *Controller) saveAnnouncedState() {
+	if c.config.StateFile == "" {
+		return
+	}
+
+	announced := c.announced.Attributes()
+	if maps.Equal(announced, c.savedState) {
+		return
+	}
+	if err := writeAnnouncedState(c.config.StateFile, announced); err != nil {
+		klog.Error(err)
+		return
+	}
+	c.savedState = announced
+}
+
+// restoreAnnouncedState announces again the routes of the state file with their attributes at startup, before
+// the informer caches sync, so that the routes announced before a restart are back as soon as possible. The
+// restored routes go through the export filters and the route events like any other, and are not announced
+// while the announcements are suppressed. The invalid prefixes are dropped, and the routes not expected anymore
+// are withdrawn by the first reconciliation once the caches synced.
+func (c *Controller) restoreAnnouncedState() {
+	if c.config.StateFile == "" {
+		return
+	}
+
+	routes, err := readAnnouncedState(c.config.StateFile)
+	if err != nil {
+		klog.Errorf("failed to restore announced routes: %v", err)
+		return
+	}
+	c.savedState = routes
+
+	expectedPrefixes := make(prefixMap)
+	attrs := make(prefixAttributes, len(routes))
+	for route, routeAttrs := range routes {
+		if prefix := addExpectedPrefix(route, expectedPrefixes); prefix != "" {
+			attrs[prefix] = routeAttrs
+		}
+	}
+	if len(expectedPrefixes) == 0 {
+		return
+	}
+	klog.Infof("restoring %d routes announced before the restart from %s", len(attrs), c.config.StateFile)
+	c.reconcileRoutes(expectedPrefixes, attrs)
+}
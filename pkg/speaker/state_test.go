@@ -0,0 +1,104 @@
+package speaker
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestAnnouncedStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// No route is restored before the state file is written
+	announced, err := readAnnouncedState(path)
+	require.NoError(t, err)
+	require.Empty(t, announced)
+
+	routes := prefixAttributes{
+		"192.168.1.1/32": {hasMED: true, med: 0, instance: bgpInstanceSecondary, neighbors: "10.32.32.1"},
+		"2001:db8::1/128": {
+			hasLocalPref:      true,
+			localPref:         200,
+			linkBandwidth:     125000,
+			batch:             "batch1",
+			drainingCommunity: 65000<<16 | 1,
+			draining:          true,
+			degradedCommunity: 65000<<16 | 2,
+		},
+		"192.168.1.2/32": {},
+	}
+	require.NoError(t, writeAnnouncedState(path, routes))
+	announced, err = readAnnouncedState(path)
+	require.NoError(t, err)
+	require.Equal(t, routes, announced)
+
+	// The state file is replaced, no temporary file being left behind
+	require.NoError(t, writeAnnouncedState(path, nil))
+	announced, err = readAnnouncedState(path)
+	require.NoError(t, err)
+	require.Empty(t, announced)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	require.NoError(t, os.WriteFile(path, []byte("192.168.1.1/32"), 0o600))
+	_, err = readAnnouncedState(path)
+	require.ErrorContains(t, err, "failed to parse state file")
+}
+
+func TestRestoreAnnouncedState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			StateFile:              path,
+		},
+		announced:   newAnnouncedStore(),
+		announcer:   a,
+		reconcileCh: make(chan struct{}, 1),
+	}
+
+	// Nothing is restored without state file
+	c.restoreAnnouncedState()
+	require.Empty(t, c.announced.List())
+	require.Empty(t, a.calls)
+
+	// The valid prefixes of the state file are announced again with their attributes
+	attrs := routeAttributes{hasMED: true, med: 100, hasLocalPref: true, localPref: 200}
+	require.NoError(t, writeAnnouncedState(path, prefixAttributes{
+		"192.168.1.1/32": {},
+		"192.168.1.2/32": attrs,
+		"invalid":        {},
+	}))
+	c.restoreAnnouncedState()
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, c.announced.List())
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, a.announced.SortedList())
+	require.Equal(t, attrs, c.announced.Attributes()["192.168.1.2/32"])
+
+	// The restored routes announced with the expected attributes are not announced again by the next
+	// reconciliation, the routes not expected anymore are withdrawn and the state file updated
+	a.calls = nil
+	c.reconcileRoutes(prefixMap{api.Family_AFI_IP: set.New("192.168.1.2/32", "192.168.1.3/32")}, prefixAttributes{"192.168.1.2/32": attrs})
+	c.saveAnnouncedState()
+	require.Equal(t, []string{"192.168.1.2/32", "192.168.1.3/32"}, a.announced.SortedList())
+	require.Equal(t, []fakeAnnouncerCall{
+		{withdraw: false, prefixes: []string{"192.168.1.3/32"}},
+		{withdraw: true, prefixes: []string{"192.168.1.1/32"}},
+	}, a.calls)
+	announced, err := readAnnouncedState(path)
+	require.NoError(t, err)
+	require.Equal(t, prefixAttributes{"192.168.1.2/32": attrs, "192.168.1.3/32": {}}, announced)
+
+	// The state file is not written again while the announced routes do not change
+	require.NoError(t, os.Remove(path))
+	c.saveAnnouncedState()
+	require.NoFileExists(t, path)
+}
@@ -0,0 +1,90 @@
+package speaker
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/vishvananda/netlink"
+)
+
+// addStaticPrefixes adds the prefixes configured to always be announced, the node addresses announced and the SRv6
+// locator of the node, to the prefixes we should be announcing
+func (c *Controller) addStaticPrefixes(expectedPrefixes prefixMap) {
+	for _, prefix := range c.config.StaticAnnounceCIDRs {
+		addExpectedPrefix(prefix.String(), expectedPrefixes)
+	}
+	for _, addr := range c.config.NodeAnnounceAddresses {
+		addExpectedPrefix(addr.String(), expectedPrefixes)
+	}
+	if c.config.SRv6Locator.IsValid() {
+		addExpectedPrefix(c.config.SRv6Locator.String(), expectedPrefixes)
+	}
+}
+
+// getNodeAnnounceAddresses returns the addresses of the node announced as host routes, the given addresses
+// or the addresses of the loopback interface if none is given
+func getNodeAnnounceAddresses(addresses []net.IP) ([]netip.Addr, error) {
+	if len(addresses) == 0 {
+		link, err := netlink.LinkByName("lo")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get loopback interface: %w", err)
+		}
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses of loopback interface: %w", err)
+		}
+		for _, addr := range addrs {
+			addresses = append(addresses, addr.IP)
+		}
+	}
+	return filterNodeAnnounceAddresses(addresses), nil
+}
+
+// filterNodeAnnounceAddresses returns the addresses which can be announced, excluding the loopback, link-local
+// and unspecified addresses which are only meaningful to the node itself
+func filterNodeAnnounceAddresses(addresses []net.IP) []netip.Addr {
+	var result []netip.Addr
+	for _, ip := range addresses {
+		if !ip.IsGlobalUnicast() {
+			continue
+		}
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			result = append(result, addr.Unmap())
+		}
+	}
+	return result
+}
+
+// validateStaticAnnounceCIDRs checks that the prefixes configured to always be announced can be announced,
+// a default route must not be announced unconditionally
+func validateStaticAnnounceCIDRs(cidrs []netip.Prefix) error {
+	for _, cidr := range cidrs {
+		if cidr.Bits() == 0 {
+			return fmt.Errorf("invalid static-announce-cidrs: %s is a default route", cidr)
+		}
+	}
+	return nil
+}
+
+// parseSRv6Locator parses the SRv6 locator prefix of the node announced with --announce-srv6-locator, an IPv6 prefix
+// without host bits
+func parseSRv6Locator(locator string) (netip.Prefix, error) {
+	if locator == "" {
+		return netip.Prefix{}, nil
+	}
+	prefix, err := netip.ParsePrefix(locator)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid announce-srv6-locator: %w", err)
+	}
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("invalid announce-srv6-locator: %s is not an IPv6 prefix", locator)
+	}
+	if prefix.Bits() == 0 {
+		return netip.Prefix{}, fmt.Errorf("invalid announce-srv6-locator: %s is a default route", locator)
+	}
+	if prefix != prefix.Masked() {
+		return netip.Prefix{}, fmt.Errorf("invalid announce-srv6-locator: %s has host bits set, the locator is %s", locator, prefix.Masked())
+	}
+	return prefix, nil
+}
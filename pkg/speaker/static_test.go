@@ -0,0 +1,118 @@
+package speaker
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestValidateStaticAnnounceCIDRs(t *testing.T) {
+	require.NoError(t, validateStaticAnnounceCIDRs(nil))
+	require.NoError(t, validateStaticAnnounceCIDRs([]netip.Prefix{netip.MustParsePrefix("10.0.0.10/32"), netip.MustParsePrefix("fd00::/64")}))
+	require.Error(t, validateStaticAnnounceCIDRs([]netip.Prefix{netip.MustParsePrefix("10.0.0.10/32"), netip.MustParsePrefix("0.0.0.0/0")}))
+	require.Error(t, validateStaticAnnounceCIDRs([]netip.Prefix{netip.MustParsePrefix("::/0")}))
+}
+
+func TestAddStaticPrefixes(t *testing.T) {
+	c := &Controller{config: &Configuration{StaticAnnounceCIDRs: ipNetsToPrefixes([]net.IPNet{
+		{IP: net.ParseIP("10.0.0.10"), Mask: net.CIDRMask(32, 32)},
+		{IP: net.ParseIP("10.1.0.1"), Mask: net.CIDRMask(24, 32)},
+		{IP: net.ParseIP("fd00::"), Mask: net.CIDRMask(64, 128)},
+	})}}
+
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32")}
+	c.addStaticPrefixes(expected)
+	require.Equal(t, prefixMap{
+		api.Family_AFI_IP:  set.New("192.168.1.1/32", "10.0.0.10/32", "10.1.0.0/24"),
+		api.Family_AFI_IP6: set.New("fd00::/64"),
+	}, expected)
+}
+
+func TestReconcileRoutesStaticPrefixes(t *testing.T) {
+	s := newTestBgpServer(t)
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:              s,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			StaticAnnounceCIDRs:    []netip.Prefix{netip.MustParsePrefix("10.0.0.10/32")},
+		},
+		announced: newAnnouncedStore(),
+	}
+
+	// Static prefixes are announced along with the other routes
+	c.reconcileRoutes(prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32")}, nil)
+	require.Equal(t, []string{"10.0.0.10/32", "192.168.1.1/32"}, c.announced.List())
+
+	// and kept announced when no other route is expected
+	c.reconcileRoutes(make(prefixMap), nil)
+	require.Equal(t, []string{"10.0.0.10/32"}, c.announced.List())
+	require.Equal(t, []string{"10.0.0.10/32"}, listTestBgpServerPrefixes(t, s, bgp.RF_IPv4_UC))
+}
+
+func TestGetNodeAnnounceAddresses(t *testing.T) {
+	addrs, err := getNodeAnnounceAddresses([]net.IP{
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("127.0.0.1"),
+		net.ParseIP("::1"),
+		net.ParseIP("fe80::1"),
+		net.ParseIP("0.0.0.0"),
+		net.ParseIP("fd00::1"),
+		net.ParseIP("::ffff:10.0.0.2"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("fd00::1"), netip.MustParseAddr("10.0.0.2")}, addrs)
+}
+
+func TestAddNodeAnnounceAddresses(t *testing.T) {
+	c := &Controller{config: &Configuration{
+		StaticAnnounceCIDRs:   []netip.Prefix{netip.MustParsePrefix("10.1.0.0/24")},
+		NodeAnnounceAddresses: []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("fd00::1")},
+	}}
+
+	// Node addresses are announced as host routes along with the other routes
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32")}
+	c.addStaticPrefixes(expected)
+	require.Equal(t, prefixMap{
+		api.Family_AFI_IP:  set.New("192.168.1.1/32", "10.1.0.0/24", "10.0.0.1/32"),
+		api.Family_AFI_IP6: set.New("fd00::1/128"),
+	}, expected)
+}
+
+func TestParseSRv6Locator(t *testing.T) {
+	locator, err := parseSRv6Locator("")
+	require.NoError(t, err)
+	require.False(t, locator.IsValid())
+
+	locator, err = parseSRv6Locator("fd00:0:1::/48")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("fd00:0:1::/48"), locator)
+
+	for _, invalid := range []string{"fd00:0:1::", "10.0.0.0/24", "::ffff:10.0.0.0/120", "::/0", "fd00:0:1::1/48", "invalid"} {
+		_, err = parseSRv6Locator(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestAddStaticPrefixesSRv6Locator(t *testing.T) {
+	c := &Controller{config: &Configuration{SRv6Locator: netip.MustParsePrefix("fd00:0:1::/48")}}
+
+	// The locator is announced along with the EIPs
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32"), api.Family_AFI_IP6: set.New("2001:db8::1/128")}
+	c.addStaticPrefixes(expected)
+	require.Equal(t, prefixMap{
+		api.Family_AFI_IP:  set.New("192.168.1.1/32"),
+		api.Family_AFI_IP6: set.New("2001:db8::1/128", "fd00:0:1::/48"),
+	}, expected)
+
+	// and without any EIP
+	expected = make(prefixMap)
+	c.addStaticPrefixes(expected)
+	require.Equal(t, prefixMap{api.Family_AFI_IP6: set.New("fd00:0:1::/48")}, expected)
+}
@@ -0,0 +1,88 @@
+package speaker
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/klog/v2"
+)
+
+// StatusPath is the path of the endpoint serving the status of the speaker
+const StatusPath = "/status"
+
+const (
+	speakerModeNatGw  = "nat-gw"
+	speakerModeSubnet = "subnet"
+)
+
+// speakerStatus is the mode, the main settings and the state of the speaker. Secrets are never part of it.
+type speakerStatus struct {
+	Mode        string `json:"mode"`
+	NodeName    string `json:"nodeName,omitempty"`
+	GatewayName string `json:"gatewayName,omitempty"`
+	ClusterAs   uint32 `json:"clusterAs"`
+	RouterID    string `json:"routerId,omitempty"`
+	// Authentication is whether the sessions are authenticated with a password, which is not disclosed
+	Authentication bool             `json:"authentication"`
+	Neighbors      []neighborStatus `json:"neighbors"`
+	// AnnouncedRoutes is the number of routes currently announced
+	AnnouncedRoutes int `json:"announcedRoutes"`
+	// SuppressedReason is why the last reconciliation suppressed the announcements, if it did
+	SuppressedReason string `json:"suppressedReason,omitempty"`
+}
+
+// neighborStatus is the configuration of a BGP neighbor and the state of the session with it
+type neighborStatus struct {
+	Address      string `json:"address"`
+	As           uint32 `json:"as"`
+	Secondary    bool   `json:"secondary,omitempty"`
+	SessionState string `json:"sessionState"`
+}
+
+// getStatus returns the status of the speaker. It only reads the state of the speaker: the suppression reason is
+// the one of the last reconciliation, computing it again would check the announce gate file and the pod of the
+// speaker, and could end the initial announce hold.
+func (c *Controller) getStatus() *speakerStatus {
+	status := &speakerStatus{
+		Mode:            speakerModeSubnet,
+		NodeName:        c.config.NodeName,
+		ClusterAs:       c.config.ClusterAs,
+		Authentication:  c.config.AuthPassword != "",
+		Neighbors:       []neighborStatus{},
+		AnnouncedRoutes: len(c.announced.List()),
+	}
+	status.SuppressedReason, _ = c.suppressedReason.Load().(string)
+	if c.config.NatGwMode {
+		status.Mode = speakerModeNatGw
+		status.GatewayName = getGatewayName()
+	}
+	if c.config.RouterID != nil {
+		status.RouterID = c.config.RouterID.String()
+	}
+
+	for _, neighbor := range c.config.allNeighborAddresses() {
+		status.Neighbors = append(status.Neighbors, neighborStatus{
+			Address:      neighbor.String(),
+			As:           c.config.getNeighborAs(neighbor),
+			Secondary:    c.config.isSecondaryNeighbor(neighbor),
+			SessionState: c.sessions.State(neighbor.String()).String(),
+		})
+	}
+	return status
+}
+
+// StatusHandler returns the handler serving the status of the speaker as JSON.
+// The metrics server serves it without authentication, as it does not use secure serving, and it discloses the
+// addresses and AS numbers of the neighbors: the metrics port must not be reachable from untrusted networks.
+func (c *Controller) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(c.getStatus()); err != nil {
+			klog.Errorf("failed to write speaker status: %v", err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package speaker
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/pkg/packet/bgp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestStatusHandler(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:                  true,
+			NodeName:                   "node1",
+			ClusterAs:                  65000,
+			RouterID:                   net.ParseIP("10.32.32.2"),
+			AuthPassword:               "secret",
+			NeighborAddresses:          []net.IP{net.ParseIP("10.32.32.1")},
+			NeighborAs:                 65001,
+			SecondaryNeighborAddresses: []net.IP{net.ParseIP("10.33.33.1")},
+			SecondaryNeighborAs:        65002,
+		},
+		announced: newAnnouncedStore(),
+		sessions:  newSessionTracker(),
+	}
+	c.announced.Add("192.168.1.1/32", routeAttributes{})
+	c.announced.Add("192.168.1.2/32", routeAttributes{})
+	c.sessions.Update("10.32.32.1", bgp.BGP_FSM_ESTABLISHED)
+
+	rec := httptest.NewRecorder()
+	c.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, StatusPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.NotContains(t, rec.Body.String(), "secret")
+
+	var status speakerStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Equal(t, speakerStatus{
+		Mode:           speakerModeNatGw,
+		NodeName:       "node1",
+		GatewayName:    "gw1",
+		ClusterAs:      65000,
+		RouterID:       "10.32.32.2",
+		Authentication: true,
+		Neighbors: []neighborStatus{
+			{Address: "10.32.32.1", As: 65001, SessionState: bgp.BGP_FSM_ESTABLISHED.String()},
+			{Address: "10.33.33.1", As: 65002, Secondary: true, SessionState: bgp.BGP_FSM_IDLE.String()},
+		},
+		AnnouncedRoutes: 2,
+	}, status)
+
+	rec = httptest.NewRecorder()
+	c.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, StatusPath, strings.NewReader("")))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestStatusSubnetMode(t *testing.T) {
+	c := &Controller{
+		config:    &Configuration{NodeName: "node1", ClusterAs: 65000},
+		announced: newAnnouncedStore(),
+		sessions:  newSessionTracker(),
+	}
+	status := c.getStatus()
+	require.Equal(t, speakerModeSubnet, status.Mode)
+	require.Empty(t, status.GatewayName)
+	require.False(t, status.Authentication)
+	require.Empty(t, status.Neighbors)
+}
+
+func TestStatusSuppressedReason(t *testing.T) {
+	c := &Controller{
+		config: &Configuration{
+			EnableLeaderElection:    true,
+			LeaderElectionNamespace: "kube-system",
+			LeaderElectionLease:     "kube-ovn-speaker",
+		},
+		announced: newAnnouncedStore(),
+		announcer: newFakeAnnouncer(),
+		sessions:  newSessionTracker(),
+	}
+	require.Empty(t, c.getStatus().SuppressedReason)
+
+	// The reason is the one of the last reconciliation
+	c.reconcileRoutes(make(prefixMap), nil)
+	require.Equal(t, "not holding leader lease kube-system/kube-ovn-speaker", c.getStatus().SuppressedReason)
+
+	// It is not computed again by the status
+	c.leading.Store(true)
+	require.NotEmpty(t, c.getStatus().SuppressedReason)
+	c.reconcileRoutes(make(prefixMap), nil)
+	require.Empty(t, c.getStatus().SuppressedReason)
+}
@@ -0,0 +1,99 @@
+package speaker
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/osrg/gobgp/v4/api"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+// announcedRoute is a prefix announced by the speaker
+type announcedRoute struct {
+	afi   api.Family_Afi
+	attrs routeAttributes
+}
+
+// announcedStore keeps track of the prefixes announced by the speaker and of their attributes.
+// It is safe for concurrent use.
+type announcedStore struct {
+	mutex  sync.RWMutex
+	routes map[string]announcedRoute
+}
+
+func newAnnouncedStore() *announcedStore {
+	return &announcedStore{routes: make(map[string]announcedRoute)}
+}
+
+// Add records a prefix as announced with the given attributes, replacing its previous attributes
+func (s *announcedStore) Add(prefix string, attrs routeAttributes) {
+	p, err := parsePrefix(prefix)
+	if err != nil {
+		klog.Errorf("failed to parse announced prefix %q: %v", prefix, err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.routes[prefix] = announcedRoute{afi: prefixToAFI(p), attrs: attrs}
+}
+
+// Remove records a prefix as withdrawn
+func (s *announcedStore) Remove(prefix string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.routes, prefix)
+}
+
+// Has returns whether a prefix is announced
+func (s *announcedStore) Has(prefix string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, ok := s.routes[prefix]
+	return ok
+}
+
+// List returns the sorted list of announced prefixes
+func (s *announcedStore) List() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	prefixes := make([]string, 0, len(s.routes))
+	for prefix := range s.routes {
+		prefixes = append(prefixes, prefix)
+	}
+	slices.Sort(prefixes)
+	return prefixes
+}
+
+// Diff compares the announced prefixes of an address family with the expected ones. It returns the prefixes
+// to announce, which are either not announced yet or announced with other attributes than the expected ones,
+// and the announced prefixes to withdraw.
+func (s *announcedStore) Diff(afi api.Family_Afi, expected set.Set[string], attrs prefixAttributes) (toAdd, toDel set.Set[string]) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	toAdd, toDel = set.New[string](), set.New[string]()
+	for prefix := range expected {
+		if route, ok := s.routes[prefix]; !ok || route.attrs != attrs[prefix] {
+			toAdd.Insert(prefix)
+		}
+	}
+	for prefix, route := range s.routes {
+		if route.afi == afi && !expected.Has(prefix) {
+			toDel.Insert(prefix)
+		}
+	}
+	return toAdd, toDel
+}
+
+// Attributes returns the announced prefixes along with the attributes they are announced with
+func (s *announcedStore) Attributes() prefixAttributes {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	attrs := make(prefixAttributes, len(s.routes))
+	for prefix, route := range s.routes {
+		attrs[prefix] = route.attrs
+	}
+	return attrs
+}
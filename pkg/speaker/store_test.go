@@ -0,0 +1,129 @@
+package speaker
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestAnnouncedStore(t *testing.T) {
+	s := newAnnouncedStore()
+	require.Empty(t, s.List())
+	require.False(t, s.Has("192.168.1.1/32"))
+
+	s.Add("192.168.1.2/32", routeAttributes{})
+	s.Add("192.168.1.1/32", routeAttributes{})
+	s.Add("2001:db8::1/128", routeAttributes{})
+	s.Add("invalid", routeAttributes{})
+	require.True(t, s.Has("192.168.1.1/32"))
+	require.False(t, s.Has("invalid"))
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32", "2001:db8::1/128"}, s.List())
+
+	// Adding a prefix again only replaces its attributes
+	s.Add("192.168.1.1/32", routeAttributes{hasMED: true, med: 10})
+	require.Len(t, s.List(), 3)
+
+	s.Remove("192.168.1.1/32")
+	s.Remove("192.168.1.3/32")
+	require.False(t, s.Has("192.168.1.1/32"))
+	require.Equal(t, []string{"192.168.1.2/32", "2001:db8::1/128"}, s.List())
+}
+
+func TestAnnouncedStoreDiff(t *testing.T) {
+	med := func(v uint32) routeAttributes { return routeAttributes{hasMED: true, med: v} }
+
+	tests := []struct {
+		name          string
+		announced     prefixAttributes
+		afi           api.Family_Afi
+		expected      []string
+		attrs         prefixAttributes
+		expectedToAdd []string
+		expectedToDel []string
+	}{
+		{
+			name:          "nothing announced yet",
+			afi:           api.Family_AFI_IP,
+			expected:      []string{"1.1.1.1/32", "2.2.2.2/32"},
+			expectedToAdd: []string{"1.1.1.1/32", "2.2.2.2/32"},
+		},
+		{
+			name:          "new routes are announced and stale ones withdrawn",
+			announced:     prefixAttributes{"1.1.1.1/32": {}, "3.3.3.3/32": {}},
+			afi:           api.Family_AFI_IP,
+			expected:      []string{"1.1.1.1/32", "2.2.2.2/32"},
+			expectedToAdd: []string{"2.2.2.2/32"},
+			expectedToDel: []string{"3.3.3.3/32"},
+		},
+		{
+			name:      "routes with unchanged attributes are not announced again",
+			announced: prefixAttributes{"1.1.1.1/32": med(100)},
+			afi:       api.Family_AFI_IP,
+			expected:  []string{"1.1.1.1/32"},
+			attrs:     prefixAttributes{"1.1.1.1/32": med(100)},
+		},
+		{
+			name:          "routes whose attributes changed are announced again",
+			announced:     prefixAttributes{"1.1.1.1/32": med(100), "2.2.2.2/32": med(100)},
+			afi:           api.Family_AFI_IP,
+			expected:      []string{"1.1.1.1/32", "2.2.2.2/32"},
+			attrs:         prefixAttributes{"1.1.1.1/32": med(50), "2.2.2.2/32": med(100)},
+			expectedToAdd: []string{"1.1.1.1/32"},
+		},
+		{
+			name:          "routes whose attributes were removed are announced again",
+			announced:     prefixAttributes{"1.1.1.1/32": med(100)},
+			afi:           api.Family_AFI_IP,
+			expected:      []string{"1.1.1.1/32"},
+			expectedToAdd: []string{"1.1.1.1/32"},
+		},
+		{
+			name:          "routes of other address families are left untouched",
+			announced:     prefixAttributes{"1.1.1.1/32": {}, "2001:db8::1/128": {}},
+			afi:           api.Family_AFI_IP6,
+			expected:      []string{"2001:db8::2/128"},
+			expectedToAdd: []string{"2001:db8::2/128"},
+			expectedToDel: []string{"2001:db8::1/128"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAnnouncedStore()
+			for prefix, attrs := range tt.announced {
+				s.Add(prefix, attrs)
+			}
+
+			toAdd, toDel := s.Diff(tt.afi, set.New(tt.expected...), tt.attrs)
+			require.ElementsMatch(t, tt.expectedToAdd, toAdd.UnsortedList())
+			require.ElementsMatch(t, tt.expectedToDel, toDel.UnsortedList())
+		})
+	}
+}
+
+func TestAnnouncedStoreConcurrentAccess(t *testing.T) {
+	s := newAnnouncedStore()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 100 {
+				prefix := fmt.Sprintf("10.%d.%d.1/32", i, j)
+				s.Add(prefix, routeAttributes{})
+				require.True(t, s.Has(prefix))
+				_ = s.List()
+				_, _ = s.Diff(api.Family_AFI_IP, set.New(prefix), nil)
+				if j%2 == 0 {
+					s.Remove(prefix)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	require.Len(t, s.List(), 8*50)
+}
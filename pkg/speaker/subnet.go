@@ -28,24 +28,32 @@ const (
 )
 
 func (c *Controller) syncSubnetRoutes() {
+	bgpExpected, err := c.getSubnetDesiredRoutes()
+	if err != nil {
+		klog.Error(err)
+		return
+	}
+
+	c.reconcileRoutes(bgpExpected, nil)
+}
+
+// getSubnetDesiredRoutes returns the prefixes of the subnets, pods and services we should be announcing
+func (c *Controller) getSubnetDesiredRoutes() (prefixMap, error) {
 	bgpExpected := make(prefixMap)
 
 	subnets, err := c.subnetsLister.List(labels.Everything())
 	if err != nil {
-		klog.Errorf("failed to list subnets, %v", err)
-		return
+		return nil, fmt.Errorf("failed to list subnets, %w", err)
 	}
 	pods, err := c.podsLister.List(labels.Everything())
 	if err != nil {
-		klog.Errorf("failed to list pods, %v", err)
-		return
+		return nil, fmt.Errorf("failed to list pods, %w", err)
 	}
 
 	if c.config.AnnounceClusterIP {
 		services, err := c.servicesLister.List(labels.Everything())
 		if err != nil {
-			klog.Errorf("failed to list services, %v", err)
-			return
+			return nil, fmt.Errorf("failed to list services, %w", err)
 		}
 		for _, svc := range services {
 			if svc.Annotations != nil && svc.Annotations[util.BgpAnnotation] == "true" && isClusterIPService(svc) {
@@ -92,10 +100,7 @@ func (c *Controller) syncSubnetRoutes() {
 	}
 
 	collectPodExpectedPrefixes(pods, subnetByName, c.config.NodeName, bgpExpected)
-
-	if err := c.reconcileRoutes(bgpExpected); err != nil {
-		klog.Errorf("failed to reconcile routes: %s", err.Error())
-	}
+	return bgpExpected, nil
 }
 
 // collectPodExpectedPrefixes iterates over pods and collects IPs that should be announced via BGP.
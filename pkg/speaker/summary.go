@@ -0,0 +1,123 @@
+package speaker
+
+import (
+	"net/netip"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+
+	v1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+)
+
+// Origins of the reconciliations, the first one being run at startup, the next ones when requested by an event
+// or periodically
+const (
+	reconcileOriginStartup  = "startup"
+	reconcileOriginEvent    = "event"
+	reconcileOriginPeriodic = "reconcile"
+)
+
+// Types of the results of a reconciliation counted by kube_ovn_speaker_reconcile_result_total
+const (
+	reconcileResultAnnounced = "announced"
+	reconcileResultWithdrawn = "withdrawn"
+	reconcileResultSkipped   = "skipped"
+	reconcileResultError     = "error"
+)
+
+// reconcileSummary sums up a reconciliation of the routes of the EIPs
+type reconcileSummary struct {
+	// eips is the number of EIPs considered for announcement, skipped the number of them without any route to announce
+	eips    int
+	skipped int
+	// announced and withdrawn are the numbers of routes successfully announced and withdrawn
+	announced int
+	withdrawn int
+	// errors is the number of routes which failed to be announced or withdrawn
+	errors   int
+	duration time.Duration
+}
+
+// newReconcileSummary sums up a reconciliation from the result of each route announced or withdrawn, the routes
+// successfully announced being still recorded as announced and the ones successfully withdrawn not anymore
+func newReconcileSummary(eips int, results routeResults, announced *announcedStore, duration time.Duration) reconcileSummary {
+	s := reconcileSummary{eips: eips, duration: duration}
+	for route, err := range results {
+		switch {
+		case err != nil:
+			s.errors++
+		case announced.Has(route):
+			s.announced++
+		default:
+			s.withdrawn++
+		}
+	}
+	return s
+}
+
+// keysAndValues returns the fields of the summary as structured logging key/value pairs
+func (s reconcileSummary) keysAndValues() []any {
+	return []any{"eips", s.eips, "skipped", s.skipped, "announced", s.announced, "withdrawn", s.withdrawn, "errors", s.errors, "duration", s.duration}
+}
+
+// log logs the summary on a single line, to monitor the reconciliations without verbose logs
+func (s reconcileSummary) log() {
+	klog.V(2).InfoS("reconciled EIP routes", s.keysAndValues()...)
+}
+
+// count adds the results of the reconciliation to kube_ovn_speaker_reconcile_result_total
+func (s reconcileSummary) count() {
+	for result, n := range map[string]int{
+		reconcileResultAnnounced: s.announced,
+		reconcileResultWithdrawn: s.withdrawn,
+		reconcileResultSkipped:   s.skipped,
+		reconcileResultError:     s.errors,
+	} {
+		if n != 0 {
+			metricReconcileResults.WithLabelValues(result).Add(float64(n))
+		}
+	}
+}
+
+// countSkippedEIPs returns the number of EIPs none of whose addresses is announced by the expected routes, either
+// with a host route or with the route of its point-to-point link
+func countSkippedEIPs(eips []*v1.IptablesEIP, expectedPrefixes prefixMap) int {
+	var skipped int
+	for _, eip := range eips {
+		if !isEIPExpected(eip, expectedPrefixes) {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// isEIPExpected returns whether an address of an EIP is announced by the expected routes
+func isEIPExpected(eip *v1.IptablesEIP, expectedPrefixes prefixMap) bool {
+	for _, address := range []string{getEIPv4Address(eip), eip.Spec.V6ip} {
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		for _, bits := range []int{addr.BitLen(), addr.BitLen() - 1} {
+			if prefix, err := addr.Prefix(bits); err == nil && expectedPrefixes[prefixToAFI(prefix)].Has(prefix.String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// countAnnouncedRoutes counts the routes announced by a reconciliation, given the routes announced before and after it
+func countAnnouncedRoutes(origin string, before set.Set[string], after []string) {
+	var count int
+	for _, route := range after {
+		if !before.Has(route) {
+			count++
+		}
+	}
+	if count != 0 {
+		metricRoutesAnnounced.WithLabelValues(origin).Add(float64(count))
+	}
+}
@@ -0,0 +1,201 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/set"
+
+	kubeovnv1 "github.com/kubeovn/kube-ovn/pkg/apis/kubeovn/v1"
+	kubeovnlister "github.com/kubeovn/kube-ovn/pkg/client/listers/kubeovn/v1"
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+func TestReconcileSummary(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, eip := range []struct{ name, ip string }{
+		{"eip-announced", "192.168.1.1"},
+		{"eip-new", "192.168.1.2"},
+		{"eip-failing", "192.168.1.3"},
+	} {
+		e := newTestEIP(eip.name, eip.ip, "", true, bgpAnnotation)
+		e.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+		require.NoError(t, eipIndexer.Add(e))
+	}
+
+	a := newFakeAnnouncer()
+	a.failing.Insert("192.168.1.3/32")
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:              true,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		eipsWithoutAddress: set.New[string](),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+	}
+	// The route of eip-announced is already announced, the route of a deleted EIP is still announced
+	require.NoError(t, c.addRoutes([]string{"192.168.1.1/32", "192.168.1.4/32"}, nil).err())
+
+	eips, err := c.listGatewayEIPs()
+	require.NoError(t, err)
+	expected, attrs := c.getGatewayEIPsExpectedRoutes(eips)
+	results := c.reconcileRoutes(expected, attrs)
+
+	summary := newReconcileSummary(len(eips), results, c.announced, 42*time.Millisecond)
+	require.Equal(t, reconcileSummary{eips: 3, announced: 1, withdrawn: 1, errors: 1, duration: 42 * time.Millisecond}, summary)
+	require.Equal(t, []any{"eips", 3, "skipped", 0, "announced", 1, "withdrawn", 1, "errors", 1, "duration", 42 * time.Millisecond}, summary.keysAndValues())
+
+	// The failing route is announced by the next reconciliation, after which nothing is left to reconcile
+	a.failing.Clear()
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32"}, c.announced.List())
+	summary = newReconcileSummary(len(eips), c.reconcileRoutes(expected, attrs), c.announced, 0)
+	require.Equal(t, reconcileSummary{eips: 3}, summary)
+}
+
+func TestReconcileOrigin(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	addEIP := func(name, ip string) {
+		e := newTestEIP(name, ip, "", true, bgpAnnotation)
+		e.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+		require.NoError(t, eipIndexer.Add(e))
+	}
+	addEIP("eip1", "192.168.1.1")
+
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:              true,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		eipsWithoutAddress: set.New[string](),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+		reconcileCh:        make(chan struct{}, 1),
+	}
+	announcedBy := func(origin string) float64 {
+		return testutil.ToFloat64(metricRoutesAnnounced.WithLabelValues(origin))
+	}
+	startup, event, periodic := announcedBy(reconcileOriginStartup), announcedBy(reconcileOriginEvent), announcedBy(reconcileOriginPeriodic)
+
+	// The first reconciliation of the loop is the startup one, the reconciliations requested by events follow
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.runReconcileLoop(stopCh)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return announcedBy(reconcileOriginStartup) == startup+1 }, 5*time.Second, 10*time.Millisecond)
+
+	addEIP("eip2", "192.168.1.2")
+	c.requestReconcile()
+	require.Eventually(t, func() bool { return announcedBy(reconcileOriginEvent) == event+1 }, 5*time.Second, 10*time.Millisecond)
+	close(stopCh)
+	<-done
+
+	// Routes already announced are not counted again, the failing ones are counted once announced
+	a.failing.Insert("192.168.1.3/32")
+	addEIP("eip3", "192.168.1.3")
+	c.Reconcile(reconcileOriginPeriodic)
+	require.Equal(t, periodic, announcedBy(reconcileOriginPeriodic))
+	a.failing.Clear()
+	c.Reconcile(reconcileOriginPeriodic)
+	require.Equal(t, periodic+1, announcedBy(reconcileOriginPeriodic))
+	require.Equal(t, startup+1, announcedBy(reconcileOriginStartup))
+	require.Equal(t, event+1, announcedBy(reconcileOriginEvent))
+}
+
+func TestReconcileResults(t *testing.T) {
+	t.Setenv(util.EnvGatewayName, "gw1")
+	bgpAnnotation := map[string]string{util.BgpAnnotation: "true"}
+	eipIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, eip := range []struct {
+		name, ip    string
+		ready       bool
+		annotations map[string]string
+	}{
+		{"eip-announced", "192.168.1.1", true, bgpAnnotation},
+		{"eip-failing", "192.168.1.2", true, bgpAnnotation},
+		{"eip-not-ready", "192.168.1.3", false, bgpAnnotation},
+		{"eip-without-bgp", "192.168.1.4", true, nil},
+	} {
+		e := newTestEIP(eip.name, eip.ip, "", eip.ready, eip.annotations)
+		e.Labels = map[string]string{util.VpcNatGatewayNameLabel: "gw1"}
+		require.NoError(t, eipIndexer.Add(e))
+	}
+
+	a := newFakeAnnouncer()
+	a.failing.Insert("192.168.1.2/32")
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NatGwMode:              true,
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		eipLister:          kubeovnlister.NewIptablesEIPLister(eipIndexer),
+		subnetsLister:      newTestSubnetLister(t),
+		natgatewayLister:   kubeovnlister.NewVpcNatGatewayLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		eipsWithoutAddress: set.New[string](),
+		announced:          newAnnouncedStore(),
+		announcer:          a,
+	}
+	// The route of a deleted EIP is still announced
+	require.NoError(t, c.addRoutes([]string{"192.168.1.5/32"}, nil).err())
+
+	results := func(result string) float64 {
+		return testutil.ToFloat64(metricReconcileResults.WithLabelValues(result))
+	}
+	announced, withdrawn := results(reconcileResultAnnounced), results(reconcileResultWithdrawn)
+	skipped, failed := results(reconcileResultSkipped), results(reconcileResultError)
+
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, announced+1, results(reconcileResultAnnounced))
+	require.Equal(t, withdrawn+1, results(reconcileResultWithdrawn))
+	require.Equal(t, skipped+2, results(reconcileResultSkipped))
+	require.Equal(t, failed+1, results(reconcileResultError))
+
+	// Only the failing route is announced by the next reconciliation, the EIPs not to announce being skipped again
+	a.failing.Clear()
+	require.NoError(t, c.syncEIPRoutes())
+	require.Equal(t, announced+2, results(reconcileResultAnnounced))
+	require.Equal(t, withdrawn+1, results(reconcileResultWithdrawn))
+	require.Equal(t, skipped+4, results(reconcileResultSkipped))
+	require.Equal(t, failed+1, results(reconcileResultError))
+}
+
+func TestCountSkippedEIPs(t *testing.T) {
+	eips := []*kubeovnv1.IptablesEIP{
+		newTestEIP("eip-host", "192.168.1.1", "", true, nil),
+		newTestEIP("eip-link", "192.168.1.3", "", true, nil),
+		newTestEIP("eip-ipv6", "", "2001:db8::1", true, nil),
+		newTestEIP("eip-skipped", "192.168.1.4", "2001:db8::4", true, nil),
+		newTestEIP("eip-without-address", "", "", true, nil),
+	}
+	expected := prefixMap{
+		api.Family_AFI_IP:  set.New("192.168.1.1/32", "192.168.1.2/31"),
+		api.Family_AFI_IP6: set.New("2001:db8::1/128"),
+	}
+	require.Equal(t, 2, countSkippedEIPs(eips, expected))
+	require.Equal(t, len(eips), countSkippedEIPs(eips, make(prefixMap)))
+}
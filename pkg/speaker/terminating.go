@@ -0,0 +1,59 @@
+package speaker
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+// isPodTerminating returns whether the pod of the speaker is being deleted, the routes being then withdrawn
+// before it stops to drain the traffic, with --withdraw-on-terminating
+func (c *Controller) isPodTerminating() bool {
+	if !c.config.WithdrawOnTerminating {
+		return false
+	}
+
+	pod, err := c.podsLister.Pods(c.config.PodNamespace).Get(c.config.PodName)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to get pod %s/%s: %v", c.config.PodNamespace, c.config.PodName, err)
+		}
+		return false
+	}
+	return pod.DeletionTimestamp != nil
+}
+
+// terminatingReason returns why the routes are withdrawn while the pod of the speaker is terminating
+func (c *Controller) terminatingReason() string {
+	return fmt.Sprintf("pod %s/%s is terminating", c.config.PodNamespace, c.config.PodName)
+}
+
+// enqueueUpdatePod reconciles the routes as soon as the pod of the speaker starts terminating, so that they are
+// withdrawn right away, when a NAT gateway pod update may change the node announcing the routes, or when the NAT
+// gateway of the speaker becomes degraded or healthy again
+func (c *Controller) enqueueUpdatePod(oldObj, newObj any) {
+	oldPod, newPod := oldObj.(*corev1.Pod), newObj.(*corev1.Pod)
+	if c.isSingleAnnouncerUpdate(oldPod, newPod) {
+		klog.Infof("pod %s/%s of vpc nat gateway %s changed, electing the node announcing the routes", newPod.Namespace, newPod.Name, getGatewayName())
+		c.requestReconcile()
+		return
+	}
+	if c.isDegradedUpdate(oldPod, newPod) {
+		if isGatewayDegraded(newPod, c.config.HealthProbeContainer) {
+			klog.Warningf("container %s of pod %s/%s is not ready, announcing routes with the degraded community", c.config.HealthProbeContainer, newPod.Namespace, newPod.Name)
+		} else {
+			klog.Infof("container %s of pod %s/%s is ready again, announcing routes without the degraded community", c.config.HealthProbeContainer, newPod.Namespace, newPod.Name)
+		}
+		c.requestReconcile()
+		return
+	}
+	if !c.config.WithdrawOnTerminating || newPod.Name != c.config.PodName || newPod.Namespace != c.config.PodNamespace {
+		return
+	}
+	if oldPod.DeletionTimestamp == nil && newPod.DeletionTimestamp != nil {
+		klog.Infof("pod %s/%s is terminating, withdrawing routes", newPod.Namespace, newPod.Name)
+		c.requestReconcile()
+	}
+}
@@ -0,0 +1,68 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/set"
+)
+
+func TestWithdrawOnTerminating(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc-nat-gw-gw1-0", Namespace: "kube-system"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	require.NoError(t, podIndexer.Add(pod))
+
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+			WithdrawOnTerminating:  true,
+			PodName:                pod.Name,
+			PodNamespace:           pod.Namespace,
+		},
+		podsLister:  listerv1.NewPodLister(podIndexer),
+		announced:   newAnnouncedStore(),
+		announcer:   a,
+		reconcileCh: make(chan struct{}, 1),
+	}
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32")}
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// Updates of other pods or not deleting the pod do not trigger a reconciliation
+	other := pod.DeepCopy()
+	other.Name = "vpc-nat-gw-gw2-0"
+	terminatingOther := other.DeepCopy()
+	terminatingOther.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	c.enqueueUpdatePod(other, terminatingOther)
+	c.enqueueUpdatePod(pod, pod.DeepCopy())
+	require.Empty(t, c.reconcileCh)
+
+	// The routes are withdrawn once the pod is terminating, although still running
+	terminating := pod.DeepCopy()
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	require.NoError(t, podIndexer.Update(terminating))
+	c.enqueueUpdatePod(pod, terminating)
+	require.Len(t, c.reconcileCh, 1)
+	require.Equal(t, "pod kube-system/vpc-nat-gw-gw1-0 is terminating", c.announcementSuppressedReason(time.Now()))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.False(t, a.isRouteAnnounced("192.168.1.1/32"))
+
+	// The routes are kept without --withdraw-on-terminating
+	c.config.WithdrawOnTerminating = false
+	require.Empty(t, c.announcementSuppressedReason(time.Now()))
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.True(t, a.isRouteAnnounced("192.168.1.1/32"))
+}
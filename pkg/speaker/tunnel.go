@@ -0,0 +1,77 @@
+package speaker
+
+import (
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/vishvananda/netlink"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeovn/kube-ovn/pkg/util"
+)
+
+// tunnelLinkTypes are the types of the links accepted by --tunnel-interface
+var tunnelLinkTypes = []string{"gre", "ip6gre", "ipip", "ip6tnl", "sit"}
+
+// validateTunnelInterface checks that the link of --tunnel-interface is a GRE or IPIP tunnel
+func validateTunnelInterface(link netlink.Link) error {
+	if !slices.Contains(tunnelLinkTypes, link.Type()) {
+		return fmt.Errorf("invalid tunnel-interface: link %s is of type %s, expected one of %v", link.Attrs().Name, link.Type(), tunnelLinkTypes)
+	}
+	return nil
+}
+
+// checkTunnelRoutes warns about the neighbors which are not reached through the tunnel interface, the routes
+// advertised to them having a next hop only reachable through the tunnel
+func (config *Configuration) checkTunnelRoutes(link netlink.Link) {
+	for _, neighbor := range config.allNeighborAddresses() {
+		routes, err := netlink.RouteGet(neighbor)
+		if err != nil {
+			klog.Warningf("failed to look up the route to neighbor %s: %v", neighbor, err)
+			continue
+		}
+		if len(routes) == 0 || routes[0].LinkIndex != link.Attrs().Index {
+			klog.Warningf("neighbor %s is not reached through tunnel interface %s, it may not reach the next hop advertised to it", neighbor, link.Attrs().Name)
+		}
+	}
+}
+
+// getTunnelNextHop returns the address of the tunnel interface advertised as next hop to a neighbor, nil if the
+// tunnel interface is not configured or has no address of the family of the neighbor
+func (c *Controller) getTunnelNextHop(neighbor net.IP) net.IP {
+	if c.config.TunnelInterface == "" {
+		return nil
+	}
+
+	link, err := netlink.LinkByName(c.config.TunnelInterface)
+	if err != nil {
+		klog.Errorf("failed to get tunnel interface %s: %v", c.config.TunnelInterface, err)
+		return nil
+	}
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		klog.Errorf("failed to list the addresses of tunnel interface %s: %v", c.config.TunnelInterface, err)
+		return nil
+	}
+	return selectTunnelNextHop(neighbor, addrs)
+}
+
+// selectTunnelNextHop returns the address of the tunnel interface of the family of a neighbor to advertise as next
+// hop to it, preferring the address whose network holds the neighbor. Link-local IPv6 addresses are never selected.
+func selectTunnelNextHop(neighbor net.IP, addrs []netlink.Addr) net.IP {
+	protocol := util.CheckProtocol(neighbor.String())
+	var nextHop net.IP
+	for _, addr := range addrs {
+		if addr.IPNet == nil || addr.IP.IsLinkLocalUnicast() || util.CheckProtocol(addr.IP.String()) != protocol {
+			continue
+		}
+		if addr.Contains(neighbor) {
+			return addr.IP
+		}
+		if nextHop == nil {
+			nextHop = addr.IP
+		}
+	}
+	return nextHop
+}
@@ -0,0 +1,84 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+)
+
+func TestValidateTunnelInterface(t *testing.T) {
+	attrs := netlink.LinkAttrs{Name: "tun0"}
+	require.NoError(t, validateTunnelInterface(&netlink.Gretun{LinkAttrs: attrs}))
+	require.NoError(t, validateTunnelInterface(&netlink.Iptun{LinkAttrs: attrs}))
+	require.NoError(t, validateTunnelInterface(&netlink.Ip6tnl{LinkAttrs: attrs}))
+	require.ErrorContains(t, validateTunnelInterface(&netlink.Dummy{LinkAttrs: attrs}), "link tun0 is of type dummy")
+}
+
+func TestSelectTunnelNextHop(t *testing.T) {
+	parseAddrs := func(cidrs ...string) []netlink.Addr {
+		addrs := make([]netlink.Addr, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			addr, err := netlink.ParseAddr(cidr)
+			require.NoError(t, err)
+			addrs = append(addrs, *addr)
+		}
+		return addrs
+	}
+
+	tests := []struct {
+		name     string
+		neighbor string
+		addrs    []netlink.Addr
+		expected net.IP
+	}{
+		{
+			name:     "address of the network of the neighbor",
+			neighbor: "172.16.0.1",
+			addrs:    parseAddrs("192.168.0.2/30", "172.16.0.2/30", "fd00::2/64"),
+			expected: net.ParseIP("172.16.0.2"),
+		},
+		{
+			name:     "first address of the family of the neighbor",
+			neighbor: "10.32.32.1",
+			addrs:    parseAddrs("fd00::2/64", "192.168.0.2/30", "172.16.0.2/30"),
+			expected: net.ParseIP("192.168.0.2"),
+		},
+		{
+			name:     "IPv6 neighbor",
+			neighbor: "fd00::1",
+			addrs:    parseAddrs("fe80::2/64", "172.16.0.2/30", "fd00::2/64"),
+			expected: net.ParseIP("fd00::2"),
+		},
+		{
+			name:     "link-local addresses are not selected",
+			neighbor: "fe80::1",
+			addrs:    parseAddrs("fe80::2/64"),
+		},
+		{
+			name:     "no address of the family of the neighbor",
+			neighbor: "fd00::1",
+			addrs:    parseAddrs("172.16.0.2/30"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, selectTunnelNextHop(net.ParseIP(tt.neighbor), tt.addrs))
+		})
+	}
+}
+
+func TestGetNextHopAttributeWithoutTunnel(t *testing.T) {
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{config: &Configuration{
+		NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+	}}
+	require.Nil(t, c.getTunnelNextHop(neighbor))
+	require.Equal(t, net.ParseIP("10.32.32.2"), c.getNextHopAttribute(neighbor))
+
+	// The local address of the neighbor is still advertised when the tunnel interface cannot be found
+	c.config.TunnelInterface = "kube-ovn-missing-tunnel"
+	require.Nil(t, c.getTunnelNextHop(neighbor))
+	require.Equal(t, net.ParseIP("10.32.32.2"), c.getNextHopAttribute(neighbor))
+}
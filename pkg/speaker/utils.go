@@ -17,12 +17,48 @@ import (
 // prefixMap is a map associating a BGP address family (IPv4 or IPv6) and an IP set
 type prefixMap map[api.Family_Afi]set.Set[string]
 
+// routeAttributes holds the optional BGP path attributes of an announced prefix
+type routeAttributes struct {
+	hasMED bool
+	med    uint32
+	// localPref is the LOCAL_PREF of the route, advertised if hasLocalPref is true
+	hasLocalPref bool
+	localPref    uint32
+	// linkBandwidth is the bandwidth advertised in the link bandwidth extended community in bytes per second,
+	// the community is not advertised if zero
+	linkBandwidth float32
+	// batch is the name of the batch of routes the route is announced and withdrawn with, it is not advertised
+	batch string
+	// instance is the BGP instance whose neighbors the route is advertised to
+	instance bgpInstance
+	// neighbors is the sorted comma separated list of the addresses of the only neighbors the route is advertised to,
+	// the route is advertised to every neighbor if empty
+	neighbors string
+	// drainingCommunity is the community the routes of drained EIPs are announced with, they are withdrawn if zero
+	drainingCommunity uint32
+	// draining is whether the route is announced with the draining community
+	draining bool
+	// rpkiCommunity is the community tagging the origin validation state of the route, not advertised if zero.
+	// It is set when the path of the route is built, from the prefix of the route.
+	rpkiCommunity uint32
+	// gracefulShutdownCommunity is the community the routes are announced with while the speaker shuts down,
+	// not advertised if zero
+	gracefulShutdownCommunity uint32
+	// degradedCommunity is the community the routes are announced with while the NAT gateway is degraded,
+	// not advertised if zero
+	degradedCommunity uint32
+}
+
+// prefixAttributes is a map associating an announced prefix and its optional BGP path attributes
+type prefixAttributes map[string]routeAttributes
+
 // addExpectedPrefix adds a new prefix to the list of expected prefixes we should be announcing
-func addExpectedPrefix(ip string, expectedPrefixes prefixMap) {
+// and returns it, or returns an empty string if the address cannot be parsed
+func addExpectedPrefix(ip string, expectedPrefixes prefixMap) string {
 	prefix, err := parsePrefix(ip)
 	if err != nil {
 		klog.Errorf("failed to parse prefix of address %q: %v", ip, err)
-		return
+		return ""
 	}
 
 	if afi := prefixToAFI(prefix); expectedPrefixes[afi] == nil {
@@ -30,6 +66,7 @@ func addExpectedPrefix(ip string, expectedPrefixes prefixMap) {
 	} else {
 		expectedPrefixes[afi].Insert(prefix.String())
 	}
+	return prefix.String()
 }
 
 // isPodAlive returns whether a Pod is alive or not
@@ -57,14 +94,23 @@ func isClusterIPService(svc *corev1.Service) bool {
 
 // parsePrefix returns the prefix by parsing the received ip address or network string
 // If the input is an IP address, it converts it to a /32 or /128 prefix
+// IPv4-mapped IPv6 addresses and prefixes are converted to their IPv4 form, so that they are announced as IPv4
 func parsePrefix(s string) (netip.Prefix, error) {
 	if strings.Contains(s, "/") {
-		return netip.ParsePrefix(s)
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96), nil
+		}
+		return prefix, nil
 	}
 	addr, err := netip.ParseAddr(s)
 	if err != nil {
 		return netip.Prefix{}, err
 	}
+	addr = addr.Unmap()
 	return netip.PrefixFrom(addr, addr.BitLen()), nil
 }
 
@@ -84,3 +130,28 @@ func prefixToAFI(prefix netip.Prefix) api.Family_Afi {
 		return api.Family_AFI_UNSPECIFIED
 	}
 }
+
+// ipNetsToPrefixes converts IP networks to network prefixes
+func ipNetsToPrefixes(ipNets []net.IPNet) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		addr, _ := netip.AddrFromSlice(ipNet.IP)
+		ones, _ := ipNet.Mask.Size()
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), ones).Masked())
+	}
+	return prefixes
+}
+
+// isPrefixOriginAllowed returns whether a prefix is covered by one of the CIDRs the cluster AS is allowed
+// to originate, every prefix is allowed if there are no such CIDRs
+func isPrefixOriginAllowed(prefix netip.Prefix, allowed []netip.Prefix) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, cidr := range allowed {
+		if cidr.Bits() <= prefix.Bits() && cidr.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
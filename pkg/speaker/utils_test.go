@@ -0,0 +1,41 @@
+package speaker
+
+import (
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		expectedAFI api.Family_Afi
+		expectError bool
+	}{
+		{input: "10.16.0.5", expected: "10.16.0.5/32", expectedAFI: api.Family_AFI_IP},
+		{input: "10.16.0.0/16", expected: "10.16.0.0/16", expectedAFI: api.Family_AFI_IP},
+		{input: "fd00:10:16::5", expected: "fd00:10:16::5/128", expectedAFI: api.Family_AFI_IP6},
+		{input: "fd00:10:16::/64", expected: "fd00:10:16::/64", expectedAFI: api.Family_AFI_IP6},
+		{input: "::ffff:10.16.0.5", expected: "10.16.0.5/32", expectedAFI: api.Family_AFI_IP},
+		{input: "::ffff:10.16.0.0/112", expected: "10.16.0.0/16", expectedAFI: api.Family_AFI_IP},
+		{input: "::ffff:0:0/96", expected: "0.0.0.0/0", expectedAFI: api.Family_AFI_IP},
+		{input: "::/64", expected: "::/64", expectedAFI: api.Family_AFI_IP6},
+		{input: "10.16.0", expectError: true},
+		{input: "10.16.0.0/33", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prefix, err := parsePrefix(tt.input)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, prefix.String())
+			require.Equal(t, tt.expectedAFI, prefixToAFI(prefix))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package speaker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/osrg/gobgp/v4/pkg/apiutil"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/set"
+)
+
+const (
+	// announcementVerifyTimeout is how long the announced routes are waited for in the RIB of the BGP server
+	announcementVerifyTimeout = time.Second
+	// announcementVerifyInterval is the interval at which the RIB is checked for the announced routes
+	announcementVerifyInterval = 100 * time.Millisecond
+)
+
+// verifyAnnouncements checks that the routes whose paths were accepted by the BGP server are in its RIB, as paths
+// may still be rejected by a policy once accepted. The routes not found in the RIB within the verification timeout
+// are reported as failed, so that they are not recorded as announced and are announced again by the next
+// reconciliation. Routes without any path are not verified.
+func (c *Controller) verifyAnnouncements(routePaths map[string][]*apiutil.Path, results routeResults) {
+	pending := set.New[string]()
+	for route, paths := range routePaths {
+		if results[route] == nil && len(paths) != 0 {
+			pending.Insert(route)
+		}
+	}
+	if pending.Len() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), announcementVerifyTimeout)
+	defer cancel()
+	_ = wait.PollUntilContextCancel(ctx, announcementVerifyInterval, true, func(context.Context) (bool, error) {
+		for _, route := range pending.UnsortedList() {
+			announced, err := c.getAnnouncer().IsRouteAnnounced(route)
+			if err != nil {
+				klog.Error(err)
+				continue
+			}
+			if announced {
+				pending.Delete(route)
+			}
+		}
+		return pending.Len() == 0, nil
+	})
+
+	for _, route := range pending.SortedList() {
+		results[route] = fmt.Errorf("route %s is not in the RIB within %s of being announced, its paths may be rejected by a policy", route, announcementVerifyTimeout)
+	}
+}
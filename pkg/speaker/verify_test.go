@@ -0,0 +1,76 @@
+package speaker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestGobgpAnnouncerIsRouteAnnounced(t *testing.T) {
+	s := newTestBgpServer(t)
+	c := &Controller{
+		config: &Configuration{
+			BgpServer:              s,
+			NeighborAddresses:      []net.IP{net.ParseIP("10.32.32.1")},
+			NeighborIPv6Addresses:  []net.IP{net.ParseIP("fd00::1")},
+			NeighborLocalAddresses: map[string]net.IP{"10.32.32.1": net.ParseIP("10.32.32.2"), "fd00::1": net.ParseIP("fd00::2")},
+			VerifyAnnouncements:    true,
+		},
+		announced: newAnnouncedStore(),
+	}
+	require.NoError(t, c.addRoutes([]string{"192.168.1.1/32", "fd00:10::1/128"}, nil).err())
+
+	a := gobgpAnnouncer{server: s}
+	for route, expected := range map[string]bool{
+		"192.168.1.1/32": true,
+		"192.168.1.2/32": false,
+		"192.168.1.0/24": false,
+		"fd00:10::1/128": true,
+		"fd00:10::2/128": false,
+	} {
+		announced, err := a.IsRouteAnnounced(route)
+		require.NoError(t, err)
+		require.Equal(t, expected, announced, route)
+	}
+
+	_, err := a.IsRouteAnnounced("invalid")
+	require.Error(t, err)
+}
+
+func TestVerifyAnnouncements(t *testing.T) {
+	a := newFakeAnnouncer()
+	a.rejected.Insert("192.168.1.2/32")
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced: newAnnouncedStore(),
+		announcer: a,
+	}
+	expected := prefixMap{api.Family_AFI_IP: set.New("192.168.1.1/32", "192.168.1.2/32")}
+
+	// Without verification, a route accepted by the BGP server is recorded as announced even if rejected
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, c.announced.List())
+	require.False(t, a.isRouteAnnounced("192.168.1.2/32"))
+
+	// With verification, the rejected route is not recorded as announced
+	c.announced = newAnnouncedStore()
+	c.config.VerifyAnnouncements = true
+	results := c.reconcileRoutes(expected, nil)
+	require.Equal(t, []string{"192.168.1.2/32"}, results.failed())
+	require.ErrorContains(t, results["192.168.1.2/32"], "not in the RIB")
+	require.Equal(t, []string{"192.168.1.1/32"}, c.announced.List())
+
+	// The rejected route is announced again by the next reconciliation, and recorded once confirmed
+	a.rejected.Clear()
+	a.calls = nil
+	require.Empty(t, c.reconcileRoutes(expected, nil).failed())
+	require.Equal(t, []fakeAnnouncerCall{{prefixes: []string{"192.168.1.2/32"}}}, a.calls)
+	require.Equal(t, []string{"192.168.1.1/32", "192.168.1.2/32"}, c.announced.List())
+}
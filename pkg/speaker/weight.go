@@ -0,0 +1,89 @@
+package speaker
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nodeWeightMED advertises the weight of the node as MED, the weight being converted like a GW priority
+	nodeWeightMED = "med"
+	// nodeWeightLinkBandwidth advertises the weight of the node in the link bandwidth extended community,
+	// the weight being a bandwidth in Mbit/s
+	nodeWeightLinkBandwidth = "link-bandwidth"
+)
+
+// validateNodeWeightAttribute checks the attribute the weight of the node is advertised with
+func validateNodeWeightAttribute(attribute string) error {
+	switch attribute {
+	case nodeWeightMED, nodeWeightLinkBandwidth:
+		return nil
+	default:
+		return fmt.Errorf("invalid node-weight-attribute %q: must be %q or %q", attribute, nodeWeightMED, nodeWeightLinkBandwidth)
+	}
+}
+
+// nodeWeightToAttributes returns the route attributes advertising the weight of a node
+func nodeWeightToAttributes(weight, attribute string) (routeAttributes, error) {
+	var attrs routeAttributes
+	switch attribute {
+	case nodeWeightMED:
+		med, err := priorityToMED(weight)
+		if err != nil {
+			return attrs, err
+		}
+		attrs.hasMED, attrs.med = true, med
+	case nodeWeightLinkBandwidth:
+		linkBandwidth, err := parseLinkBandwidth(weight)
+		if err != nil {
+			return attrs, err
+		}
+		attrs.linkBandwidth = linkBandwidth
+	}
+	return attrs, nil
+}
+
+// applyNodeWeight sets the attribute advertising the weight of the node of the speaker, read from the node weight
+// label, on the attributes of the routes of the EIPs unless the GW already sets it
+func (c *Controller) applyNodeWeight(attrs *routeAttributes) {
+	if c.config.NodeWeightLabel == "" {
+		return
+	}
+
+	node, err := c.nodesLister.Get(c.config.NodeName)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("failed to get node %s: %v", c.config.NodeName, err)
+		}
+		return
+	}
+	weight := node.Labels[c.config.NodeWeightLabel]
+	if weight == "" {
+		return
+	}
+
+	nodeAttrs, err := nodeWeightToAttributes(weight, c.config.NodeWeightAttribute)
+	if err != nil {
+		klog.Errorf("invalid label %s=%s on node %s: %v", c.config.NodeWeightLabel, weight, node.Name, err)
+		return
+	}
+	if !attrs.hasMED {
+		attrs.hasMED, attrs.med = nodeAttrs.hasMED, nodeAttrs.med
+	}
+	if attrs.linkBandwidth == 0 {
+		attrs.linkBandwidth = nodeAttrs.linkBandwidth
+	}
+}
+
+// enqueueUpdateNode reconciles the routes when the weight label of the node of the speaker changes, so that the
+// new weight is advertised right away
+func (c *Controller) enqueueUpdateNode(oldObj, newObj any) {
+	oldNode, newNode := oldObj.(*corev1.Node), newObj.(*corev1.Node)
+	if oldNode.Labels[c.config.NodeWeightLabel] != newNode.Labels[c.config.NodeWeightLabel] {
+		klog.Infof("weight of node %s changed to %q, reconciling routes", newNode.Name, newNode.Labels[c.config.NodeWeightLabel])
+		c.requestReconcile()
+	}
+}
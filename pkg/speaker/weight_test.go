@@ -0,0 +1,117 @@
+package speaker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestValidateNodeWeightAttribute(t *testing.T) {
+	require.NoError(t, validateNodeWeightAttribute(nodeWeightMED))
+	require.NoError(t, validateNodeWeightAttribute(nodeWeightLinkBandwidth))
+	require.Error(t, validateNodeWeightAttribute("local-pref"))
+}
+
+func TestNodeWeightToAttributes(t *testing.T) {
+	tests := []struct {
+		name      string
+		weight    string
+		attribute string
+		expected  routeAttributes
+		expectErr bool
+	}{
+		{
+			name:      "MED",
+			weight:    "50",
+			attribute: nodeWeightMED,
+			expected:  routeAttributes{hasMED: true, med: maxGatewayPriority - 50},
+		},
+		{
+			name:      "link bandwidth",
+			weight:    "800",
+			attribute: nodeWeightLinkBandwidth,
+			expected:  routeAttributes{linkBandwidth: 100 * 1000 * 1000},
+		},
+		{
+			name:      "weight out of the range of the priorities",
+			weight:    "65536",
+			attribute: nodeWeightMED,
+			expectErr: true,
+		},
+		{
+			name:      "zero bandwidth",
+			weight:    "0",
+			attribute: nodeWeightLinkBandwidth,
+			expectErr: true,
+		},
+		{
+			name:      "invalid weight",
+			weight:    "heavy",
+			attribute: nodeWeightMED,
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs, err := nodeWeightToAttributes(tt.weight, tt.attribute)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, attrs)
+		})
+	}
+}
+
+func TestApplyNodeWeight(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"bgp-weight": "100"}}}
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, nodeIndexer.Add(node))
+	c := &Controller{
+		config: &Configuration{
+			NodeName:            "node1",
+			NodeWeightLabel:     "bgp-weight",
+			NodeWeightAttribute: nodeWeightMED,
+		},
+		nodesLister: listerv1.NewNodeLister(nodeIndexer),
+		reconcileCh: make(chan struct{}, 1),
+	}
+
+	var attrs routeAttributes
+	c.applyNodeWeight(&attrs)
+	require.Equal(t, routeAttributes{hasMED: true, med: maxGatewayPriority - 100}, attrs)
+
+	// The MED of the GW takes precedence over the weight of the node
+	attrs = routeAttributes{hasMED: true, med: 10}
+	c.applyNodeWeight(&attrs)
+	require.Equal(t, routeAttributes{hasMED: true, med: 10}, attrs)
+
+	// The weight is advertised as link bandwidth along with the MED of the GW
+	c.config.NodeWeightAttribute = nodeWeightLinkBandwidth
+	c.applyNodeWeight(&attrs)
+	require.Equal(t, routeAttributes{hasMED: true, med: 10, linkBandwidth: 100 * 1000 * 1000 / 8}, attrs)
+
+	// Nothing is advertised for nodes without the label or with an invalid weight
+	for _, weight := range []string{"", "heavy"} {
+		updated := node.DeepCopy()
+		updated.Labels["bgp-weight"] = weight
+		require.NoError(t, nodeIndexer.Update(updated))
+		attrs = routeAttributes{}
+		c.applyNodeWeight(&attrs)
+		require.Equal(t, routeAttributes{}, attrs)
+	}
+
+	// A change of the weight triggers a reconciliation, other changes of the node do not
+	updated := node.DeepCopy()
+	updated.Labels["zone"] = "a"
+	c.enqueueUpdateNode(node, updated)
+	require.Empty(t, c.reconcileCh)
+	updated.Labels["bgp-weight"] = "200"
+	c.enqueueUpdateNode(node, updated)
+	require.Len(t, c.reconcileCh, 1)
+}
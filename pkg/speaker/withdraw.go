@@ -0,0 +1,48 @@
+package speaker
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+// newWithdrawLimiter returns the limiter pacing the withdrawals of routes, or nil if withdrawals are not paced
+func newWithdrawLimiter(withdrawRate float64, burst int) *rate.Limiter {
+	if withdrawRate == 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(withdrawRate), burst)
+}
+
+// paceWithdrawals splits a batch of routes to withdraw into chunks of at most the burst of the withdraw limiter.
+// Batches are not split if withdrawals are not paced.
+func (c *Controller) paceWithdrawals(batch []string) [][]string {
+	if c.withdrawLimiter == nil || len(batch) <= c.withdrawLimiter.Burst() {
+		return [][]string{batch}
+	}
+
+	var chunks [][]string
+	for i := 0; i < len(batch); i += c.withdrawLimiter.Burst() {
+		chunks = append(chunks, batch[i:min(i+c.withdrawLimiter.Burst(), len(batch))])
+	}
+	return chunks
+}
+
+// waitWithdrawals waits for the withdraw limiter to allow the withdrawal of a chunk of routes. Withdrawals are not
+// paced anymore once the controller is stopped, so that the shutdown is not held back by them.
+func (c *Controller) waitWithdrawals(chunk []string) {
+	if c.withdrawLimiter == nil {
+		return
+	}
+	ctx := c.stopCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err := c.withdrawLimiter.WaitN(ctx, len(chunk)); err != nil && ctx.Err() == nil {
+		klog.Errorf("failed to wait for the withdrawal of routes %v: %v", chunk, err)
+	}
+}
@@ -0,0 +1,95 @@
+package speaker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/v4/api"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/set"
+)
+
+func TestPaceWithdrawals(t *testing.T) {
+	batch := []string{"192.168.1.1/32", "192.168.1.2/32", "192.168.1.3/32", "192.168.1.4/32", "192.168.1.5/32"}
+
+	c := &Controller{}
+	require.Equal(t, [][]string{batch}, c.paceWithdrawals(batch))
+
+	c.withdrawLimiter = newWithdrawLimiter(10, 2)
+	require.Equal(t, [][]string{batch[:2], batch[2:4], batch[4:]}, c.paceWithdrawals(batch))
+	require.Equal(t, [][]string{batch[:2]}, c.paceWithdrawals(batch[:2]))
+
+	require.Nil(t, newWithdrawLimiter(0, 100))
+}
+
+func TestBulkWithdrawalsPaced(t *testing.T) {
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced:       newAnnouncedStore(),
+		announcer:       a,
+		withdrawLimiter: newWithdrawLimiter(100, 5),
+	}
+
+	expected := set.New[string]()
+	for i := range 25 {
+		expected.Insert(fmt.Sprintf("192.168.1.%d/32", i+1))
+	}
+
+	// Announcements are not paced
+	require.Empty(t, c.reconcileRoutes(prefixMap{api.Family_AFI_IP: expected}, nil).failed())
+	require.Len(t, a.calls, 1)
+
+	// Withdrawals beyond the burst are withdrawn in chunks at the withdraw rate,
+	// 20 routes at 100 routes per second taking about 200ms
+	a.calls = nil
+	start := time.Now()
+	require.Empty(t, c.reconcileRoutes(make(prefixMap), nil).failed())
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	require.Len(t, a.calls, 5)
+	for _, call := range a.calls {
+		require.True(t, call.withdraw)
+		require.Len(t, call.prefixes, 5)
+	}
+	require.Empty(t, a.announced)
+	require.Empty(t, c.announced.List())
+}
+
+func TestWithdrawalsNotPacedOnShutdown(t *testing.T) {
+	a := newFakeAnnouncer()
+	neighbor := net.ParseIP("10.32.32.1")
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Controller{
+		config: &Configuration{
+			NeighborAddresses:      []net.IP{neighbor},
+			NeighborLocalAddresses: map[string]net.IP{neighbor.String(): net.ParseIP("10.32.32.2")},
+		},
+		announced:       newAnnouncedStore(),
+		announcer:       a,
+		withdrawLimiter: newWithdrawLimiter(1, 5),
+		stopCtx:         ctx,
+	}
+
+	expected := set.New[string]()
+	for i := range 25 {
+		expected.Insert(fmt.Sprintf("192.168.1.%d/32", i+1))
+	}
+	require.Empty(t, c.reconcileRoutes(prefixMap{api.Family_AFI_IP: expected}, nil).failed())
+
+	// A withdrawal waiting for the withdraw limiter proceeds as soon as the controller is stopped,
+	// and the next ones are not paced anymore, 20 routes at 1 route per second taking 20s otherwise
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	require.Empty(t, c.reconcileRoutes(make(prefixMap), nil).failed())
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Len(t, a.calls, 6)
+	require.Empty(t, a.announced)
+	require.Empty(t, c.announced.List())
+}
@@ -35,6 +35,16 @@ const (
 	VMAnnotation                 = "ovn.kubernetes.io/virtualmachine"
 	ActivationStrategyAnnotation = "ovn.kubernetes.io/activation_strategy"
 
+	BgpPriorityAnnotation      = "ovn.kubernetes.io/bgp-priority"
+	BgpDrainAnnotation         = "ovn.kubernetes.io/bgp-drain"
+	BgpDrainUntilAnnotation    = "ovn.kubernetes.io/bgp-drain-until"
+	BgpLinkBandwidthAnnotation = "ovn.kubernetes.io/bgp-link-bandwidth"
+	BgpInstanceAnnotation      = "ovn.kubernetes.io/bgp-instance"
+	BgpNeighborAnnotation      = "ovn.kubernetes.io/bgp-neighbor"
+	BgpLocalPrefAnnotation     = "ovn.kubernetes.io/bgp-local-pref"
+	BgpMEDv4Annotation         = "ovn.kubernetes.io/bgp-med-v4"
+	BgpMEDv6Annotation         = "ovn.kubernetes.io/bgp-med-v6"
+
 	VpcNatGatewayAnnotation                 = "ovn.kubernetes.io/vpc_nat_gw"
 	VpcNatGatewayInitAnnotation             = "ovn.kubernetes.io/vpc_nat_gw_init"
 	VpcNatGatewayContainerRestartAnnotation = "ovn.kubernetes.io/vpc_nat_gw_container_restarted"